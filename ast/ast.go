@@ -18,6 +18,7 @@ type FenceSection struct {
 	Props      []PropNode
 	Variables  []VariableNode // Assuming VariableNode exists or will be added
 	RawContent string         // Store raw JS content for now
+	Pos        Position       // Start of RawContent in the template
 }
 
 func (f *FenceSection) NodeType() string { return "FenceSection" }
@@ -75,10 +76,18 @@ func (e *Element) NodeType() string { return "Element" }
 type Attribute struct {
 	Name       string
 	Value      string
-	Dynamic    bool   // true if value contains {}
-	IsAlpine   bool   // true if this is an Alpine.js directive
-	AlpineType string // "data", "bind", "on", etc.
-	AlpineKey  string // For x-bind:class, this would be "class"
+	Dynamic    bool       // true if value contains {}
+	IsAlpine   bool       // true if this is an Alpine.js directive
+	AlpineType string     // "data", "bind", "on", etc.
+	AlpineKey  string     // For x-bind:class, this would be "class"
+	Modifiers  []Modifier // For @click.prevent.stop, this would be [prevent stop]
+}
+
+// Modifier represents a single Alpine.js directive modifier such as .prevent
+// or .debounce.300ms
+type Modifier struct {
+	Name string // e.g., "debounce"
+	Arg  string // Optional argument, e.g., "300ms"
 }
 
 // TextNode represents a text node
@@ -128,7 +137,8 @@ func (l *Loop) NodeType() string { return "Loop" }
 type ComponentNode struct {
 	Name    string // e.g., "Head" or "./path/comp.html" for dynamic
 	Props   []ComponentProp
-	Dynamic bool // True if tag starts with <=
+	Dynamic bool     // True if tag starts with <=
+	Pos     Position // Start of the tag in the template
 }
 
 func (c *ComponentNode) NodeType() string { return "Component" }
@@ -136,9 +146,12 @@ func (c *ComponentNode) NodeType() string { return "Component" }
 // ComponentProp represents a prop passed to a component
 type ComponentProp struct {
 	Name        string
-	Value       string // Store expression string or static value string
-	IsShorthand bool   // True for {prop} shorthand
-	IsDynamic   bool   // True for prop={expression}
+	Value       string     // Store expression string or static value string
+	IsShorthand bool       // True for {prop} shorthand
+	IsDynamic   bool       // True for prop={expression}
+	IsEvent     bool       // True for on:event={handler}, where Name is the event name
+	IsBinding   bool       // True for bind:prop={expression}, where Name is the prop name
+	Modifiers   []Modifier // Event modifiers, for on:select|once this would be [once]
 }
 
 // --- Simple Directive Nodes ---
@@ -0,0 +1,111 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Position locates a point of a template. The zero Position is unknown.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line, starting at 1
+	Column int // column in bytes, starting at 1
+}
+
+// IsValid reports whether the position is known
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// Advance returns the position after text, which starts at p
+func (p Position) Advance(text string) Position {
+	p.Offset += len(text)
+	if newlines := strings.Count(text, "\n"); newlines > 0 {
+		p.Line += newlines
+		p.Column = len(text) - strings.LastIndex(text, "\n")
+	} else {
+		p.Column += len(text)
+	}
+	return p
+}
+
+// PositionOf returns the position of an offset of a template's source
+func PositionOf(source string, offset int) Position {
+	return Position{Line: 1, Column: 1}.Advance(source[:offset])
+}
+
+// location formats where an error happened: the file, the position in it when
+// known, and the files importing it
+func location(file string, pos Position, importChain []string) string {
+	var sb strings.Builder
+	sb.WriteString(file)
+	if file == "" {
+		sb.WriteString("template")
+	}
+	if pos.IsValid() {
+		fmt.Fprintf(&sb, ":%d:%d", pos.Line, pos.Column)
+	}
+	if len(importChain) > 0 {
+		fmt.Fprintf(&sb, " (imported by %s)", strings.Join(importChain, " -> "))
+	}
+	return sb.String()
+}
+
+// ParseError reports a template that can't be parsed
+type ParseError struct {
+	File        string   // file of the template, empty when it wasn't loaded from one
+	Pos         Position // where parsing stopped
+	ImportChain []string // files importing the template, from the page to the direct importer
+	Message     string
+}
+
+func (e *ParseError) Error() string {
+	return location(e.File, e.Pos, e.ImportChain) + ": " + e.Message
+}
+
+// ComponentNotFoundError reports a component that is imported or used but can't
+// be found
+type ComponentNotFoundError struct {
+	Name        string   // name or path the component is used or imported under
+	File        string   // file using or importing the component
+	Pos         Position // the import or the component tag
+	ImportChain []string // files importing File, from the page to the direct importer
+	Err         error    // why the component's file couldn't be loaded, if it was imported
+}
+
+func (e *ComponentNotFoundError) Error() string {
+	message := location(e.File, e.Pos, e.ImportChain) + ": component " + e.Name + " not found"
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *ComponentNotFoundError) Unwrap() error { return e.Err }
+
+// ComponentCycleError reports components nested in themselves without a
+// conditional or a loop to end the recursion
+type ComponentCycleError struct {
+	Cycle       []string // components of the cycle, like [Menu Item Menu]
+	File        string   // file using the component that closes the cycle
+	Pos         Position // the component tag
+	ImportChain []string // files importing File, from the page to the direct importer
+}
+
+func (e *ComponentCycleError) Error() string {
+	return location(e.File, e.Pos, e.ImportChain) + ": component cycle without a condition or loop to end it: " + strings.Join(e.Cycle, " -> ")
+}
+
+// EvalError reports fence code that fails when it's evaluated on the server
+type EvalError struct {
+	File        string   // file of the fence
+	Pos         Position // the statement that failed
+	ImportChain []string // files importing File, from the page to the direct importer
+	Err         error
+}
+
+func (e *EvalError) Error() string {
+	return location(e.File, e.Pos, e.ImportChain) + ": evaluating the fence: " + e.Err.Error()
+}
+
+func (e *EvalError) Unwrap() error { return e.Err }
@@ -0,0 +1,85 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestPositionOf(t *testing.T) {
+	source := "---\nlet a = 1;\n---\n<main>\n\t<Card />\n</main>\n"
+
+	tests := []struct {
+		offset int
+		line   int
+		column int
+	}{
+		{0, 1, 1},
+		{3, 1, 4},
+		{4, 2, 1},
+		{8, 2, 5},
+		{27, 5, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.offset), func(t *testing.T) {
+			pos := PositionOf(source, tt.offset)
+			if pos.Offset != tt.offset || pos.Line != tt.line || pos.Column != tt.column {
+				t.Errorf("Expected %d:%d at offset %d, got %+v", tt.line, tt.column, tt.offset, pos)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "parse error",
+			err:      &ParseError{File: "components/Card.html", Pos: Position{Line: 3, Column: 7}, ImportChain: []string{"index.html"}, Message: "unclosed tag"},
+			expected: "components/Card.html:3:7 (imported by index.html): unclosed tag",
+		},
+		{
+			name:     "parse error without a file",
+			err:      &ParseError{Message: "unclosed tag"},
+			expected: "template: unclosed tag",
+		},
+		{
+			name:     "component not found",
+			err:      &ComponentNotFoundError{Name: "Card", File: "lib/List.html", Pos: Position{Line: 8, Column: 3}, ImportChain: []string{"index.html", "lib/Page.html"}},
+			expected: "lib/List.html:8:3 (imported by index.html -> lib/Page.html): component Card not found",
+		},
+		{
+			name:     "component that can't be loaded",
+			err:      &ComponentNotFoundError{Name: "Card", File: "index.html", Pos: Position{Line: 2, Column: 1}, Err: fs.ErrNotExist},
+			expected: "index.html:2:1: component Card not found: file does not exist",
+		},
+		{
+			name:     "component cycle",
+			err:      &ComponentCycleError{Cycle: []string{"Menu", "Item", "Menu"}, File: "Item.html", Pos: Position{Line: 5, Column: 2}, ImportChain: []string{"index.html", "Menu.html"}},
+			expected: "Item.html:5:2 (imported by index.html -> Menu.html): component cycle without a condition or loop to end it: Menu -> Item -> Menu",
+		},
+		{
+			name:     "eval error",
+			err:      &EvalError{File: "index.html", Pos: Position{Line: 4, Column: 1}, Err: errors.New("ReferenceError: window is not defined")},
+			expected: "index.html:4:1: evaluating the fence: ReferenceError: window is not defined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, tt.err.Error())
+			}
+		})
+	}
+
+	// The cause of an error is reachable through errors.Is
+	if err := error(&ComponentNotFoundError{Name: "Card", Err: fs.ErrNotExist}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected the error to wrap fs.ErrNotExist")
+	}
+}
@@ -79,7 +79,11 @@ func debugRenderer() {
 
 		// 5. Render the template using the renderer package
 		fmt.Printf("Rendering template %s...\n", file)
-		markup, script, style := renderer.Render(file, props)
+		result, err := renderer.Render(file, props)
+		if err != nil {
+			fmt.Printf("❌ Failed to render template: %v\n", err)
+		}
+		markup, script, style := result.Markup, result.Script, result.Style
 
 		// 6. Check render output
 		if markup == "" {
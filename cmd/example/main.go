@@ -11,10 +11,12 @@ import (
 	"text/template"
 
 	"github.com/jimafisk/custom_go_template/ast"
-	"github.com/jimafisk/custom_go_template/parser"
-	"github.com/jimafisk/custom_go_template/transformer"
+	"github.com/jimafisk/custom_go_template/engine"
 )
 
+// pages keeps the parsed and transformed templates between requests
+var pages = engine.New(engine.Options{})
+
 func main() {
 	// Define command-line flags
 	port := flag.Int("port", 8083, "Port to run the server on")
@@ -73,19 +75,6 @@ func main() {
 }
 
 func serveTemplate(w http.ResponseWriter, templatePath, componentsDir string) {
-	// Read the template file
-	content, err := os.ReadFile(templatePath)
-	if err != nil {
-		http.Error(w, "Error reading template file", http.StatusInternalServerError)
-		return
-	}
-
-	// Parse the template
-	parsedTemplate, parseErr := parser.ParseTemplate(string(content))
-	if parseErr != nil {
-		http.Error(w, fmt.Sprintf("Error parsing template: %v", parseErr), http.StatusInternalServerError)
-		return
-	}
 
 	// Create a data scope with some example data
 	dataScope := map[string]interface{}{
@@ -149,8 +138,15 @@ func serveTemplate(w http.ResponseWriter, templatePath, componentsDir string) {
 		"status": "active", // Add status for the status conditional example
 	}
 
-	// Transform the template
-	transformedTemplate := transformer.TransformAST(parsedTemplate, dataScope)
+	// Transform the template, reusing the work of earlier requests
+	transformedTemplate, err := pages.TransformFile(templatePath, dataScope)
+	if transformedTemplate == nil {
+		http.Error(w, fmt.Sprintf("Error loading template: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		log.Printf("Error transforming %s: %v", templatePath, err)
+	}
 
 	// Render the template
 	tmpl := template.New("page").Funcs(template.FuncMap{
@@ -237,19 +233,6 @@ func serveTemplate(w http.ResponseWriter, templatePath, componentsDir string) {
 }
 
 func renderToString(templatePath, componentsDir string) string {
-	// Read the template file
-	content, err := os.ReadFile(templatePath)
-	if err != nil {
-		log.Fatalf("Error reading template file: %v", err)
-		return ""
-	}
-
-	// Parse the template
-	parsedTemplate, parseErr := parser.ParseTemplate(string(content))
-	if parseErr != nil {
-		log.Fatalf("Error parsing template: %v", parseErr)
-		return ""
-	}
 
 	// Create a data scope with example data
 	dataScope := map[string]interface{}{
@@ -314,7 +297,13 @@ func renderToString(templatePath, componentsDir string) string {
 	}
 
 	// Transform the template
-	transformedTemplate := transformer.TransformAST(parsedTemplate, dataScope)
+	transformedTemplate, err := pages.TransformFile(templatePath, dataScope)
+	if transformedTemplate == nil {
+		log.Fatalf("Error loading template: %v", err)
+	}
+	if err != nil {
+		log.Printf("Error transforming %s: %v", templatePath, err)
+	}
 
 	// Render the template to a string
 	var sb strings.Builder
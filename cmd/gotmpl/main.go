@@ -0,0 +1,83 @@
+// Command gotmpl works with templates from the command line.
+//
+//	gotmpl generate [-dir templates] [-out dir] [-package name] [template ...]
+//
+// generate compiles templates to Go code, one file per template, with a
+// RenderX(w io.Writer, props XProps) error function writing what
+// renderer.Render would. Templates are paths in the -dir directory; without
+// them every .html file in it is compiled.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/jimafisk/custom_go_template/codegen"
+)
+
+// logger reports the errors of the command. The standard logger is silenced,
+// as the transformer logs every render with it.
+var logger = log.New(os.Stderr, "gotmpl: ", 0)
+
+func main() {
+	log.SetOutput(io.Discard)
+	if len(os.Args) < 2 || os.Args[1] != "generate" {
+		fmt.Fprintln(os.Stderr, "usage: gotmpl generate [-dir templates] [-out dir] [-package name] [template ...]")
+		os.Exit(2)
+	}
+	generate(os.Args[2:])
+}
+
+func generate(args []string) {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	dir := flags.String("dir", ".", "Directory holding the pages and components")
+	out := flags.String("out", ".", "Directory to write the Go files to")
+	pkg := flags.String("package", "", "Package of the Go files (default: name of the -out directory)")
+	flags.Parse(args)
+
+	absOut, err := filepath.Abs(*out)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if *pkg == "" {
+		*pkg = filepath.Base(absOut)
+	}
+	absDir, err := filepath.Abs(*dir)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	// The //line directives are relative to the generated files when they can be
+	lineDir, err := filepath.Rel(absOut, absDir)
+	if err != nil {
+		lineDir = absDir
+	}
+
+	templatesFS := os.DirFS(*dir)
+	templates := flags.Args()
+	if len(templates) == 0 {
+		if templates, err = codegen.Templates(templatesFS, "."); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	files, err := codegen.Generate(codegen.Options{
+		FS:      templatesFS,
+		Package: *pkg,
+		LineDir: filepath.ToSlash(lineDir),
+	}, templates...)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		logger.Fatal(err)
+	}
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(*out, file.FileName()), file.Source, 0o644); err != nil {
+			logger.Fatal(err)
+		}
+	}
+}
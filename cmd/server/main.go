@@ -1,23 +1,39 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"regexp"
 	"strings"
 
-	// Import the new renderer package
-	"github.com/jimafisk/custom_go_template/ast"
-	"github.com/jimafisk/custom_go_template/parser"
+	"github.com/jimafisk/custom_go_template/engine"
+	"github.com/jimafisk/custom_go_template/examples"
+	"github.com/jimafisk/custom_go_template/modules"
 	"github.com/jimafisk/custom_go_template/renderer"
-	"github.com/jimafisk/custom_go_template/transformer"
 )
 
 func main() {
+	templatesDir := flag.String("templates", "", "directory whose pages and components override the embedded examples")
+	entrypoint := flag.String("page", "pages/comprehensive.html", "page to serve, relative to the templates")
+	var mode engine.Mode
+	flag.Var(&mode, "mode", "how pages are rendered: client, ssr to evaluate expressions, conditions and loops on the server too, or static for plain HTML without Alpine.js")
+	flag.Parse()
+	
 	log.Println("Starting server...")
 	
+	// Templates are read from the embedded examples, under the templates directory if one is given
+	var templates fs.FS = examples.FS
+	if *templatesDir != "" {
+		templates = modules.Layered(os.DirFS(*templatesDir), examples.FS)
+	}
+	// The engine keeps the parsed and transformed templates between requests,
+	// and picks up the templates that change
+	pages := engine.New(engine.Options{FS: templates, Mode: mode})
+	
 	// Create the public directory if it doesn't exist
 	publicDir := "./public" // Use a variable for clarity
 	err := os.MkdirAll(publicDir, 0755)
@@ -25,12 +41,8 @@ func main() {
 		log.Fatalf("Failed to create public directory: %v", err)
 	}
 	
-	// Register components
-	registerComponents()
-	
 	// Extract variables from the fence section for Alpine.js data scope
-	entrypoint := "examples/pages/comprehensive.html"
-	alpineDataScope := getAlpineDataScope(entrypoint)
+	alpineDataScope := getAlpineDataScope(*entrypoint)
 	
 	// Set up the HTTP server
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -40,14 +52,22 @@ func main() {
 			return
 		}
 		
-		// Render the template
+		// Render the template, with the components it imports
 		props := make(map[string]interface{})
-		markup, script, style := renderer.Render(entrypoint, props)
+		result, err := pages.Render(*entrypoint, props)
+		if err != nil {
+			log.Printf("Error rendering %s: %v", *entrypoint, err)
+			if result == (renderer.Result{}) {
+				http.Error(w, "Failed to render the page", http.StatusInternalServerError)
+				return
+			}
+		}
+		markup, script, style := result.Markup, result.Script, result.Style
 		
 		// If the style is empty, try to extract it directly from the template file
 		if style == "" {
 			log.Println("Style is empty, extracting directly from template file")
-			templateContent, err := os.ReadFile(entrypoint)
+			templateContent, err := fs.ReadFile(templates, *entrypoint)
 			if err == nil {
 				// Extract style content between <style> tags
 				styleRegex := regexp.MustCompile(`(?s)<style>(.*?)</style>`)
@@ -66,7 +86,9 @@ func main() {
 		// Write the output files to the public directory
 		err = os.WriteFile(publicDir+"/script.js", []byte(script), 0644) // Use standard file permissions
 		if err != nil {
-			log.Fatalf("Failed to write script.js: %v", err)
+			log.Printf("Failed to write script.js: %v", err)
+			http.Error(w, "Failed to write the page", http.StatusInternalServerError)
+			return
 		}
 		
 		// Make sure the style doesn't contain style tags
@@ -76,7 +98,9 @@ func main() {
 		
 		err = os.WriteFile(publicDir+"/style.css", []byte(style), 0644)
 		if err != nil {
-			log.Fatalf("Failed to write style.css: %v", err)
+			log.Printf("Failed to write style.css: %v", err)
+			http.Error(w, "Failed to write the page", http.StatusInternalServerError)
+			return
 		}
 		
 		// Add Alpine.js data scope directly to the html tag
@@ -94,7 +118,9 @@ func main() {
 		
 		err = os.WriteFile(publicDir+"/index.html", []byte(htmlWithLinks), 0644)
 		if err != nil {
-			log.Fatalf("Failed to write index.html: %v", err)
+			log.Printf("Failed to write index.html: %v", err)
+			http.Error(w, "Failed to write the page", http.StatusInternalServerError)
+			return
 		}
 		
 		// Serve the index.html file
@@ -159,92 +185,6 @@ func getAlpineDataScope(entrypoint string) string {
 	return alpineDataScope
 }
 
-func registerComponents() {
-	// Register components with the transformer
-	componentDir := "examples/components"
-	files, err := os.ReadDir(componentDir)
-	if err != nil {
-		log.Fatalf("Failed to read component directory: %v", err)
-	}
-	
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".html") {
-			componentName := strings.TrimSuffix(file.Name(), ".html")
-			componentPath := fmt.Sprintf("%s/%s", componentDir, file.Name())
-			log.Printf("Registering component: %s from %s", componentName, componentPath)
-			
-			// Read component file
-			componentContent, err := os.ReadFile(componentPath)
-			if err != nil {
-				log.Fatalf("Error reading component: %v", err)
-			}
-			
-			// Parse the component template
-			componentAST, err := parser.ParseTemplate(string(componentContent))
-			if err != nil {
-				log.Fatalf("Error parsing component: %v", err)
-			}
-			
-			// Extract props from the component template
-			componentProps := extractComponentProps(componentAST)
-			
-			// Register the component with the transformer - both by name and by path
-			transformer.RegisterComponent(componentName, componentAST, componentProps)
-			
-			// Also register with path for import resolution
-			pathWithPrefix := fmt.Sprintf("./components/%s.html", componentName)
-			transformer.RegisterComponent(pathWithPrefix, componentAST, componentProps)
-		}
-	}
-}
-
-func extractComponentProps(template *ast.Template) []string {
-	var props []string
-	
-	// Look for prop declarations in fence sections
-	for _, node := range template.RootNodes {
-		if fence, ok := node.(*ast.FenceSection); ok {
-			// Extract props directly from the Props field
-			for _, prop := range fence.Props {
-				props = append(props, prop.Name)
-			}
-			
-			// Also check the raw content for any props that might not have been parsed
-			content := fence.RawContent
-			// Look for lines starting with "prop"
-			lines := strings.Split(content, "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if strings.HasPrefix(line, "prop ") {
-					// Extract the prop name
-					parts := strings.SplitN(line, "=", 2)
-					if len(parts) > 0 {
-						propDecl := strings.TrimSpace(parts[0])
-						propName := strings.TrimPrefix(propDecl, "prop ")
-						propName = strings.TrimSpace(propName)
-						
-						// Check if this prop is already in our list
-						found := false
-						for _, existingProp := range props {
-							if existingProp == propName {
-								found = true
-								break
-							}
-						}
-						
-						// Add it if it's not already in the list
-						if !found {
-							props = append(props, propName)
-						}
-					}
-				}
-			}
-		}
-	}
-	
-	return props
-}
-
 func addLinksToHTML(html string, alpineDataScope string) string {
 	// Check if the HTML already has a head tag
 	headRegex := regexp.MustCompile(`(?i)<head>`)
@@ -0,0 +1,308 @@
+// Package codegen compiles pages and components to Go code. The generated code
+// writes the markup, script and style renderer.Render produces for a template,
+// without parsing, transforming or evaluating JavaScript when it runs.
+//
+// Each template becomes a file exposing a Props struct and a Render function.
+// Templates whose output depends on their props in other ways than writing
+// their values, like derived values evaluated on the server, aren't compiled.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/engine"
+	"github.com/jimafisk/custom_go_template/modules"
+)
+
+// Options configures the generation of Go code
+type Options struct {
+	// FS holds the pages and components. Nil reads the host file system.
+	FS fs.FS
+
+	// Resolver resolves the component imports of the templates, see engine.Options
+	Resolver *modules.Resolver
+
+	// SnapshotDerived evaluates derived fence consts on the server, see
+	// engine.Options. Templates deriving values from props can't be compiled
+	// with it.
+	SnapshotDerived bool
+
+	// Package is the name of the package of the generated code
+	Package string
+
+	// LineDir is joined before the template paths in the //line directives of
+	// the generated code, which are relative to the directory of the generated
+	// files. It is usually the path from that directory to the root of FS.
+	LineDir string
+}
+
+// File is the Go code generated for a template
+type File struct {
+	Template string // path of the template
+	Name     string // name of the template in Go, like PagesIndex for pages/index.html
+	Source   []byte // formatted Go source
+}
+
+// FileName returns the name of the Go file of a template, like
+// pages_index_gotmpl.go for pages/index.html
+func (f *File) FileName() string {
+	var b strings.Builder
+	for i, word := range words(strings.TrimSuffix(f.Template, path.Ext(f.Template))) {
+		if i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteString(strings.ToLower(word))
+	}
+	return b.String() + "_gotmpl.go"
+}
+
+// Generate generates the Go code of each template, given by their paths
+func Generate(options Options, templates ...string) ([]*File, error) {
+	e := newEngine(options)
+	files := make([]*File, 0, len(templates))
+	names := make(map[string]string)
+	for _, template := range templates {
+		file, err := generate(e, template, options)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := names[file.Name]; ok {
+			return nil, fmt.Errorf("%s and %s are both named %s in Go", other, template, file.Name)
+		}
+		names[file.Name] = template
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// Templates returns the paths of the templates under dir in fsys, the .html files
+func Templates(fsys fs.FS, dir string) ([]string, error) {
+	var templates []string
+	err := fs.WalkDir(fsys, dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && path.Ext(file) == ".html" {
+			templates = append(templates, file)
+		}
+		return nil
+	})
+	return templates, err
+}
+
+func generate(e *engine.Engine, template string, options Options) (*File, error) {
+	c, err := compile(e, template)
+	if err != nil {
+		return nil, err
+	}
+	source, err := readFile(options.FS, c.path)
+	if err != nil {
+		return nil, err
+	}
+	module, _ := e.Modules().Module(c.path)
+
+	g := &generator{
+		compiled: c,
+		name:     goName(words(strings.TrimSuffix(template, path.Ext(template)))),
+		file:     path.Join(options.LineDir, template),
+		lines:    templateLines(string(source), module.Template, c.props),
+	}
+	g.fields = fieldNames(c.props)
+
+	code, err := format.Source(g.generate(options.Package))
+	if err != nil {
+		return nil, fmt.Errorf("%s: formatting generated code: %w", template, err)
+	}
+	return &File{Template: template, Name: g.name, Source: code}, nil
+}
+
+func readFile(fsys fs.FS, file string) ([]byte, error) {
+	if fsys == nil {
+		return os.ReadFile(file)
+	}
+	return fs.ReadFile(fsys, file)
+}
+
+// lines are the positions in a template the generated code refers to
+type lines struct {
+	markup, script, style ast.Position
+	props                 []ast.Position
+}
+
+// propDeclaration matches the declaration of a prop in a fence
+var propDeclaration = regexp.MustCompile(`(?:^|[;{}\n])\s*(prop\s+([A-Za-z_$][A-Za-z0-9_$]*))`)
+
+// templateLines finds the markup, script, style and prop declarations in a template
+func templateLines(source string, template *ast.Template, props []string) lines {
+	l := lines{markup: ast.Position{Line: 1, Column: 1}, props: make([]ast.Position, len(props))}
+	markupStart := 0
+	for _, node := range template.RootNodes {
+		fence, ok := node.(*ast.FenceSection)
+		if !ok || !fence.Pos.IsValid() {
+			continue
+		}
+		for _, match := range propDeclaration.FindAllStringSubmatchIndex(fence.RawContent, -1) {
+			name := fence.RawContent[match[4]:match[5]]
+			for i, prop := range props {
+				if prop == name && !l.props[i].IsValid() {
+					l.props[i] = fence.Pos.Advance(fence.RawContent[:match[2]])
+				}
+			}
+		}
+		markupStart = fence.Pos.Offset + len(fence.RawContent)
+		if end := strings.Index(source[markupStart:], "---"); end >= 0 {
+			markupStart += end + len("---")
+		}
+	}
+	if start := strings.Index(source[markupStart:], "<"); start >= 0 {
+		l.markup = ast.PositionOf(source, markupStart+start)
+	}
+	l.script, l.style = l.markup, l.markup
+	if start := strings.Index(source, "<script"); start >= 0 {
+		l.script = ast.PositionOf(source, start)
+	}
+	if start := strings.Index(source, "<style"); start >= 0 {
+		l.style = ast.PositionOf(source, start)
+	}
+	for i := range l.props {
+		if !l.props[i].IsValid() {
+			l.props[i] = ast.Position{Line: 1, Column: 1}
+		}
+	}
+	return l
+}
+
+// generator writes the Go code of a compiled template
+type generator struct {
+	*compiled
+	name   string
+	file   string // path of the template in //line directives
+	lines  lines
+	fields []string
+	buf    bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// line writes a //line directive pointing at a position of the template
+func (g *generator) line(pos ast.Position) {
+	g.printf("//line %s:%d:%d\n", g.file, pos.Line, pos.Column)
+}
+
+func (g *generator) generate(pkg string) []byte {
+	g.printf("// Code generated by gotmpl generate from %s. DO NOT EDIT.\n\n", g.path)
+	g.printf("package %s\n\n", pkg)
+	g.printf("import (\n\t\"io\"\n\n\t\"github.com/jimafisk/custom_go_template/codegen/rt\"\n)\n\n")
+
+	g.printf("// %sProps holds the props of %s.\n// A nil prop takes the default of its declaration, like a prop left out of the\n// props given to renderer.Render.\n", g.name, g.path)
+	g.printf("type %sProps struct {\n", g.name)
+	for i, field := range g.fields {
+		g.printf("\t%s any // %s\n", field, g.props[i])
+	}
+	g.printf("}\n\n")
+
+	g.printf("// Render%s writes %s: its markup, followed by its\n// style and script in <style> and <script> elements when it has them\n", g.name, g.path)
+	g.printf("func Render%s(w io.Writer, props %sProps) error {\n", g.name, g.name)
+	g.printf("\tout := rt.NewWriter(w)\n")
+	g.printf("\twrite%sMarkup(out, props)\n", g.name)
+	if len(g.style) > 0 {
+		g.printf("\tout.WriteString(\"<style>\")\n\twrite%sStyle(out, props)\n\tout.WriteString(\"</style>\")\n", g.name)
+	}
+	if len(g.script) > 0 {
+		g.printf("\tout.WriteString(\"<script>\")\n\twrite%sScript(out, props)\n\tout.WriteString(\"</script>\")\n", g.name)
+	}
+	g.printf("\treturn out.Err()\n}\n\n")
+
+	g.printf("// Render%sParts writes the markup, script and style of %s\n// to separate writers, like the fields of renderer.Result\n", g.name, g.path)
+	g.printf("func Render%sParts(markup, script, style io.Writer, props %sProps) error {\n", g.name, g.name)
+	for _, part := range []struct{ writer, name string }{{"markup", "Markup"}, {"script", "Script"}, {"style", "Style"}} {
+		g.printf("\t%sOut := rt.NewWriter(%s)\n\twrite%s%s(%sOut, props)\n", part.writer, part.writer, g.name, part.name, part.writer)
+		g.printf("\tif err := %sOut.Err(); err != nil {\n\t\treturn err\n\t}\n", part.writer)
+	}
+	g.printf("\treturn nil\n}\n")
+
+	g.part("Markup", g.markup, g.lines.markup)
+	g.part("Script", g.script, g.lines.script)
+	g.part("Style", g.style, g.lines.style)
+	return g.buf.Bytes()
+}
+
+// part writes the function writing a part of the output
+func (g *generator) part(name string, segments []segment, pos ast.Position) {
+	g.printf("\nfunc write%s%s(out *rt.Writer, props %sProps) {\n", g.name, name, g.name)
+	if len(segments) == 0 {
+		g.printf("}\n")
+		return
+	}
+	for _, s := range segments {
+		if s.prop < 0 {
+			g.line(pos)
+			g.printf("\tout.WriteString(%s)\n", strconv.Quote(s.text))
+			continue
+		}
+		field := "props." + g.fields[s.prop]
+		g.line(g.lines.props[s.prop])
+		g.printf("\tif %s == nil {\n\t\tout.WriteString(%s)\n\t} else {\n\t\tout.WriteString(%s)\n\t}\n", field, strconv.Quote(s.absent), s.encoding.code(field))
+	}
+	g.printf("}\n")
+}
+
+// code returns the Go expression encoding value
+func (e encoding) code(value string) string {
+	code := fmt.Sprintf("rt.Text(%s)", value)
+	if e.js {
+		code = fmt.Sprintf("rt.JSValue(%s, %t)", value, e.htmlQuotes)
+	}
+	for _, singleQuotes := range e.escapes {
+		code = fmt.Sprintf("rt.EscapeAttr(%s, %t)", code, singleQuotes)
+	}
+	return code
+}
+
+// words splits a template path or prop name into words, at anything but letters
+// and digits
+func words(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// goName joins words into an exported Go name
+func goName(words []string) string {
+	var b strings.Builder
+	for _, word := range words {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		name = "T" + name
+	}
+	return name
+}
+
+// fieldNames returns the names of the Props fields of props, unique and exported
+func fieldNames(props []string) []string {
+	fields := make([]string, len(props))
+	used := make(map[string]bool)
+	for i, prop := range props {
+		field := goName(words(prop))
+		for n := 2; used[field]; n++ {
+			field = fmt.Sprintf("%s%d", goName(words(prop)), n)
+		}
+		used[field] = true
+		fields[i] = field
+	}
+	return fields
+}
@@ -0,0 +1,84 @@
+package codegen
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		options  Options
+		wantErr  string
+		contains []string
+	}{
+		{
+			name: "page with props",
+			files: map[string]string{
+				"pages/user-profile.html": "---\nprop user_name = \"Guest\";\nprop $id;\n---\n<h1>{user_name}</h1>\n<style>h1 { margin: 0; }</style>\n",
+			},
+			contains: []string{
+				"type PagesUserProfileProps struct",
+				"UserName any // user_name",
+				"Id       any // $id",
+				"func RenderPagesUserProfile(w io.Writer, props PagesUserProfileProps) error",
+				"//line ../templates/pages/user-profile.html:2:1\n\tif props.UserName == nil {",
+				"//line ../templates/pages/user-profile.html:3:1\n\tif props.Id == nil {",
+				"//line ../templates/pages/user-profile.html:5:1\n\tout.WriteString(",
+				"//line ../templates/pages/user-profile.html:6:1\n\tout.WriteString(\"h1 { margin: 0; }\\n\")",
+			},
+		},
+		{
+			name: "template without props",
+			files: map[string]string{
+				"pages/about.html": "<p>About</p>\n",
+			},
+			contains: []string{"type PagesAboutProps struct {\n}", "//line ../templates/pages/about.html:1:1"},
+		},
+		{
+			name: "values derived from props on the server",
+			files: map[string]string{
+				"pages/index.html": "---\nprop count = 1;\nconst doubled = count * 2;\n---\n<p>{doubled}</p>\n",
+			},
+			options: Options{SnapshotDerived: true},
+			wantErr: "pages/index.html renders differently",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			var templates []string
+			for name, content := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(content)}
+				templates = append(templates, name)
+			}
+			options := tt.options
+			options.FS, options.Package, options.LineDir = fsys, "views", "../templates"
+
+			files, err := Generate(options, templates...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			source := string(files[0].Source)
+			if _, err := parser.ParseFile(token.NewFileSet(), files[0].FileName(), source, parser.AllErrors); err != nil {
+				t.Fatalf("Generated code doesn't parse: %v\n%s", err, source)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(source, want) {
+					t.Errorf("Expected the generated code to contain %q\n%s", want, source)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package codegen
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/jimafisk/custom_go_template/codegen/rt"
+	"github.com/jimafisk/custom_go_template/engine"
+	"github.com/jimafisk/custom_go_template/renderer"
+)
+
+// A template is compiled by rendering it with placeholder props, and finding
+// where their values end up in the output and how they were encoded there. The
+// rest of the output doesn't depend on the props and is written as it is. The
+// result is checked against renders with other values, so templates whose output
+// depends on the props in other ways, like derived values evaluated on the
+// server, fail to compile rather than render differently.
+
+// sentinelPrefix starts the placeholder values of the props
+const sentinelPrefix = "gotmpl"
+
+// sentinel returns the placeholder value of the prop at index i. The two sets
+// differ in length so that values computed from props differ between them.
+func sentinel(set string, i int) string {
+	return fmt.Sprintf("%s%s%dZ", sentinelPrefix, set, i)
+}
+
+// encoding is the way a prop is written in the output: formatted as a JavaScript
+// value or as it is, then escaped for HTML attributes zero or more times
+type encoding struct {
+	js         bool
+	htmlQuotes bool   // quote strings with &quot;, see rt.JSValue
+	escapes    []bool // escaping passes, each escaping single quotes or not
+}
+
+// encodings are the candidate encodings of a prop
+var encodings = func() []encoding {
+	escapes := [][]bool{nil, {true}, {false}, {true, true}, {false, true}, {true, false}, {false, false}}
+	var all []encoding
+	for _, js := range []bool{true, false} {
+		for _, htmlQuotes := range []bool{false, true} {
+			if !js && htmlQuotes {
+				continue
+			}
+			for _, e := range escapes {
+				all = append(all, encoding{js: js, htmlQuotes: htmlQuotes, escapes: e})
+			}
+		}
+	}
+	return all
+}()
+
+// encodeString encodes a value already formatted
+func (e encoding) encodeString(s string) string {
+	for _, singleQuotes := range e.escapes {
+		s = rt.EscapeAttr(s, singleQuotes)
+	}
+	return s
+}
+
+func (e encoding) encode(value any) string {
+	if e.js {
+		return e.encodeString(rt.JSValue(value, e.htmlQuotes))
+	}
+	return e.encodeString(rt.Text(value))
+}
+
+// segment is a piece of output: text written as it is, or a prop
+type segment struct {
+	text     string
+	prop     int // index of the prop, -1 for text
+	encoding encoding
+	absent   string // written when the prop isn't given
+}
+
+// compiled is a template compiled to the pieces of its output
+type compiled struct {
+	path   string
+	props  []string
+	markup []segment
+	script []segment
+	style  []segment
+}
+
+// execute renders the compiled template, with a nil value for each prop that
+// isn't given
+func (c *compiled) execute(values []any) renderer.Result {
+	return renderer.Result{
+		Markup: executeSegments(c.markup, values),
+		Script: executeSegments(c.script, values),
+		Style:  executeSegments(c.style, values),
+	}
+}
+
+func executeSegments(segments []segment, values []any) string {
+	var b strings.Builder
+	for _, s := range segments {
+		switch {
+		case s.prop < 0:
+			b.WriteString(s.text)
+		case values[s.prop] == nil:
+			b.WriteString(s.absent)
+		default:
+			b.WriteString(s.encoding.encode(values[s.prop]))
+		}
+	}
+	return b.String()
+}
+
+// sampleValues are the values a compiled template is checked with
+var sampleValues = []any{
+	"It's <b>\"quoted\"</b> & escaped \\ \n\t",
+	"() => alert(1)",
+	42,
+	2.5,
+	true,
+	[]any{"a", 1.5, map[string]any{"k": nil}},
+	map[string]any{"b": []any{}, "a": "x'y"},
+}
+
+// compile compiles the template at path, rendered with the engine
+func compile(e *engine.Engine, path string) (*compiled, error) {
+	module, err := e.Modules().Load(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiled{path: module.Path}
+	seen := make(map[string]bool)
+	for _, prop := range module.Props {
+		if !seen[prop] {
+			seen[prop] = true
+			c.props = append(c.props, prop)
+		}
+	}
+
+	render := func(values []any) (renderer.Result, error) {
+		props := make(map[string]any, len(values))
+		for i, value := range values {
+			if value != nil {
+				props[c.props[i]] = value
+			}
+		}
+		return e.Render(path, props)
+	}
+	sentinels := func(set string) []any {
+		values := make([]any, len(c.props))
+		for i := range values {
+			values[i] = sentinel(set, i)
+		}
+		return values
+	}
+
+	valuesA := sentinels("A")
+	resultA, err := render(valuesA)
+	if err != nil {
+		return nil, err
+	}
+	c.markup = split(resultA.Markup, "A", valuesA)
+	c.script = split(resultA.Script, "A", valuesA)
+	c.style = split(resultA.Style, "A", valuesA)
+
+	// The output must only change where the props are written
+	valuesB := sentinels("BB")
+	resultB, err := render(valuesB)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.check(resultB, valuesB, "with other values of its props"); err != nil {
+		return nil, err
+	}
+
+	for i := range c.props {
+		values := append([]any(nil), valuesA...)
+		values[i] = nil
+		result, err := render(values)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range []struct {
+			segments []segment
+			output   string
+		}{{c.markup, result.Markup}, {c.script, result.Script}, {c.style, result.Style}} {
+			if !fillAbsent(part.segments, i, values, part.output) {
+				return nil, c.dependsOnProps(fmt.Sprintf("without the prop %s", c.props[i]))
+			}
+		}
+	}
+
+	checks := [][]any{make([]any, len(c.props))}
+	for _, value := range sampleValues {
+		values := make([]any, len(c.props))
+		for i := range values {
+			values[i] = value
+		}
+		checks = append(checks, values)
+	}
+	for _, values := range checks {
+		result, err := render(values)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.check(result, values, "with sample props"); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// check compares the output of the compiled template with the output of the engine
+func (c *compiled) check(want renderer.Result, values []any, with string) error {
+	if c.execute(values) != want {
+		return c.dependsOnProps(with)
+	}
+	return nil
+}
+
+func (c *compiled) dependsOnProps(with string) error {
+	return fmt.Errorf("%s renders differently %s than where its props are written; templates computing values from props on the server, like derived values with SnapshotDerived, can't be compiled", c.path, with)
+}
+
+// split splits output at the props written in it, found by their sentinel values
+func split(output string, set string, values []any) []segment {
+	var segments []segment
+	marker := sentinelPrefix + set
+	text := 0
+	for search := 0; ; {
+		found := strings.Index(output[search:], marker)
+		if found < 0 {
+			break
+		}
+		found += search
+		search = found + len(marker)
+
+		digits := search
+		for digits < len(output) && output[digits] >= '0' && output[digits] <= '9' {
+			digits++
+		}
+		prop, err := strconv.Atoi(output[search:digits])
+		if err != nil || prop >= len(values) || sentinel(set, prop) != output[found:digits+min(1, len(output)-digits)] {
+			continue
+		}
+
+		// The longest encoding of the sentinel found there is the one used
+		var best encoding
+		start, end := -1, -1
+		for _, e := range encodings {
+			encoded := e.encode(values[prop])
+			offset := strings.Index(encoded, values[prop].(string))
+			if offset < 0 || found-offset < text || !strings.HasPrefix(output[found-offset:], encoded) {
+				continue
+			}
+			if end-start < len(encoded) {
+				best, start, end = e, found-offset, found-offset+len(encoded)
+			}
+		}
+		if start < 0 {
+			continue
+		}
+		if start > text {
+			segments = append(segments, segment{text: output[text:start], prop: -1})
+		}
+		segments = append(segments, segment{prop: prop, encoding: best})
+		text, search = end, end
+	}
+	if text < len(output) {
+		segments = append(segments, segment{text: output[text:], prop: -1})
+	}
+	return segments
+}
+
+// fillAbsent finds what the segments of prop are replaced with in output, which
+// was rendered without the prop and with values for the other props. It reports
+// whether the rest of output matches the segments.
+func fillAbsent(segments []segment, prop int, values []any, output string) bool {
+	// The output is made of the text between the segments of the prop, and what
+	// each of them is replaced with
+	var between []string
+	var holes []int
+	var piece strings.Builder
+	for i, s := range segments {
+		if s.prop == prop {
+			between = append(between, piece.String())
+			holes = append(holes, i)
+			piece.Reset()
+			continue
+		}
+		piece.WriteString(executeSegments(segments[i:i+1], values))
+	}
+	between = append(between, piece.String())
+
+	if !strings.HasPrefix(output, between[0]) {
+		return false
+	}
+	position := len(between[0])
+	for i, hole := range holes {
+		next := between[i+1]
+		var absent string
+		switch {
+		case i == len(holes)-1:
+			if !strings.HasSuffix(output[position:], next) {
+				return false
+			}
+			absent = output[position : len(output)-len(next)]
+		case next == "":
+			// Props written next to each other can't be told apart
+			return false
+		default:
+			end := strings.Index(output[position:], next)
+			if end < 0 {
+				return false
+			}
+			absent = output[position : position+end]
+		}
+		segments[hole].absent = absent
+		position += len(absent) + len(next)
+	}
+	return position == len(output)
+}
+
+// newEngine returns an engine rendering the templates to compile
+func newEngine(options Options) *engine.Engine {
+	return engine.New(engine.Options{
+		FS:              options.FS,
+		Resolver:        options.Resolver,
+		SnapshotDerived: options.SnapshotDerived,
+		Logger:          log.New(io.Discard, "", 0),
+		CacheSize:       -1,
+	})
+}
@@ -0,0 +1,78 @@
+// Code generated by gotmpl generate from components/Card.html. DO NOT EDIT.
+
+package example
+
+import (
+	"io"
+
+	"github.com/jimafisk/custom_go_template/codegen/rt"
+)
+
+// ComponentsCardProps holds the props of components/Card.html.
+// A nil prop takes the default of its declaration, like a prop left out of the
+// props given to renderer.Render.
+type ComponentsCardProps struct {
+	Label    any // label
+	Featured any // featured
+}
+
+// RenderComponentsCard writes components/Card.html: its markup, followed by its
+// style and script in <style> and <script> elements when it has them
+func RenderComponentsCard(w io.Writer, props ComponentsCardProps) error {
+	out := rt.NewWriter(w)
+	writeComponentsCardMarkup(out, props)
+	out.WriteString("<style>")
+	writeComponentsCardStyle(out, props)
+	out.WriteString("</style>")
+	return out.Err()
+}
+
+// RenderComponentsCardParts writes the markup, script and style of components/Card.html
+// to separate writers, like the fields of renderer.Result
+func RenderComponentsCardParts(markup, script, style io.Writer, props ComponentsCardProps) error {
+	markupOut := rt.NewWriter(markup)
+	writeComponentsCardMarkup(markupOut, props)
+	if err := markupOut.Err(); err != nil {
+		return err
+	}
+	scriptOut := rt.NewWriter(script)
+	writeComponentsCardScript(scriptOut, props)
+	if err := scriptOut.Err(); err != nil {
+		return err
+	}
+	styleOut := rt.NewWriter(style)
+	writeComponentsCardStyle(styleOut, props)
+	if err := styleOut.Err(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func writeComponentsCardMarkup(out *rt.Writer, props ComponentsCardProps) {
+//line templates/components/Card.html:5:1
+	out.WriteString("<div x-data=\"{&quot;featured&quot;: ")
+//line templates/components/Card.html:3:1
+	if props.Featured == nil {
+		out.WriteString("false")
+	} else {
+		out.WriteString(rt.EscapeAttr(rt.JSValue(props.Featured, false), true))
+	}
+//line templates/components/Card.html:5:1
+	out.WriteString(", &quot;label&quot;: ")
+//line templates/components/Card.html:2:1
+	if props.Label == nil {
+		out.WriteString("null")
+	} else {
+		out.WriteString(rt.EscapeAttr(rt.JSValue(props.Label, false), true))
+	}
+//line templates/components/Card.html:5:1
+	out.WriteString("}\"><p class=\"card\"><span x-text=\"label\"></span></p></div>")
+}
+
+func writeComponentsCardScript(out *rt.Writer, props ComponentsCardProps) {
+}
+
+func writeComponentsCardStyle(out *rt.Writer, props ComponentsCardProps) {
+//line templates/components/Card.html:6:1
+	out.WriteString("\n\t.card { margin: 0; }\n\n")
+}
@@ -0,0 +1,5 @@
+// Package example holds Go code generated from the templates in its templates
+// directory, checked against the engine by its tests
+package example
+
+//go:generate go run ../../../cmd/gotmpl generate -dir templates -out .
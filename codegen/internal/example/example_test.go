@@ -0,0 +1,105 @@
+package example
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/codegen"
+	"github.com/jimafisk/custom_go_template/engine"
+)
+
+func TestGeneratedCodeIsCurrent(t *testing.T) {
+	templates := os.DirFS("templates")
+	paths, err := codegen.Templates(templates, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := codegen.Generate(codegen.Options{FS: templates, Package: "example", LineDir: "templates"}, paths...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, file := range files {
+		current, err := os.ReadFile(file.FileName())
+		if err != nil || !bytes.Equal(current, file.Source) {
+			t.Errorf("%s is out of date, run go generate", file.FileName())
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	pages := engine.New(engine.Options{FS: os.DirFS("templates"), Logger: log.New(io.Discard, "", 0)})
+
+	tests := []struct {
+		name   string
+		page   string
+		props  map[string]any
+		render func(markup, script, style io.Writer) error
+	}{
+		{
+			name: "defaults",
+			page: "pages/index.html",
+			render: func(markup, script, style io.Writer) error {
+				return RenderPagesIndexParts(markup, script, style, PagesIndexProps{})
+			},
+		},
+		{
+			name:  "props",
+			page:  "pages/index.html",
+			props: map[string]any{"title": `It's "<Home>" & more`, "tags": []any{"go", map[string]any{"name": "alpine", "stars": 4.5}}},
+			render: func(markup, script, style io.Writer) error {
+				return RenderPagesIndexParts(markup, script, style, PagesIndexProps{
+					Title: `It's "<Home>" & more`,
+					Tags:  []any{"go", map[string]any{"name": "alpine", "stars": 4.5}},
+				})
+			},
+		},
+		{
+			name:  "component",
+			page:  "components/Card.html",
+			props: map[string]any{"label": "Featured", "featured": true},
+			render: func(markup, script, style io.Writer) error {
+				return RenderComponentsCardParts(markup, script, style, ComponentsCardProps{Label: "Featured", Featured: true})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := pages.Render(tt.page, tt.props)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var markup, script, style strings.Builder
+			if err := tt.render(&markup, &script, &style); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if markup.String() != want.Markup {
+				t.Errorf("Markup\n%s\nwant\n%s", markup.String(), want.Markup)
+			}
+			if script.String() != want.Script {
+				t.Errorf("Script\n%s\nwant\n%s", script.String(), want.Script)
+			}
+			if style.String() != want.Style {
+				t.Errorf("Style\n%s\nwant\n%s", style.String(), want.Style)
+			}
+		})
+	}
+}
+
+func TestRenderDocument(t *testing.T) {
+	var out strings.Builder
+	if err := RenderPagesIndex(&out, PagesIndexProps{Title: "Docs"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := out.String()
+	if !strings.HasPrefix(got, `<div x-data="{`) || !strings.Contains(got, "<style>") || !strings.HasSuffix(got, "</style>") {
+		t.Errorf("Expected the markup followed by the style, got %s", got)
+	}
+	if !strings.Contains(got, "&#39;Docs&#39;") {
+		t.Errorf("Expected the title in the Alpine.js data, got %s", got)
+	}
+}
@@ -0,0 +1,78 @@
+// Code generated by gotmpl generate from pages/index.html. DO NOT EDIT.
+
+package example
+
+import (
+	"io"
+
+	"github.com/jimafisk/custom_go_template/codegen/rt"
+)
+
+// PagesIndexProps holds the props of pages/index.html.
+// A nil prop takes the default of its declaration, like a prop left out of the
+// props given to renderer.Render.
+type PagesIndexProps struct {
+	Title any // title
+	Tags  any // tags
+}
+
+// RenderPagesIndex writes pages/index.html: its markup, followed by its
+// style and script in <style> and <script> elements when it has them
+func RenderPagesIndex(w io.Writer, props PagesIndexProps) error {
+	out := rt.NewWriter(w)
+	writePagesIndexMarkup(out, props)
+	out.WriteString("<style>")
+	writePagesIndexStyle(out, props)
+	out.WriteString("</style>")
+	return out.Err()
+}
+
+// RenderPagesIndexParts writes the markup, script and style of pages/index.html
+// to separate writers, like the fields of renderer.Result
+func RenderPagesIndexParts(markup, script, style io.Writer, props PagesIndexProps) error {
+	markupOut := rt.NewWriter(markup)
+	writePagesIndexMarkup(markupOut, props)
+	if err := markupOut.Err(); err != nil {
+		return err
+	}
+	scriptOut := rt.NewWriter(script)
+	writePagesIndexScript(scriptOut, props)
+	if err := scriptOut.Err(); err != nil {
+		return err
+	}
+	styleOut := rt.NewWriter(style)
+	writePagesIndexStyle(styleOut, props)
+	if err := styleOut.Err(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func writePagesIndexMarkup(out *rt.Writer, props PagesIndexProps) {
+//line templates/pages/index.html:7:1
+	out.WriteString("<div x-data=\"{&quot;&quot;: null, &quot;count&quot;: 0, &quot;tag&quot;: null, &quot;tags&quot;: ")
+//line templates/pages/index.html:4:1
+	if props.Tags == nil {
+		out.WriteString("[]")
+	} else {
+		out.WriteString(rt.EscapeAttr(rt.JSValue(props.Tags, false), true))
+	}
+//line templates/pages/index.html:7:1
+	out.WriteString(", &quot;title&quot;: ")
+//line templates/pages/index.html:3:1
+	if props.Title == nil {
+		out.WriteString("&quot;Home&quot;")
+	} else {
+		out.WriteString(rt.EscapeAttr(rt.JSValue(props.Title, false), true))
+	}
+//line templates/pages/index.html:7:1
+	out.WriteString("}\"><main>  <h1><span x-text=\"title\"></span></h1><div x-data=\"{&quot;featured&quot;: false, get label() { return title }}\" x-component=\"Card\" style=\"display: contents\"><p class=\"card\"><span x-text=\"label\"></span></p></div>  <ul><template x-for=\"(tag, ) in tags\"><li><span x-text=\"tag\"></span></li></template></ul>  <button @click=\"count++\">Clicked <span x-text=\"count\"></span> times</button> </main></div>")
+}
+
+func writePagesIndexScript(out *rt.Writer, props PagesIndexProps) {
+}
+
+func writePagesIndexStyle(out *rt.Writer, props PagesIndexProps) {
+//line templates/pages/index.html:17:1
+	out.WriteString("\n\t.card { margin: 0; }\n\n\n\th1 { color: rebeccapurple; }\n\n")
+}
@@ -0,0 +1,126 @@
+// Package rt holds the helpers of the Go code generated by codegen. It encodes
+// props the way the transformer and renderer do, without parsing templates,
+// regular expressions or a JavaScript runtime.
+package rt
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// JSValue formats a prop as a JavaScript value, like the transformer formats the
+// data of an Alpine.js component. Props are plain values: nil, booleans, numbers,
+// strings, []any and map[string]any. htmlQuotes quotes strings and keys with
+// &quot; instead of quotes, like the transformer does for some data scopes.
+func JSValue(value any, htmlQuotes bool) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case float32, float64:
+		return fmt.Sprintf("%g", v)
+	case string:
+		return jsString(v, htmlQuotes)
+	case []any:
+		elements := make([]string, len(v))
+		for i, element := range v {
+			elements[i] = JSValue(element, htmlQuotes)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		properties := make([]string, len(keys))
+		for i, key := range keys {
+			if htmlQuotes {
+				properties[i] = fmt.Sprintf("&quot;%s&quot;: %s", key, JSValue(v[key], htmlQuotes))
+			} else {
+				properties[i] = fmt.Sprintf("\"%s\": %s", key, JSValue(v[key], htmlQuotes))
+			}
+		}
+		return "{" + strings.Join(properties, ", ") + "}"
+	default:
+		return fmt.Sprintf("'%v'", v)
+	}
+}
+
+// jsString quotes a string for JavaScript, keeping function expressions as they are
+func jsString(s string, htmlQuotes bool) string {
+	if isFunctionExpression(s) {
+		return s
+	}
+	escaped := strings.ReplaceAll(s, "\\", "\\\\")
+	escaped = strings.ReplaceAll(escaped, "'", "\\'")
+	escaped = strings.ReplaceAll(escaped, "\n", "\\n")
+	escaped = strings.ReplaceAll(escaped, "\r", "\\r")
+	escaped = strings.ReplaceAll(escaped, "\t", "\\t")
+	if htmlQuotes {
+		return "&quot;" + strings.ReplaceAll(escaped, "\"", "&quot;") + "&quot;"
+	}
+	return "'" + escaped + "'"
+}
+
+// Text formats a prop written as it is
+func Text(value any) string {
+	return fmt.Sprint(value)
+}
+
+// isFunctionExpression reports whether a string looks like a JavaScript function
+// expression, which the transformer emits as code rather than as a string
+func isFunctionExpression(expr string) bool {
+	expr = strings.TrimSpace(expr)
+	return strings.HasPrefix(expr, "function") ||
+		strings.HasPrefix(expr, "()") ||
+		strings.Contains(expr, "=>") ||
+		strings.Contains(expr, "function(") ||
+		(strings.Contains(expr, "(") && strings.Contains(expr, ")") && strings.Contains(expr, "{") && strings.Contains(expr, "}"))
+}
+
+// EscapeAttr escapes a value for an HTML attribute like the renderer does,
+// escaping single quotes too when singleQuotes is set
+func EscapeAttr(value string, singleQuotes bool) string {
+	value = strings.ReplaceAll(value, `&`, `&amp;`)
+	value = strings.ReplaceAll(value, `"`, `&quot;`)
+	value = strings.ReplaceAll(value, `<`, `&lt;`)
+	value = strings.ReplaceAll(value, `>`, `&gt;`)
+	if singleQuotes {
+		value = strings.ReplaceAll(value, "'", `&#39;`)
+	}
+	return value
+}
+
+// Writer writes the output of a generated template, keeping the first error so
+// the generated code doesn't check every write
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+// NewWriter returns a Writer writing to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteString writes s unless an earlier write failed
+func (w *Writer) WriteString(s string) {
+	if w.err != nil || s == "" {
+		return
+	}
+	_, w.err = io.WriteString(w.w, s)
+}
+
+// Err returns the first error a write returned
+func (w *Writer) Err() error {
+	return w.err
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/modules"
+	"github.com/jimafisk/custom_go_template/renderer"
+)
+
+// DefaultCacheSize is the number of transformed pages an engine keeps when
+// Options.CacheSize is 0
+const DefaultCacheSize = 1000
+
+// CacheStats reports how well an engine reuses its work
+type CacheStats struct {
+	Hits      uint64 // renders of a page already transformed with the same props
+	Misses    uint64 // renders that transformed the page
+	Evictions uint64 // transformed pages dropped to stay within the cache size
+	Entries   int    // transformed pages kept
+	Capacity  int    // transformed pages kept at most, 0 when caching is off
+
+	// Modules reports the parsing of the pages and components, which is shared
+	// by every render whatever its props
+	Modules modules.Stats
+}
+
+// cachedPage is a page transformed with a set of props
+type cachedPage struct {
+	key      string
+	page     string
+	template *ast.Template
+
+	once   sync.Once
+	result renderer.Result
+}
+
+// render returns the output of the page, generated on first use
+func (p *cachedPage) render(generate func(*ast.Template) renderer.Result) renderer.Result {
+	p.once.Do(func() {
+		p.result = generate(p.template)
+	})
+	return p.result
+}
+
+// pageCache keeps the most recently used transformed pages
+type pageCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // most recently used first
+	stats    CacheStats
+}
+
+func newPageCache(capacity int) *pageCache {
+	if capacity == 0 {
+		capacity = DefaultCacheSize
+	}
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &pageCache{capacity: capacity, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// cacheKey identifies a page transformed with a set of props. Props that can't
+// be encoded, like functions, can't be compared and aren't cached.
+func cacheKey(page string, props map[string]any) (string, bool) {
+	encoded, err := json.Marshal(props)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(encoded)
+	return page + "\x00" + hex.EncodeToString(sum[:]), true
+}
+
+func (c *pageCache) get(key string) (*cachedPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*cachedPage), true
+}
+
+func (c *pageCache) add(page *cachedPage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.capacity == 0 {
+		return
+	}
+	if element, ok := c.entries[page.key]; ok {
+		element.Value = page
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[page.key] = c.order.PushFront(page)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedPage).key)
+		c.stats.Evictions++
+	}
+}
+
+// removePages drops the transformed pages of the given paths
+func (c *pageCache) removePages(pages []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := make(map[string]bool, len(pages))
+	for _, page := range pages {
+		removed[page] = true
+	}
+	for key, element := range c.entries {
+		if removed[element.Value.(*cachedPage).page] {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// clear drops every transformed page
+func (c *pageCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+func (c *pageCache) statistics() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	stats.Capacity = c.capacity
+	return stats
+}
@@ -0,0 +1,223 @@
+// Package engine renders templates with a configuration, component registry and
+// module cache of its own, so several engines, and several renders on one engine,
+// can run at the same time without affecting each other.
+package engine
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/modules"
+	"github.com/jimafisk/custom_go_template/parser"
+	"github.com/jimafisk/custom_go_template/renderer"
+	"github.com/jimafisk/custom_go_template/transformer"
+)
+
+// Mode is the way an engine renders pages
+type Mode int
+
+const (
+	// Client leaves the expressions, conditions and loops of the pages to
+	// Alpine.js, see renderer.Generate
+	Client Mode = iota
+
+	// SSR evaluates them on the server too, so the pages read the same before
+	// Alpine.js starts, see renderer.GenerateSSR
+	SSR
+
+	// Static evaluates them on the server only and drops the Alpine.js
+	// directives, for plain HTML and CSS, see renderer.GenerateStatic
+	Static
+)
+
+var modeNames = []string{"client", "ssr", "static"}
+
+func (m Mode) String() string {
+	if m < 0 || int(m) >= len(modeNames) {
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+	return modeNames[m]
+}
+
+// Set sets the mode from its name, so a Mode can be a flag.Value
+func (m *Mode) Set(name string) error {
+	for i, modeName := range modeNames {
+		if strings.EqualFold(name, modeName) {
+			*m = Mode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown mode %q, want one of %s", name, strings.Join(modeNames, ", "))
+}
+
+// Options configures an engine
+type Options struct {
+	// FS holds the pages and components, like an embed.FS, or a modules.Layered
+	// file system putting the templates of an application over a component
+	// library. Paths given to Render are paths in it. Nil reads the host file
+	// system.
+	FS fs.FS
+
+	// Resolver resolves the component imports of the pages. Nil resolves relative
+	// paths only.
+	Resolver *modules.Resolver
+
+	// SnapshotDerived evaluates derived fence consts on the server, see
+	// transformer.Options
+	SnapshotDerived bool
+
+	// Mode is the way the pages are rendered, Client by default
+	Mode Mode
+
+	// Logger receives the warnings of the engine. Nil uses the standard logger.
+	Logger *log.Logger
+
+	// CacheSize is the number of transformed pages kept, each for a page and a
+	// set of props. 0 uses DefaultCacheSize and a negative size turns the cache off.
+	CacheSize int
+}
+
+// Engine parses, transforms and renders templates. Its methods are safe for
+// concurrent use.
+type Engine struct {
+	options    Options
+	logger     *log.Logger
+	components *transformer.Registry
+	modules    *modules.Graph
+	cache      *pageCache
+}
+
+// New creates an engine with the given options
+func New(options Options) *Engine {
+	logger := options.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Engine{
+		options:    options,
+		logger:     logger,
+		components: transformer.NewRegistry(),
+		modules:    modules.NewGraphFS(options.FS, options.Resolver),
+		cache:      newPageCache(options.CacheSize),
+	}
+}
+
+// RegisterComponent makes a component available to every template the engine
+// transforms, without importing it
+func (e *Engine) RegisterComponent(name string, template *ast.Template, props []string) {
+	e.components.Register(name, template, props)
+	// Pages already transformed may use the component
+	e.cache.clear()
+}
+
+// Parse parses the source of a template
+func (e *Engine) Parse(source string) (*ast.Template, error) {
+	return parser.ParseTemplate(source)
+}
+
+// Transform transforms a parsed template to Alpine.js compatible nodes, using the
+// components registered with the engine. Errors are reported like
+// transformer.Transform reports them.
+func (e *Engine) Transform(template *ast.Template, props map[string]any) (*ast.Template, error) {
+	return transformer.Transform(template, props, e.transformOptions(""))
+}
+
+// Render renders the template at a path, with the components it imports. Errors
+// are reported like renderer.Render reports them.
+//
+// Pages and components are parsed once and kept for the life of the engine, and
+// pages transformed with the same props are rendered from the cache. Before each
+// render the files of the page are checked, and a page whose files changed is
+// loaded and transformed again.
+func (e *Engine) Render(path string, props map[string]any) (renderer.Result, error) {
+	page, err := e.transformFile(path, props)
+	if page == nil {
+		return renderer.Result{}, err
+	}
+	return page.render(e.generate), err
+}
+
+// TransformFile transforms the template at a path like Render, and returns the
+// transformed template. The template is shared with other renders and must not
+// be modified.
+func (e *Engine) TransformFile(path string, props map[string]any) (*ast.Template, error) {
+	page, err := e.transformFile(path, props)
+	if page == nil {
+		return nil, err
+	}
+	return page.template, err
+}
+
+// transformFile returns the page at a path transformed with props, from the
+// cache when it is there. Pages that fail to transform aren't cached.
+func (e *Engine) transformFile(path string, props map[string]any) (*cachedPage, error) {
+	for _, changed := range e.modules.Changed(path) {
+		e.Invalidate(changed)
+	}
+	module, err := e.modules.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, cacheable := cacheKey(module.Path, props)
+	if cacheable {
+		if page, ok := e.cache.get(key); ok {
+			return page, nil
+		}
+	}
+	transformed, err := transformer.Transform(module.Template, props, e.transformOptions(module.Path))
+	page := &cachedPage{key: key, page: module.Path, template: transformed}
+	if cacheable && err == nil {
+		e.cache.add(page)
+	}
+	return page, err
+}
+
+// Invalidate drops a page or component from the engine, with the pages and
+// components using it, so they are loaded and transformed again. Render finds
+// changed files on its own; Invalidate is for changes it can't see, like a file
+// rewritten with the same size and modification time.
+func (e *Engine) Invalidate(path string) {
+	e.cache.removePages(e.modules.Invalidate(path))
+}
+
+// CacheStats returns the statistics of the engine's caches
+func (e *Engine) CacheStats() CacheStats {
+	stats := e.cache.statistics()
+	stats.Modules = e.modules.Stats()
+	return stats
+}
+
+// Modules returns the graph of the pages and components the engine has loaded
+func (e *Engine) Modules() *modules.Graph {
+	return e.modules
+}
+
+// generate returns the output of a transformed page
+func (e *Engine) generate(template *ast.Template) renderer.Result {
+	switch e.options.Mode {
+	case SSR:
+		return renderer.GenerateSSR(template, e.logger)
+	case Static:
+		return renderer.GenerateStatic(template, e.logger)
+	}
+	return renderer.Generate(template, e.logger)
+}
+
+// transformOptions returns the transformer options of a template loaded from path,
+// or of a template that wasn't loaded from a file when path is empty
+func (e *Engine) transformOptions(path string) transformer.Options {
+	options := transformer.Options{
+		SnapshotDerived: e.options.SnapshotDerived,
+		Components:      e.components,
+		Logger:          e.logger,
+	}
+	if path != "" {
+		options.Modules = e.modules
+		options.Path = path
+	}
+	return options
+}
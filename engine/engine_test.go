@@ -0,0 +1,430 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/modules"
+	"github.com/jimafisk/custom_go_template/renderer"
+)
+
+// quietOptions keeps the warnings of the engine out of the test output
+var quietOptions = Options{Logger: log.New(io.Discard, "", 0)}
+
+// writeSite writes the files of a site to a temporary directory and returns it
+func writeSite(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := filepath.ToSlash(t.TempDir())
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestRender(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"index.html": "---\nimport Card from \"./Card.html\";\nprop name;\n---\n<main>\n\t<h1>{name}</h1>\n\t<Card title=\"Hello\" />\n</main>\n",
+		"Card.html":  "---\nprop title;\n---\n<article>{title}</article>\n",
+	})
+	engine := New(quietOptions)
+
+	result, err := engine.Render(root+"/index.html", map[string]any{"name": "World"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, expected := range []string{`<h1><span x-text="name"></span></h1>`, `x-component="Card"`, "<article"} {
+		if !strings.Contains(result.Markup, expected) {
+			t.Errorf("Expected markup to contain %q, got:\n%s", expected, result.Markup)
+		}
+	}
+
+	if _, err := engine.Render(root+"/missing.html", nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a missing template not to exist, got %v", err)
+	}
+}
+
+func TestRenderFS(t *testing.T) {
+	library := fstest.MapFS{
+		"components/Card.html":   {Data: []byte("---\nimport Button from \"./Button.html\";\n---\n<article class=\"library\">\n\t<Button />\n</article>\n")},
+		"components/Button.html": {Data: []byte("<button>Library button</button>")},
+	}
+	app := fstest.MapFS{
+		"pages/index.html":       {Data: []byte("---\nimport Card from \"../components/Card.html\";\n---\n<main>\n\t<Card />\n</main>\n")},
+		"components/Button.html": {Data: []byte("<button>App button</button>")},
+	}
+	engine := New(Options{FS: modules.Layered(app, library), Logger: quietOptions.Logger})
+
+	result, err := engine.Render("pages/index.html", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The app overrides the button the library card imports
+	for _, expected := range []string{`class="library"`, "App button"} {
+		if !strings.Contains(result.Markup, expected) {
+			t.Errorf("Expected markup to contain %q, got:\n%s", expected, result.Markup)
+		}
+	}
+	if strings.Contains(result.Markup, "Library button") {
+		t.Errorf("Expected the library button to be overridden, got:\n%s", result.Markup)
+	}
+}
+
+func TestRenderSSR(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/index.html":     {Data: []byte("---\nimport Card from \"../components/Card.html\";\nprop user;\n---\n<main>\n\t<h1>Hi {user.name}</h1>\n\t<Card label={user.name} />\n\t<ul>\n\t\t{for tag in user.tags}\n\t\t\t<li>{tag}</li>\n\t\t{end}\n\t</ul>\n</main>\n")},
+		"components/Card.html": {Data: []byte("---\nprop label;\n---\n<p class=\"card\">{label}</p>\n")},
+	}
+	engine := New(Options{FS: fsys, Logger: quietOptions.Logger, Mode: SSR})
+
+	props := map[string]any{"user": map[string]any{"name": "Ada", "tags": []any{"go", "js"}}}
+	for i := 0; i < 2; i++ {
+		result, err := engine.Render("pages/index.html", props)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, expected := range []string{
+			`<h1>Hi <span x-text="user.name">Ada</span></h1>`,
+			`<span x-text="label">Ada</span>`,
+			`<li data-ssr="1"><span x-text="tag">go</span></li><li data-ssr="1"><span x-text="tag">js</span></li>`,
+		} {
+			if !strings.Contains(result.Markup, expected) {
+				t.Errorf("Render %d: expected markup to contain %q, got:\n%s", i+1, expected, result.Markup)
+			}
+		}
+	}
+	if stats := engine.CacheStats(); stats.Hits != 1 {
+		t.Errorf("Expected the second render to come from the cache, got %+v", stats)
+	}
+}
+
+func TestRenderStatic(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/index.html":     {Data: []byte("---\nimport Card from \"../components/Card.html\";\nprop user;\n---\n<main>\n\t<h1>Hi {user.name}</h1>\n\t<Card label={user.name} />\n\t<ul>\n\t\t{for tag in user.tags}\n\t\t\t<li>{tag}</li>\n\t\t{end}\n\t</ul>\n</main>\n")},
+		"components/Card.html": {Data: []byte("---\nprop label;\n---\n<p class=\"card\">{label}</p>\n<style>.card { color: red; }</style>\n")},
+	}
+	engine := New(Options{FS: fsys, Logger: quietOptions.Logger, Mode: Static})
+
+	result, err := engine.Render("pages/index.html", map[string]any{"user": map[string]any{"name": "Ada", "tags": []any{"go", "js"}}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, expected := range []string{`<h1>Hi Ada</h1>`, `<p class="card">Ada</p>`, `<li>go</li><li>js</li>`} {
+		if !strings.Contains(result.Markup, expected) {
+			t.Errorf("Expected markup to contain %q, got:\n%s", expected, result.Markup)
+		}
+	}
+	for _, unexpected := range []string{"x-", "<template", "<span"} {
+		if strings.Contains(result.Markup, unexpected) {
+			t.Errorf("Expected markup not to contain %q, got:\n%s", unexpected, result.Markup)
+		}
+	}
+	if result.Script != "" || !strings.Contains(result.Style, ".card") {
+		t.Errorf("Expected the style and no script, got script %q and style %q", result.Script, result.Style)
+	}
+}
+
+func TestCache(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("---\nimport Card from \"./Card.html\";\nprop name;\n---\n<main>\n\t<h1>{name}</h1>\n\t<Card />\n</main>\n")},
+		"about.html": {Data: []byte("<main>About</main>")},
+		"Card.html":  {Data: []byte("<article>Card</article>")},
+	}
+	engine := New(Options{FS: fsys, Logger: quietOptions.Logger, CacheSize: 2})
+
+	render := func(page string, props map[string]any) string {
+		t.Helper()
+		result, err := engine.Render(page, props)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return result.Markup
+	}
+	check := func(step string, hits, misses, evictions, parsed uint64) {
+		t.Helper()
+		stats := engine.CacheStats()
+		if stats.Hits != hits || stats.Misses != misses || stats.Evictions != evictions || stats.Modules.Parsed != parsed {
+			t.Errorf("%s: expected %d hits, %d misses, %d evictions and %d modules parsed, got %+v", step, hits, misses, evictions, parsed, stats)
+		}
+	}
+
+	first := render("index.html", map[string]any{"name": "Ada"})
+	if again := render("index.html", map[string]any{"name": "Ada"}); again != first {
+		t.Errorf("Expected a cached render to match, got:\n%s\n\nwant:\n%s", again, first)
+	}
+	check("same props", 1, 1, 0, 2)
+
+	// Other props transform the page again, without parsing it again
+	render("index.html", map[string]any{"name": "Grace"})
+	check("other props", 1, 2, 0, 2)
+
+	// A changed component is picked up by the pages using it
+	fsys["Card.html"] = &fstest.MapFile{Data: []byte("<article>Edited card</article>"), ModTime: time.Now()}
+	if markup := render("index.html", map[string]any{"name": "Ada"}); !strings.Contains(markup, "Edited card") {
+		t.Errorf("Expected the edited card, got:\n%s", markup)
+	}
+	check("changed component", 1, 3, 0, 3)
+
+	// The least recently used page is evicted
+	render("about.html", nil)
+	render("index.html", map[string]any{"name": "Grace"})
+	check("eviction", 1, 5, 1, 4)
+	if stats := engine.CacheStats(); stats.Entries != 2 || stats.Capacity != 2 {
+		t.Errorf("Expected the cache to be full, got %+v", stats)
+	}
+
+	// Registering a component drops the transformed pages
+	engine.RegisterComponent("Badge", &ast.Template{}, nil)
+	render("about.html", nil)
+	check("registered component", 1, 6, 1, 4)
+}
+
+func TestRenderErrors(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"index.html":   "---\nimport List from \"./List.html\";\n---\n<main>\n\t<List />\n</main>\n",
+		"List.html":    "---\nimport Item from \"./Item.html\";\n---\n<ul>\n\t<Item />\n</ul>\n",
+		"Item.html":    "<li>\n\t<p>Item\n",
+		"missing.html": "<main>\n\t<Card />\n</main>\n",
+		"eval.html":    "---\nlet width = window.innerWidth;\nlet half = width / 2;\n---\n<main>{half}</main>\n",
+	})
+	engine := New(quietOptions)
+
+	// An error in a module fails the whole page
+	result, err := engine.Render(root+"/index.html", nil)
+	var parseErr *renderer.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+	if parseErr.File != root+"/Item.html" || strings.Join(parseErr.ImportChain, " ") != root+"/index.html "+root+"/List.html" {
+		t.Errorf("Expected Item.html imported through List.html, got %v", err)
+	}
+	if result != (renderer.Result{}) {
+		t.Errorf("Expected an empty result, got %+v", result)
+	}
+
+	// Errors found while transforming still render the page
+	result, err = engine.Render(root+"/missing.html", nil)
+	var notFound *renderer.ComponentNotFoundError
+	if !errors.As(err, &notFound) || notFound.Name != "Card" || notFound.Pos.Line != 2 {
+		t.Errorf("Expected Card not to be found on line 2, got %v", err)
+	}
+	if !strings.Contains(result.Markup, "Component not found: Card") {
+		t.Errorf("Expected a placeholder for Card, got:\n%s", result.Markup)
+	}
+
+	result, err = engine.Render(root+"/eval.html", nil)
+	var evalErr *renderer.EvalError
+	if !errors.As(err, &evalErr) || evalErr.File != root+"/eval.html" || evalErr.Pos.Line != 2 {
+		t.Errorf("Expected the fence to fail on line 2, got %v", err)
+	}
+	if !strings.Contains(result.Markup, "<main") {
+		t.Errorf("Expected the page to be rendered, got:\n%s", result.Markup)
+	}
+}
+
+func TestRegisteredComponents(t *testing.T) {
+	badge := &ast.Template{RootNodes: []ast.Node{
+		&ast.Element{TagName: "span", Attributes: []ast.Attribute{{Name: "class", Value: "badge"}}},
+	}}
+	page := &ast.Template{RootNodes: []ast.Node{&ast.ComponentNode{Name: "Badge"}}}
+
+	first := New(quietOptions)
+	first.RegisterComponent("Badge", badge, nil)
+	second := New(quietOptions)
+
+	// Components registered with one engine aren't visible to another, and stay
+	// registered across transformations
+	for i := 0; i < 2; i++ {
+		markup := renderTemplate(first, page).Markup
+		if !strings.Contains(markup, `class="badge"`) {
+			t.Errorf("Expected the first engine to render the badge, got:\n%s", markup)
+		}
+	}
+	if markup := renderTemplate(second, page).Markup; strings.Contains(markup, `class="badge"`) {
+		t.Errorf("Expected the second engine not to know the badge, got:\n%s", markup)
+	}
+}
+
+// TestConcurrentRenders renders many pages at once; run with -race to check the
+// renders don't share state
+func TestConcurrentRenders(t *testing.T) {
+	files := map[string]string{
+		"components/Counter.html": "---\nprop step;\nlet count = 0;\n---\n<button @click=\"count += step\">{count}</button>\n",
+		"components/Tree.html":    "---\nprop node;\n---\n<li>\n\t{node.name}\n\t{if node.children}\n\t\t<ul>\n\t\t\t{for child in node.children}\n\t\t\t\t<Tree node={child} />\n\t\t\t{end}\n\t\t</ul>\n\t{/if}\n</li>\n",
+	}
+	files["components/Tree.html"] = "---\nimport Tree from \"./Tree.html\";\n" + strings.TrimPrefix(files["components/Tree.html"], "---\n")
+	const pages = 8
+	for i := 0; i < pages; i++ {
+		files[fmt.Sprintf("pages/page%d.html", i)] = fmt.Sprintf(
+			"---\nimport Counter from \"../components/Counter.html\";\nimport Tree from \"../components/Tree.html\";\nstore theme%d = { dark: false };\nlet title = \"Page %d\";\n---\n<main>\n\t<h1>{title}</h1>\n\t<Counter step={%d} />\n\t<ul>\n\t\t<Tree node={{ name: \"root\", children: [] }} />\n\t</ul>\n\t<p :class=\"$theme%d.dark ? 'dark' : ''\">Page</p>\n</main>\n",
+			i, i, i+1, i)
+	}
+	root := writeSite(t, files)
+	engine := New(quietOptions)
+
+	// The output of each page rendered alone, the first page last so the graph
+	// holds the other pages by then
+	expected := make([]string, pages)
+	for i := pages - 1; i >= 0; i-- {
+		result, err := engine.Render(fmt.Sprintf("%s/pages/page%d.html", root, i), nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected[i] = result.Markup + result.Script
+	}
+	// A page only gets the stores of the modules it uses
+	for _, want := range []string{"Alpine.store('theme0'", `x-component="Tree"`, `x-component="Counter"`} {
+		if !strings.Contains(expected[0], want) {
+			t.Fatalf("Expected page 0 to contain %q, got:\n%s", want, expected[0])
+		}
+	}
+	if strings.Contains(expected[0], "theme1") {
+		t.Errorf("Expected page 0 not to get the store of page 1, got:\n%s", expected[0])
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 64; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			i := n % pages
+			result, err := engine.Render(fmt.Sprintf("%s/pages/page%d.html", root, i), nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if result.Markup+result.Script != expected[i] {
+				t.Errorf("Page %d rendered concurrently differs from the page rendered alone:\n%s\n\nwant:\n%s", i, result.Markup+result.Script, expected[i])
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+// renderTemplate transforms a parsed template with an engine and renders it
+func renderTemplate(e *Engine, template *ast.Template) renderer.Result {
+	transformed, _ := e.Transform(template, map[string]any{})
+	return renderer.Generate(transformed, e.logger)
+}
+
+func TestModeSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Mode
+		wantErr bool
+	}{
+		{name: "client", want: Client},
+		{name: "SSR", want: SSR},
+		{name: "static", want: Static},
+		{name: "hybrid", wantErr: true},
+	}
+	for _, tt := range tests {
+		var mode Mode
+		err := mode.Set(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%q): unexpected error %v", tt.name, err)
+			continue
+		}
+		if !tt.wantErr && (mode != tt.want || mode.String() != strings.ToLower(tt.name)) {
+			t.Errorf("Set(%q) = %v, want %v", tt.name, mode, tt.want)
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"index.html": "---\nimport Field from \"./Field.html\";\nlet { a } = { a: 1 };\nlet b = 2;\nonMount(1);\nsetContext(b);\n$: console.log('once');\n---\n<main>\n\t<p x-show=\"b\" transition:fade={{wobble: 2}}>{b}</p>\n\t<Field bind:value={b + 1} />\n\t<button @click.bogus=\"b++\">More</button>\n</main>\n",
+		"Field.html": "---\nprop value;\n---\n<input />\n",
+	})
+	var logs strings.Builder
+	engine := New(Options{Logger: log.New(&logs, "", 0)})
+
+	if _, err := engine.Render(root+"/index.html", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"Destructuring declarations in the fence are not supported",
+		"onMount expects a function",
+		"setContext expects a string key and a value",
+		"does not depend on any fence variable",
+		"Unknown transition parameter 'wobble'",
+		"Cannot bind prop 'value'",
+		"Unknown modifier 'bogus'",
+	} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("Expected the engine's logger to receive %q, got:\n%s", expected, logs.String())
+		}
+	}
+}
+
+func TestComponentRoots(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("---\nimport Cells from \"./Cells.html\";\n---\n<table>\n\t<tbody>\n\t\t<tr>\n\t\t\t<Cells price={4} />\n\t\t</tr>\n\t</tbody>\n</table>\n")},
+		"Cells.html": {Data: []byte("---\nprop price;\n---\n<td>{price}</td>\n<td>{price * 2}</td>\n")},
+	}
+
+	for _, mode := range []Mode{Client, SSR, Static} {
+		engine := New(Options{FS: fsys, Logger: quietOptions.Logger, Mode: mode})
+		result, err := engine.Render("index.html", nil)
+		if err != nil {
+			t.Fatalf("Mode %v: unexpected error: %v", mode, err)
+		}
+		// Alpine.js only renders the first element of a template, so the cells
+		// must be in the page itself
+		if strings.Contains(result.Markup, "<template") {
+			t.Errorf("Mode %v: expected no template around the cells, got:\n%s", mode, result.Markup)
+		}
+		if cells := strings.Count(result.Markup, "<td"); cells != 2 {
+			t.Errorf("Mode %v: expected 2 cells, got %d:\n%s", mode, cells, result.Markup)
+		}
+	}
+}
+
+func TestPropDerivedValues(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("---\nimport Card from \"./Card.html\";\nlet title = 'hello';\n---\n<main>\n\t<Card label={title} />\n\t<Card label=\"static\" />\n</main>\n")},
+		"Card.html":  {Data: []byte("---\nprop label = 'default';\nlet up = label.toUpperCase();\nlet count = 1;\nlet next = count + 1;\n---\n<p>{up} {next}</p>\n")},
+	}
+
+	tests := []struct {
+		mode     Mode
+		contains []string
+	}{
+		{mode: Client, contains: []string{
+			`get up() { return this.label.toUpperCase() }`,
+			`get label() { return title }`,
+			`&quot;next&quot;: 2`,
+		}},
+		{mode: SSR, contains: []string{`<span x-text="up">HELLO</span>`, `<span x-text="up">STATIC</span>`}},
+		{mode: Static, contains: []string{`<p>HELLO 2</p>`, `<p>STATIC 2</p>`}},
+	}
+
+	for _, tt := range tests {
+		engine := New(Options{FS: fsys, Logger: quietOptions.Logger, Mode: tt.mode})
+		result, err := engine.Render("index.html", nil)
+		if err != nil {
+			t.Fatalf("Mode %v: unexpected error: %v", tt.mode, err)
+		}
+		for _, expected := range tt.contains {
+			if !strings.Contains(result.Markup, expected) {
+				t.Errorf("Mode %v: expected markup to contain %q, got:\n%s", tt.mode, expected, result.Markup)
+			}
+		}
+	}
+}
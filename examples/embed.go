@@ -0,0 +1,10 @@
+// Package examples holds the example pages and components, embedded so the
+// example server runs from any directory
+package examples
+
+import "embed"
+
+// FS holds the pages and components directories
+//
+//go:embed pages components
+var FS embed.FS
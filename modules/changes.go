@@ -0,0 +1,145 @@
+package modules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// Stats counts the work of a graph
+type Stats struct {
+	Modules int    // modules in the graph
+	Parsed  uint64 // modules parsed
+	Reused  uint64 // modules loaded again without parsing, their content unchanged
+}
+
+// fileInfo is what a module's file looked like when it was read
+type fileInfo struct {
+	size    int64
+	modTime time.Time
+}
+
+// readModule reads the content of a module's file, with its size and
+// modification time
+func readModule(fsys fs.FS, modulePath string) ([]byte, fileInfo, error) {
+	file, err := fsys.Open(modulePath)
+	if err != nil {
+		return nil, fileInfo{}, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fileInfo{}, err
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fileInfo{}, err
+	}
+	return content, fileInfo{size: stat.Size(), modTime: stat.ModTime()}, nil
+}
+
+// contentHash returns the hash modules are compared by
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Changed returns the paths of the modules a page depends on, itself included,
+// whose files changed or were removed since they were loaded. Files with the
+// size and modification time they were loaded with are assumed unchanged;
+// others are compared by the hash of their content.
+func (g *Graph) Changed(file string) []string {
+	g.mu.RLock()
+	var modules []*Module
+	var infos []fileInfo
+	pending := []string{g.cleanPath(file)}
+	seen := make(map[string]bool)
+	for len(pending) > 0 {
+		modulePath := pending[0]
+		pending = pending[1:]
+		module, ok := g.modules[modulePath]
+		if !ok || seen[modulePath] {
+			continue
+		}
+		seen[modulePath] = true
+		modules = append(modules, module)
+		infos = append(infos, module.info)
+		for _, dependency := range module.Imports {
+			pending = append(pending, dependency)
+		}
+	}
+	g.mu.RUnlock()
+
+	// Files are read without the graph locked
+	var changed []string
+	touched := make(map[*Module]fileInfo)
+	for i, module := range modules {
+		stat, err := fs.Stat(g.fsys, module.Path)
+		if err == nil && stat.Size() == infos[i].size && stat.ModTime().Equal(infos[i].modTime) {
+			continue
+		}
+		content, info, err := readModule(g.fsys, module.Path)
+		if err != nil || contentHash(content) != module.Hash {
+			changed = append(changed, module.Path)
+			continue
+		}
+		touched[module] = info
+	}
+
+	if len(touched) > 0 {
+		// Files saved without changes aren't hashed again
+		g.mu.Lock()
+		for module, info := range touched {
+			module.info = info
+		}
+		g.mu.Unlock()
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Invalidate removes a module from the graph with the modules that depend on it,
+// directly or indirectly, so they are loaded again on their next Load. It
+// returns the paths of the removed modules. Removed modules whose content is
+// unchanged when they are loaded again aren't parsed again.
+func (g *Graph) Invalidate(file string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var removed []string
+	pending := []string{g.cleanPath(file)}
+	for len(pending) > 0 {
+		modulePath := pending[0]
+		pending = pending[1:]
+		module, ok := g.modules[modulePath]
+		if !ok {
+			continue
+		}
+		delete(g.modules, modulePath)
+		g.invalidated[modulePath] = module
+		removed = append(removed, modulePath)
+
+		for _, importer := range g.paths() {
+			for _, dependency := range g.modules[importer].Imports {
+				if dependency == modulePath {
+					pending = append(pending, importer)
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// Stats returns the number of modules in the graph and the work done loading them
+func (g *Graph) Stats() Stats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	stats := g.stats
+	stats.Modules = len(g.modules)
+	return stats
+}
@@ -0,0 +1,65 @@
+package modules
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestGraphChanges(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":     {Data: []byte("---\nimport List from \"./List.html\";\n---\n<main>\n\t<List />\n</main>\n")},
+		"about.html":     {Data: []byte("<main>About</main>")},
+		"List.html":      {Data: []byte("---\nimport Item from \"./Item.html\";\n---\n<ul>\n\t<Item />\n</ul>\n")},
+		"Item.html":      {Data: []byte("<li>Item</li>")},
+		"Unrelated.html": {Data: []byte("<p>Unrelated</p>")},
+	}
+	graph := NewGraphFS(fsys, nil)
+	for _, page := range []string{"index.html", "about.html", "Unrelated.html"} {
+		if _, err := graph.Load(page); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if changed := graph.Changed("index.html"); len(changed) != 0 {
+		t.Errorf("Expected no changes, got %v", changed)
+	}
+
+	// A file saved without changes is not a change
+	fsys["Item.html"].ModTime = time.Now()
+	if changed := graph.Changed("index.html"); len(changed) != 0 {
+		t.Errorf("Expected a file saved as is not to change, got %v", changed)
+	}
+
+	fsys["Item.html"] = &fstest.MapFile{Data: []byte("<li>Edited</li>"), ModTime: time.Now()}
+	if changed := graph.Changed("index.html"); !reflect.DeepEqual(changed, []string{"Item.html"}) {
+		t.Errorf("Expected Item.html to change, got %v", changed)
+	}
+	if changed := graph.Changed("about.html"); len(changed) != 0 {
+		t.Errorf("Expected pages that don't use Item.html not to change, got %v", changed)
+	}
+
+	// The modules using the component are invalidated with it
+	removed := graph.Invalidate("Item.html")
+	if expected := []string{"Item.html", "List.html", "index.html"}; !reflect.DeepEqual(removed, expected) {
+		t.Errorf("Expected %v to be invalidated, got %v", expected, removed)
+	}
+	before := graph.Stats()
+	if _, err := graph.Load("index.html"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	after := graph.Stats()
+	// Only the component that changed is parsed again
+	if after.Parsed-before.Parsed != 1 || after.Reused-before.Reused != 2 || after.Modules != 5 {
+		t.Errorf("Expected 1 module parsed and 2 reused out of 5, got %+v then %+v", before, after)
+	}
+	if module, _ := graph.Module("Item.html"); module == nil || module.Hash == "" {
+		t.Errorf("Expected Item.html to be loaded again, got %v", module)
+	}
+
+	// A removed file is a change
+	delete(fsys, "Item.html")
+	if changed := graph.Changed("index.html"); !reflect.DeepEqual(changed, []string{"Item.html"}) {
+		t.Errorf("Expected the removed Item.html to change, got %v", changed)
+	}
+}
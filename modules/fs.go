@@ -0,0 +1,108 @@
+package modules
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// hostFS reads files from the host file system by their path as given, absolute
+// or relative to the working directory. It backs graphs created without a file
+// system, and accepts paths os.DirFS would reject.
+type hostFS struct{}
+
+func (hostFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (hostFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// Layered returns a file system made of layers, from the top down. A file is
+// read from the first layer that has it, so files of an application can
+// override the files of a component library below it. Directories list the
+// files of every layer.
+func Layered(layers ...fs.FS) fs.FS {
+	return layeredFS(layers)
+}
+
+type layeredFS []fs.FS
+
+func (l layeredFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range l {
+		file, err := layer.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := file.Stat()
+		if err != nil || !info.IsDir() {
+			return file, err
+		}
+		// A directory lists the files of the layers below it too
+		entries, err := l.ReadDir(name)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &layeredDir{File: file, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (l layeredFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	seen := make(map[string]bool)
+	var entries []fs.DirEntry
+	found := false
+	for _, layer := range l {
+		layerEntries, err := fs.ReadDir(layer, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, entry := range layerEntries {
+			// Upper layers shadow the files of the same name below them
+			if !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// layeredDir is a directory of a layered file system, listing the files of
+// every layer
+type layeredDir struct {
+	fs.File
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *layeredDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.entries[d.offset:]
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	if n > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	}
+	d.offset += len(entries)
+	return entries, nil
+}
@@ -0,0 +1,49 @@
+package modules
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLayered(t *testing.T) {
+	app := fstest.MapFS{
+		"components/Card.html": {Data: []byte("<div>App card</div>")},
+		"pages/index.html":     {Data: []byte("<main>Home</main>")},
+	}
+	lib := fstest.MapFS{
+		"components/Card.html":   {Data: []byte("<div>Library card</div>")},
+		"components/Button.html": {Data: []byte("<button>OK</button>")},
+	}
+	fsys := Layered(app, lib)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"components/Card.html", "<div>App card</div>"},
+		{"components/Button.html", "<button>OK</button>"},
+		{"pages/index.html", "<main>Home</main>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := fs.ReadFile(fsys, tt.name)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(content) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, content)
+			}
+		})
+	}
+
+	if _, err := fs.ReadFile(fsys, "components/Missing.html"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a missing file not to exist, got %v", err)
+	}
+
+	// The layers behave as a single file system, directories included
+	if err := fstest.TestFS(fsys, "components/Card.html", "components/Button.html", "pages/index.html"); err != nil {
+		t.Error(err)
+	}
+}
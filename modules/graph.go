@@ -0,0 +1,294 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/parser"
+)
+
+// importRegex matches a component import in a fence: import Name from "./Name.html"
+var importRegex = regexp.MustCompile(`(?m)^\s*import\s+([A-Za-z_$][A-Za-z0-9_$]*)\s+from\s+["']([^"']+\.html)["']`)
+
+// propRegex matches a prop declaration in a fence
+var propRegex = regexp.MustCompile(`(?:^|[;{}\n])\s*prop\s+([A-Za-z_$][A-Za-z0-9_$]*)`)
+
+// Module is a page or component file, with the components it imports
+type Module struct {
+	Path     string
+	Hash     string // hex SHA-256 of the content
+	Template *ast.Template
+	Props    []string          // props declared in the fence
+	Imports  map[string]string // component names to the paths of their modules
+
+	info fileInfo // the file the module was read from, to find changes
+}
+
+// Graph holds the modules of a set of pages and the imports between them. Loading
+// a page loads every component it depends on, directly or indirectly. A graph is
+// safe for concurrent use; loaded modules must not be modified.
+type Graph struct {
+	fsys     fs.FS
+	resolver *Resolver
+	mu       sync.RWMutex
+	modules  map[string]*Module
+
+	// invalidated holds the modules removed by Invalidate, whose templates are
+	// reused when they are loaded again with the same content
+	invalidated map[string]*Module
+	stats       Stats
+}
+
+// NewGraph creates an empty graph reading modules from the host file system and
+// resolving imports with the given resolver
+func NewGraph(resolver *Resolver) *Graph {
+	return NewGraphFS(nil, resolver)
+}
+
+// NewGraphFS creates an empty graph reading modules from a file system, like an
+// embed.FS or a Layered one, and resolving imports with the given resolver.
+// Module paths are paths in the file system, and absolute specifiers are
+// resolved from its root when the resolver has no Root. Nil reads the host
+// file system.
+func NewGraphFS(fsys fs.FS, resolver *Resolver) *Graph {
+	if fsys == nil {
+		fsys = hostFS{}
+	}
+	if resolver == nil {
+		resolver = &Resolver{}
+	}
+	return &Graph{
+		fsys:        fsys,
+		resolver:    resolver,
+		modules:     make(map[string]*Module),
+		invalidated: make(map[string]*Module),
+	}
+}
+
+// Load parses the module at a path, and the modules it imports, into the graph.
+// Modules already in the graph are not loaded again, so import cycles are fine.
+func (g *Graph) Load(file string) (*Module, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.load(file)
+}
+
+// load loads a module like Load, with the graph locked
+func (g *Graph) load(file string) (*Module, error) {
+	modulePath := g.cleanPath(file)
+	if module, ok := g.modules[modulePath]; ok {
+		return module, nil
+	}
+
+	content, info, err := readModule(g.fsys, modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading module: %w", err)
+	}
+	hash := contentHash(content)
+
+	var template *ast.Template
+	if previous, ok := g.invalidated[modulePath]; ok && previous.Hash == hash {
+		// A module invalidated because a component it uses changed keeps its template
+		template = previous.Template
+		g.stats.Reused++
+	} else {
+		template, err = parser.ParseTemplate(string(content))
+		if err != nil {
+			var parseErr *ast.ParseError
+			if errors.As(err, &parseErr) {
+				parseErr.File = modulePath
+			}
+			return nil, err
+		}
+		g.stats.Parsed++
+	}
+	delete(g.invalidated, modulePath)
+
+	module := &Module{Path: modulePath, Hash: hash, Template: template, Imports: make(map[string]string), info: info}
+	g.modules[modulePath] = module
+
+	var imports []moduleImport
+	for _, node := range template.RootNodes {
+		if fence, ok := node.(*ast.FenceSection); ok {
+			for _, match := range importRegex.FindAllStringSubmatchIndex(fence.RawContent, -1) {
+				imports = append(imports, moduleImport{
+					name:      fence.RawContent[match[2]:match[3]],
+					specifier: fence.RawContent[match[4]:match[5]],
+					pos:       fence.Pos.Advance(fence.RawContent[:match[2]]),
+				})
+			}
+			for _, match := range propRegex.FindAllStringSubmatch(fence.RawContent, -1) {
+				module.Props = append(module.Props, match[1])
+			}
+		}
+	}
+	// Components can also be used by their path in the markup
+	for _, node := range componentPaths(template.RootNodes) {
+		imports = append(imports, moduleImport{name: node.Name, specifier: strings.Trim(node.Name, `"'`), pos: node.Pos})
+	}
+
+	for _, imported := range imports {
+		dependency, err := g.resolver.Resolve(modulePath, imported.specifier)
+		if err == nil {
+			dependency = g.cleanPath(dependency)
+			module.Imports[imported.name] = dependency
+			_, err = g.load(dependency)
+		}
+		if err != nil {
+			delete(g.modules, modulePath)
+			return nil, importError(err, modulePath, imported)
+		}
+	}
+
+	return module, nil
+}
+
+// moduleImport is a component a module imports, or uses by its path
+type moduleImport struct {
+	name      string
+	specifier string
+	pos       ast.Position
+}
+
+// importError reports an import of a module that failed: an import that can't be
+// loaded is a component that isn't found, and errors of the modules it imports in
+// turn are reported with the module added to their import chain
+func importError(err error, importer string, imported moduleImport) error {
+	var parseErr *ast.ParseError
+	var notFound *ast.ComponentNotFoundError
+	var evalErr *ast.EvalError
+	switch {
+	case errors.As(err, &parseErr):
+		parseErr.ImportChain = append([]string{importer}, parseErr.ImportChain...)
+	case errors.As(err, &notFound):
+		notFound.ImportChain = append([]string{importer}, notFound.ImportChain...)
+	case errors.As(err, &evalErr):
+		evalErr.ImportChain = append([]string{importer}, evalErr.ImportChain...)
+	default:
+		return &ast.ComponentNotFoundError{Name: imported.name, File: importer, Pos: imported.pos, Err: err}
+	}
+	return err
+}
+
+// Module returns the module loaded from a path
+func (g *Graph) Module(file string) (*Module, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	module, ok := g.modules[g.cleanPath(file)]
+	return module, ok
+}
+
+// Component returns the module of a component used in the module at importer,
+// either by the name it was imported under or by its path
+func (g *Graph) Component(importer, name string) (*Module, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	module, ok := g.modules[g.cleanPath(importer)]
+	if !ok {
+		return nil, false
+	}
+	dependency, ok := module.Imports[name]
+	if !ok {
+		return nil, false
+	}
+	module, ok = g.modules[dependency]
+	return module, ok
+}
+
+// Modules returns the paths of every module in the graph
+func (g *Graph) Modules() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.paths()
+}
+
+// paths returns the paths of every module in the graph, with the graph locked
+func (g *Graph) paths() []string {
+	paths := make([]string, 0, len(g.modules))
+	for modulePath := range g.modules {
+		paths = append(paths, modulePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Dependencies returns the paths of the modules a module imports directly
+func (g *Graph) Dependencies(file string) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	module, ok := g.modules[g.cleanPath(file)]
+	if !ok {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var paths []string
+	for _, dependency := range module.Imports {
+		if !seen[dependency] {
+			seen[dependency] = true
+			paths = append(paths, dependency)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Dependents returns the paths of the modules that import a module directly
+func (g *Graph) Dependents(file string) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	modulePath := g.cleanPath(file)
+	var paths []string
+	for _, importer := range g.paths() {
+		for _, dependency := range g.modules[importer].Imports {
+			if dependency == modulePath {
+				paths = append(paths, importer)
+				break
+			}
+		}
+	}
+	return paths
+}
+
+// cleanPath normalizes a file path to the form used as a module path
+func (g *Graph) cleanPath(file string) string {
+	file = path.Clean(filepath.ToSlash(file))
+	if _, ok := g.fsys.(hostFS); !ok {
+		// Paths of an fs.FS are unrooted
+		file = strings.TrimPrefix(file, "/")
+	}
+	return file
+}
+
+// componentPaths returns the components in the nodes that are used by the path
+// of their template, like <="./Card.html">. Paths built from expressions are only
+// known when the page is rendered and are left out.
+func componentPaths(nodes []ast.Node) []*ast.ComponentNode {
+	var paths []*ast.ComponentNode
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *ast.ComponentNode:
+			if strings.HasSuffix(strings.Trim(n.Name, `"'`), ".html") && !strings.ContainsAny(n.Name, "{}`") {
+				paths = append(paths, n)
+			}
+		case *ast.Element:
+			paths = append(paths, componentPaths(n.Children)...)
+		case *ast.Conditional:
+			paths = append(paths, componentPaths(n.IfContent)...)
+			for _, content := range n.ElseIfContent {
+				paths = append(paths, componentPaths(content)...)
+			}
+			paths = append(paths, componentPaths(n.ElseContent)...)
+		case *ast.Loop:
+			paths = append(paths, componentPaths(n.Content)...)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,217 @@
+package modules
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+// writeModules writes the files of a site to a temporary directory and returns it
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := filepath.ToSlash(t.TempDir())
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestGraph(t *testing.T) {
+	root := writeModules(t, map[string]string{
+		"pages/index.html":     "---\nimport Card from \"../components/Card.html\";\nimport Button from \"$lib/Button.html\";\n---\n<Card title=\"Hi\" /><Button />",
+		"pages/about.html":     "---\nimport Card from \"/components/Card.html\";\n---\n<section><=\"./Team.html\" /></section>",
+		"pages/Team.html":      "<p>Team</p>",
+		"components/Card.html": "---\nprop title;\nimport Button from \"../lib/Button.html\";\n---\n<div>{title}<Button /></div>",
+		"lib/Button.html":      "---\nprop label = \"OK\";\nimport Card from \"../components/Card.html\";\n---\n<button>{label}</button>",
+	})
+	graph := NewGraph(&Resolver{Root: root, Aliases: map[string]string{"$lib/": "lib"}})
+
+	for _, page := range []string{"pages/index.html", "pages/about.html"} {
+		if _, err := graph.Load(root + "/" + page); err != nil {
+			t.Fatalf("Unexpected error loading %s: %v", page, err)
+		}
+	}
+
+	// Paths in the results are relative to the root, for readability
+	relative := func(paths []string) []string {
+		result := make([]string, len(paths))
+		for i, p := range paths {
+			result[i] = strings.TrimPrefix(p, root+"/")
+		}
+		return result
+	}
+
+	t.Run("modules", func(t *testing.T) {
+		expected := []string{"components/Card.html", "lib/Button.html", "pages/Team.html", "pages/about.html", "pages/index.html"}
+		if result := relative(graph.Modules()); !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected modules %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("dependencies", func(t *testing.T) {
+		expected := []string{"components/Card.html", "lib/Button.html"}
+		if result := relative(graph.Dependencies(root + "/pages/index.html")); !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected dependencies %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("dependents", func(t *testing.T) {
+		expected := []string{"lib/Button.html", "pages/about.html", "pages/index.html"}
+		if result := relative(graph.Dependents(root + "/components/Card.html")); !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected dependents %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("components by name and path", func(t *testing.T) {
+		tests := []struct {
+			importer string
+			name     string
+			expected string
+		}{
+			{"pages/index.html", "Card", "components/Card.html"},
+			{"pages/index.html", "Button", "lib/Button.html"},
+			{"components/Card.html", "Button", "lib/Button.html"},
+			{"pages/about.html", `"./Team.html"`, "pages/Team.html"},
+			{"pages/about.html", "Button", ""},
+		}
+		for _, tt := range tests {
+			module, ok := graph.Component(root+"/"+tt.importer, tt.name)
+			if tt.expected == "" {
+				if ok {
+					t.Errorf("Expected %s not to be imported by %s, got %s", tt.name, tt.importer, module.Path)
+				}
+				continue
+			}
+			if !ok || module.Path != root+"/"+tt.expected {
+				t.Errorf("Expected %s in %s to be %s, got %v", tt.name, tt.importer, tt.expected, module)
+			}
+		}
+	})
+
+	t.Run("props", func(t *testing.T) {
+		module, _ := graph.Module(root + "/components/Card.html")
+		if !reflect.DeepEqual(module.Props, []string{"title"}) {
+			t.Errorf("Expected props [title], got %v", module.Props)
+		}
+	})
+}
+
+func TestGraphErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		check func(t *testing.T, root string, err error)
+	}{
+		{
+			name:  "missing import",
+			files: map[string]string{"index.html": "---\nimport Card from \"./Card.html\";\n---\n<Card />"},
+			check: func(t *testing.T, root string, err error) {
+				var notFound *ast.ComponentNotFoundError
+				if !errors.As(err, &notFound) {
+					t.Fatalf("Expected a ComponentNotFoundError, got %v", err)
+				}
+				if notFound.Name != "Card" || notFound.File != root+"/index.html" || notFound.Pos.Line != 2 || notFound.Pos.Column != 8 {
+					t.Errorf("Expected Card imported at index.html:2:8, got %+v", notFound)
+				}
+				if !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Expected the error to wrap fs.ErrNotExist, got %v", err)
+				}
+			},
+		},
+		{
+			name:  "bare specifier",
+			files: map[string]string{"index.html": "---\nimport Card from \"Card.html\";\n---\n<Card />"},
+			check: func(t *testing.T, root string, err error) {
+				var notFound *ast.ComponentNotFoundError
+				if !errors.As(err, &notFound) || !strings.Contains(err.Error(), `cannot resolve "Card.html"`) {
+					t.Fatalf("Expected a ComponentNotFoundError for the bare specifier, got %v", err)
+				}
+			},
+		},
+		{
+			name:  "missing path component",
+			files: map[string]string{"index.html": "<main>\n\t<=\"./Card.html\" />\n</main>"},
+			check: func(t *testing.T, root string, err error) {
+				var notFound *ast.ComponentNotFoundError
+				if !errors.As(err, &notFound) || notFound.Pos.Line != 2 || notFound.Pos.Column != 2 {
+					t.Fatalf("Expected a ComponentNotFoundError at 2:2, got %v", err)
+				}
+			},
+		},
+		{
+			name: "parse error in an imported component",
+			files: map[string]string{
+				"index.html":           "---\nimport List from \"./lib/List.html\";\n---\n<List />",
+				"lib/List.html":        "---\nimport Item from \"../components/Item.html\";\n---\n<ul>\n\t<Item />\n</ul>",
+				"components/Item.html": "<li class=\"a></li>",
+			},
+			check: func(t *testing.T, root string, err error) {
+				var parseErr *ast.ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("Expected a ParseError, got %v", err)
+				}
+				expected := []string{root + "/index.html", root + "/lib/List.html"}
+				if parseErr.File != root+"/components/Item.html" || !reflect.DeepEqual(parseErr.ImportChain, expected) {
+					t.Errorf("Expected Item.html imported by %v, got %+v", expected, parseErr)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeModules(t, tt.files)
+			graph := NewGraph(&Resolver{Root: root})
+			_, err := graph.Load(root + "/index.html")
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+			tt.check(t, root, err)
+			if len(graph.Modules()) != 0 {
+				t.Errorf("Expected no modules after a failed load, got %v", graph.Modules())
+			}
+		})
+	}
+}
+
+func TestGraphFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/index.html":     {Data: []byte("---\nimport Card from \"../components/Card.html\";\nimport Nav from \"/components/Nav.html\";\n---\n<main>\n\t<Card />\n\t<Nav />\n</main>\n")},
+		"components/Card.html": {Data: []byte("---\nimport Button from \"$lib/Button.html\";\n---\n<div>\n\t<Button />\n</div>\n")},
+		"components/Nav.html":  {Data: []byte("<nav></nav>")},
+		"lib/Button.html":      {Data: []byte("<button>OK</button>")},
+	}
+	graph := NewGraphFS(fsys, &Resolver{Aliases: map[string]string{"$lib/": "lib"}})
+
+	// Paths are paths in the file system, whatever form they are given in
+	if _, err := graph.Load("/pages/index.html"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"components/Card.html", "components/Nav.html", "lib/Button.html", "pages/index.html"}
+	if result := graph.Modules(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected modules %v, got %v", expected, result)
+	}
+	if module, ok := graph.Component("./pages/index.html", "Nav"); !ok || module.Path != "components/Nav.html" {
+		t.Errorf("Expected Nav to resolve from the root of the file system, got %v", module)
+	}
+
+	// Files outside the file system can't be imported
+	fsys["pages/outside.html"] = &fstest.MapFile{Data: []byte("---\nimport Card from \"../../Card.html\";\n---\n<Card />")}
+	var notFound *ast.ComponentNotFoundError
+	if _, err := graph.Load("pages/outside.html"); !errors.As(err, &notFound) || notFound.Name != "Card" {
+		t.Errorf("Expected Card not to be found, got %v", err)
+	}
+}
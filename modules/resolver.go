@@ -0,0 +1,55 @@
+package modules
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Resolver resolves the specifiers of component imports to module paths. Paths
+// use forward slashes and are cleaned, so each file has a single path.
+type Resolver struct {
+	// Root is the directory absolute specifiers like "/components/Nav.html" and
+	// relative alias targets are resolved against
+	Root string
+
+	// Aliases maps specifier prefixes to directories, for example "$lib/" to "src/lib"
+	Aliases map[string]string
+}
+
+// Resolve returns the path of the module a specifier imported by importer refers to.
+// Relative specifiers are resolved against the importer's directory.
+func (r *Resolver) Resolve(importer, specifier string) (string, error) {
+	if alias, target, ok := r.alias(specifier); ok {
+		rest := strings.TrimPrefix(specifier, alias)
+		if !path.IsAbs(target) {
+			target = path.Join(r.Root, target)
+		}
+		return path.Join(target, rest), nil
+	}
+
+	switch {
+	case strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../"):
+		return path.Join(path.Dir(importer), specifier), nil
+	case strings.HasPrefix(specifier, "/"):
+		return path.Join(r.Root, specifier), nil
+	}
+	return "", fmt.Errorf("cannot resolve %q imported by %s: use a relative path or a configured alias", specifier, importer)
+}
+
+// alias returns the longest alias the specifier starts with and its target
+func (r *Resolver) alias(specifier string) (string, string, bool) {
+	prefixes := make([]string, 0, len(r.Aliases))
+	for prefix := range r.Aliases {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(specifier, prefix) {
+			return prefix, r.Aliases[prefix], true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,52 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	resolver := &Resolver{
+		Root: "site",
+		Aliases: map[string]string{
+			"$lib/":       "src/lib",
+			"$lib/icons/": "assets/icons",
+			"@shared/":    "/opt/shared",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		importer  string
+		specifier string
+		expected  string
+		err       string
+	}{
+		{"same directory", "site/pages/index.html", "./Card.html", "site/pages/Card.html", ""},
+		{"parent directory", "site/pages/blog/post.html", "../../components/Nav.html", "site/components/Nav.html", ""},
+		{"importer without directory", "index.html", "./Card.html", "Card.html", ""},
+		{"absolute to the root", "site/pages/blog/post.html", "/components/Nav.html", "site/components/Nav.html", ""},
+		{"alias", "site/pages/index.html", "$lib/Button.html", "site/src/lib/Button.html", ""},
+		{"longest alias wins", "site/pages/index.html", "$lib/icons/Star.html", "site/assets/icons/Star.html", ""},
+		{"absolute alias target", "site/pages/index.html", "@shared/Footer.html", "/opt/shared/Footer.html", ""},
+		{"bare specifier", "site/pages/index.html", "Card.html", "", `cannot resolve "Card.html" imported by site/pages/index.html`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := resolver.Resolve(tt.importer, tt.specifier)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
@@ -85,6 +85,9 @@ func ComponentParser() Parser {
 			Name:    nameOrPath,
 			Dynamic: isDynamic,
 			Props:   props,
+			// The parser only sees the rest of the template, so the tag is located
+			// from the end until ParseTemplate resolves the position
+			Pos: ast.Position{Offset: -len(trimmedInput)},
 		}
 
 		// Calculate how much of the original input to consume
@@ -110,12 +113,15 @@ func parseComponentProps(propString string) []ast.ComponentProp {
 	// - name={...spread} (spread operator)
 	// - {shorthand} (shorthand props)
 
+	// The props written without a value, like bind:value
+	valueless := make(map[int]bool)
+
 	remainingProps := propString
 	for len(strings.TrimSpace(remainingProps)) > 0 {
 		remainingProps = strings.TrimSpace(remainingProps)
 
 		// Check for shorthand prop {prop}
-		if strings.HasPrefix(remainingProps, "{") && !strings.HasPrefix(remainingProps, "{") {
+		if strings.HasPrefix(remainingProps, "{") {
 			closeBracePos := findMatchingCloseBrace(remainingProps, 0)
 			if closeBracePos > 0 {
 				// Check if this is a shorthand prop or the start of a prop assignment
@@ -155,6 +161,7 @@ func parseComponentProps(propString string) []ast.ComponentProp {
 			propName := remainingProps
 			log.Printf("[parseComponentProps] Boolean prop: %s", propName)
 
+			valueless[len(props)] = true
 			props = append(props, ast.ComponentProp{
 				Name:        propName,
 				Value:       "true", // Default value for boolean props
@@ -175,6 +182,7 @@ func parseComponentProps(propString string) []ast.ComponentProp {
 			// This is a boolean prop with no value
 			log.Printf("[parseComponentProps] Boolean prop: %s", propName)
 
+			valueless[len(props)] = true
 			props = append(props, ast.ComponentProp{
 				Name:        propName,
 				Value:       "true", // Default value for boolean props
@@ -189,8 +197,8 @@ func parseComponentProps(propString string) []ast.ComponentProp {
 		remainingProps = strings.TrimSpace(remainingProps[1:])
 
 		// Parse the prop value
-		if strings.HasPrefix(remainingProps, "{") && !strings.HasPrefix(remainingProps, "{") {
-			// Dynamic prop with expression
+		if strings.HasPrefix(remainingProps, "{") {
+			// Dynamic prop with expression, which may contain spaces
 			closeBracePos := findMatchingCloseBrace(remainingProps, 0)
 			if closeBracePos <= 0 {
 				log.Printf("[parseComponentProps] Warning: No matching close brace for prop %s", propName)
@@ -292,6 +300,29 @@ func parseComponentProps(propString string) []ast.ComponentProp {
 		}
 	}
 
+	for i, prop := range props {
+		switch {
+		case strings.HasPrefix(prop.Name, "on:"):
+			// on:event|modifier={handler} listens to events dispatched by the component
+			directive := parseAlpineDirective(prop.Name)
+			log.Printf("[parseComponentProps] Event listener: %s", directive.key)
+			props[i].Name = directive.key
+			props[i].IsEvent = true
+			props[i].Modifiers = directive.modifiers
+		case strings.HasPrefix(prop.Name, "bind:"):
+			// bind:prop={expression} binds the prop both ways, bind:prop is short for bind:prop={prop}
+			props[i].Name = strings.TrimPrefix(prop.Name, "bind:")
+			log.Printf("[parseComponentProps] Bound prop: %s", props[i].Name)
+			if valueless[i] {
+				props[i].Value = props[i].Name
+			} else if value := strings.TrimSpace(prop.Value); strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") {
+				props[i].Value = strings.TrimSpace(value[1 : len(value)-1])
+			}
+			props[i].IsDynamic = true
+			props[i].IsBinding = true
+		}
+	}
+
 	return props
 }
 
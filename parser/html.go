@@ -8,25 +8,22 @@ import (
 	"github.com/jimafisk/custom_go_template/ast"
 )
 
-// Global counter to track element nesting depth
-var elementParserDepth int
-
 const maxElementDepth = 100 // Adjust as needed
 
 // ElementParser parses HTML elements and their children
 func ElementParser() Parser {
+	return elementParser(1)
+}
+
+// elementParser parses an element nested in depth-1 other elements. The depth is
+// carried by the parser rather than a shared counter, so templates can be parsed
+// concurrently.
+func elementParser(depth int) Parser {
 	return func(input string) Result {
 		// Track element depth to prevent infinite recursion
-		elementParserDepth++
-		log.Printf("[ElementParser] DEPTH++ = %d, starting parse of: '%.30s...'", elementParserDepth, input)
-
-		// Ensure we decrement the counter on all exit paths
-		defer func() {
-			elementParserDepth--
-			log.Printf("[ElementParser] DEPTH-- = %d after parse", elementParserDepth)
-		}()
+		log.Printf("[ElementParser] DEPTH = %d, starting parse of: '%.30s...'", depth, input)
 
-		if elementParserDepth > maxElementDepth {
+		if depth > maxElementDepth {
 			return Result{nil, input, false, fmt.Sprintf("maximum element nesting depth (%d) exceeded", maxElementDepth), false}
 		}
 
@@ -122,7 +119,7 @@ func ElementParser() Parser {
 		children := []ast.Node{}
 		if !selfClosing && !isVoidElement(tagName) {
 			log.Printf("[ElementParser] <%s>: Starting to parse children", tagName)
-			childrenRes := parseChildren(remaining, tagName)
+			childrenRes := parseChildren(remaining, tagName, depth)
 			if !childrenRes.Successful {
 				log.Printf("[ElementParser] <%s>: Failed to parse children: %s", tagName, childrenRes.Error)
 				return Result{nil, input, false, fmt.Sprintf("failed to parse children for <%s>: %s", tagName, childrenRes.Error), false}
@@ -233,7 +230,7 @@ func parseAttributes(input string, tagName string) ([]ast.Attribute, string, boo
 }
 
 // parseChildren parses all children of an element until its closing tag
-func parseChildren(input string, parentTag string) Result {
+func parseChildren(input string, parentTag string, depth int) Result {
 	children := []ast.Node{}
 	remaining := input
 
@@ -280,7 +277,7 @@ func parseChildren(input string, parentTag string) Result {
 		}
 
 		// Parse a child node
-		childRes := parseChildNode(remaining)
+		childRes := parseChildNode(remaining, depth)
 		if childRes.Successful {
 			if childNode, ok := childRes.Value.(ast.Node); ok {
 				children = append(children, childNode)
@@ -302,10 +299,10 @@ func parseChildren(input string, parentTag string) Result {
 	return Result{children, remaining, true, "", false}
 }
 
-// parseChildNode attempts to parse a single child node
-func parseChildNode(input string) Result {
+// parseChildNode attempts to parse a single child node of an element at depth
+func parseChildNode(input string, depth int) Result {
 	// Try to parse as element
-	elemRes := ElementParser()(input)
+	elemRes := elementParser(depth + 1)(input)
 	if elemRes.Successful {
 		return elemRes
 	}
@@ -463,6 +460,7 @@ func EnhancedAttributeParser() Parser {
 			IsAlpine:   alpineInfo.isAlpine,
 			AlpineType: alpineInfo.directiveType,
 			AlpineKey:  alpineInfo.key,
+			Modifiers:  alpineInfo.modifiers,
 		}
 
 		if hasValue && value != nil {
@@ -474,10 +472,21 @@ func EnhancedAttributeParser() Parser {
 			}
 		}
 
+		// on:event={handler} takes a braced expression rather than a quoted one
+		if strings.HasPrefix(name, "on:") && isBracedExpression(attr.Value) {
+			attr.Value = strings.TrimSpace(attr.Value[1 : len(attr.Value)-1])
+			attr.Dynamic = true
+		}
+
 		return Result{attr, remaining, true, "", false}
 	}
 }
 
+// isBracedExpression checks if an attribute value is a single {expression}
+func isBracedExpression(value string) bool {
+	return strings.HasPrefix(value, "{") && findMatchingCloseBrace(value, 0) == len(value)-1
+}
+
 // Special parser for x-data attribute values which can contain complex JavaScript object literals
 func parseAlpineDataAttribute(input string) ValueResult {
 	// Check for opening quote
@@ -523,6 +532,13 @@ func parseAttributeValue(input string) Result {
 		return SingleQuotedString()(input)
 	}
 
+	// Unquoted braced value, which may contain whitespace: {() => count++}
+	if strings.HasPrefix(input, "{") {
+		if closeBracePos := findMatchingCloseBrace(input, 0); closeBracePos > 0 {
+			return Result{input[:closeBracePos+1], input[closeBracePos+1:], true, "", false}
+		}
+	}
+
 	// Unquoted value (up to whitespace or >)
 	var builder strings.Builder
 	i := 0
@@ -687,33 +703,92 @@ type alpineDirectiveInfo struct {
 	isAlpine      bool
 	directiveType string
 	key           string
+	modifiers     []ast.Modifier
+}
+
+// eventModifierAliases maps on:event|modifier template modifiers to their Alpine.js equivalents.
+// Modifiers without an Alpine.js equivalent are passed through unchanged and rejected
+// when the directive is generated.
+var eventModifierAliases = map[string]string{
+	"preventDefault":  "prevent",
+	"stopPropagation": "stop",
+	"once":            "once",
+	"capture":         "capture",
+	"self":            "self",
+	"passive":         "passive",
 }
 
 // parseAlpineDirective analyzes an attribute name and extracts Alpine.js directive info
 func parseAlpineDirective(name string) alpineDirectiveInfo {
 	if strings.HasPrefix(name, "x-") {
 		parts := strings.SplitN(name[2:], ":", 2)
-		directiveType := parts[0]
+		directiveType, modifiers := splitModifiers(parts[0])
 		key := ""
 		if len(parts) > 1 {
-			key = parts[1]
+			key, modifiers = splitModifiers(parts[1])
 		}
-		return alpineDirectiveInfo{true, directiveType, key}
+		return alpineDirectiveInfo{true, directiveType, key, modifiers}
 	} else if name == "@" || strings.HasPrefix(name, "@") {
-		key := ""
-		if name != "@" {
-			key = name[1:] // Extract event name
+		key, modifiers := splitModifiers(name[1:]) // Extract event name
+		return alpineDirectiveInfo{true, "on", key, modifiers}
+	} else if strings.HasPrefix(name, "on:") && len(name) > len("on:") {
+		// Template syntax: on:click|preventDefault|once
+		parts := strings.Split(name[len("on:"):], "|")
+		var modifiers []ast.Modifier
+		for _, part := range parts[1:] {
+			if part == "" {
+				continue
+			}
+			if alias, ok := eventModifierAliases[part]; ok {
+				part = alias
+			}
+			modifiers = append(modifiers, ast.Modifier{Name: part})
 		}
-		return alpineDirectiveInfo{true, "on", key}
+		return alpineDirectiveInfo{true, "on", parts[0], modifiers}
 	} else if name == ":" || strings.HasPrefix(name, ":") {
-		key := ""
-		if name != ":" {
-			key = name[1:] // Extract binding key
+		key, modifiers := splitModifiers(name[1:]) // Extract binding key
+		return alpineDirectiveInfo{true, "bind", key, modifiers}
+	}
+
+	return alpineDirectiveInfo{false, "", "", nil}
+}
+
+// splitModifiers splits a directive segment like "keydown.enter.debounce.300ms" into
+// its base ("keydown") and its modifiers ([enter debounce(300ms)]).
+// Numeric segments and transition origins are attached as arguments of the preceding modifier.
+func splitModifiers(segment string) (string, []ast.Modifier) {
+	parts := strings.Split(segment, ".")
+	var modifiers []ast.Modifier
+	for _, part := range parts[1:] {
+		if part == "" {
+			continue
+		}
+		if len(modifiers) > 0 && isModifierArg(modifiers[len(modifiers)-1].Name, part) {
+			last := &modifiers[len(modifiers)-1]
+			if last.Arg != "" {
+				last.Arg += "."
+			}
+			last.Arg += part
+			continue
 		}
-		return alpineDirectiveInfo{true, "bind", key}
+		modifiers = append(modifiers, ast.Modifier{Name: part})
 	}
+	return parts[0], modifiers
+}
 
-	return alpineDirectiveInfo{false, "", ""}
+// isModifierArg checks if a segment is an argument of the preceding modifier
+// (e.g., 300ms in .debounce.300ms or top in .origin.top) rather than a modifier itself
+func isModifierArg(modifier string, segment string) bool {
+	if segment[0] >= '0' && segment[0] <= '9' {
+		return true
+	}
+	if modifier == "origin" {
+		switch segment {
+		case "top", "bottom", "left", "right", "center":
+			return true
+		}
+	}
+	return false
 }
 
 // Extended Result type that includes Dynamic field
@@ -803,5 +878,5 @@ func isValidAttributeNameChar(char byte) bool {
 	return (char >= 'a' && char <= 'z') ||
 		(char >= 'A' && char <= 'Z') ||
 		(char >= '0' && char <= '9') ||
-		char == '-' || char == '_' || char == '.' || char == ':'
+		char == '-' || char == '_' || char == '.' || char == ':' || char == '|'
 }
@@ -90,12 +90,16 @@ func ParseTemplate(template string) (*ast.Template, error) {
 				// Extract successfully parsed nodes before the error
 				rootNodes, _ := result.Value.([]ast.Node)
 				filteredRootNodes := filterWhitespaceRootNodes(rootNodes)
+				resolvePositions(template, filteredRootNodes)
 				log.Printf("[ParseTemplate] Final Root Nodes Count (partial due to recursion): %d", len(filteredRootNodes))
 				return &ast.Template{RootNodes: filteredRootNodes}, nil // Return partial success
 			}
 
 			// Otherwise, report specific recursion error
-			return nil, fmt.Errorf("parsing error: possibly infinite recursion detected. HTML document restarted at line %d, column %d", line, col)
+			return nil, &ast.ParseError{
+				Pos:     ast.Position{Offset: position, Line: line, Column: col},
+				Message: "possibly infinite recursion detected, the HTML document restarts here",
+			}
 		}
 
 		// For Alpine.js documents, be more lenient with parsing errors
@@ -103,13 +107,16 @@ func ParseTemplate(template string) (*ast.Template, error) {
 			log.Printf("[ParseTemplate] Alpine.js document with unparsed content. Forcing successful parse.")
 			rootNodes, _ := result.Value.([]ast.Node)
 			filteredRootNodes := filterWhitespaceRootNodes(rootNodes)
+			resolvePositions(template, filteredRootNodes)
 			log.Printf("[ParseTemplate] Final Root Nodes Count (partial): %d", len(filteredRootNodes))
 			return &ast.Template{RootNodes: filteredRootNodes}, nil
 		}
 
 		// Standard error for unparsed content
-		return nil, fmt.Errorf("unparsed content remaining at line %d, column %d, starting near: '%s'",
-			line, col, result.Remaining[:min(50, len(result.Remaining))])
+		return nil, &ast.ParseError{
+			Pos:     ast.Position{Offset: position, Line: line, Column: col},
+			Message: fmt.Sprintf("unparsed content starting near: '%s'", result.Remaining[:min(50, len(result.Remaining))]),
+		}
 	}
 
 	// Extract and validate root nodes
@@ -123,7 +130,7 @@ func ParseTemplate(template string) (*ast.Template, error) {
 				log.Printf("[ParseTemplate] Alpine.js document with unexpected result type. Forcing empty node list.")
 				return &ast.Template{RootNodes: []ast.Node{}}, nil
 			}
-			return nil, fmt.Errorf("parser did not return node slice, got %T", result.Value)
+			return nil, &ast.ParseError{Message: fmt.Sprintf("parser did not return node slice, got %T", result.Value)}
 		}
 	}
 
@@ -141,6 +148,8 @@ func ParseTemplate(template string) (*ast.Template, error) {
 		}
 	}
 
+	resolvePositions(template, filteredRootNodes)
+
 	// Create the final AST
 	root := &ast.Template{RootNodes: filteredRootNodes}
 	log.Printf("[ParseTemplate] Final Root Nodes Count: %d", len(root.RootNodes))
@@ -148,6 +157,33 @@ func ParseTemplate(template string) (*ast.Template, error) {
 	return root, nil
 }
 
+// resolvePositions sets the positions of the fence and component nodes, which the
+// parsers record relative to the end of the template
+func resolvePositions(template string, nodes []ast.Node) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *ast.FenceSection:
+			if index := strings.Index(template, "---"+n.RawContent); index >= 0 {
+				n.Pos = ast.PositionOf(template, index+len("---"))
+			}
+		case *ast.ComponentNode:
+			if n.Pos.Offset < 0 {
+				n.Pos = ast.PositionOf(template, len(template)+n.Pos.Offset)
+			}
+		case *ast.Element:
+			resolvePositions(template, n.Children)
+		case *ast.Conditional:
+			resolvePositions(template, n.IfContent)
+			for _, content := range n.ElseIfContent {
+				resolvePositions(template, content)
+			}
+			resolvePositions(template, n.ElseContent)
+		case *ast.Loop:
+			resolvePositions(template, n.Content)
+		}
+	}
+}
+
 // filterWhitespaceRootNodes removes leading/trailing whitespace-only text nodes from the root.
 func filterWhitespaceRootNodes(nodes []ast.Node) []ast.Node {
 	filtered := []ast.Node{}
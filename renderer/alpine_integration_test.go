@@ -1,6 +1,7 @@
 package renderer
 
 import (
+	"log"
 	"strings"
 	"testing"
 
@@ -111,7 +112,7 @@ func TestAlpineJSDirectiveGeneration(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := GenerateAlpineDirectives(tt.attributes)
+			got := GenerateAlpineDirectives(tt.attributes, nil)
 			// Join the directives into a single string for the test
 			gotStr := strings.Join(got, " ")
 			if !strings.Contains(gotStr, tt.want) {
@@ -241,7 +242,7 @@ func TestRenderElementWithAlpine(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var sb strings.Builder
-			renderElement(&sb, tt.element)
+			renderElement(&sb, tt.element, log.Default())
 			result := sb.String()
 			
 			for _, substr := range tt.contains {
@@ -350,7 +351,7 @@ func TestTransformAndRenderAlpine(t *testing.T) {
 			// Render the transformed template
 			var sb strings.Builder
 			for _, node := range transformed.RootNodes {
-				renderNode(&sb, node)
+				renderNode(&sb, node, log.Default())
 			}
 			result := sb.String()
 			
@@ -15,11 +15,12 @@ import (
 )
 
 // getCompArgs parses component arguments string into props and data maps.
-func getCompArgs(comp_args []string, parentProps map[string]any) (map[string]any, map[string]any) {
+func getCompArgs(comp_args []string, parentProps map[string]any, logger *log.Logger) (map[string]any, map[string]any) {
 	comp_props := make(map[string]any)
-	comp_data := make(map[string]any)                             // For x-data generation
-	reArg := regexp.MustCompile(`^({?)([a-zA-Z0-9_]+)(}?)$`)      // For shorthand {prop}
-	reArgEq := regexp.MustCompile(`^([a-zA-Z0-9_]+)\s*=\s*(.*)$`) // For prop={expr} or prop="static"
+	comp_data := make(map[string]any)                                        // For x-data generation
+	reArg := regexp.MustCompile(`^({?)([a-zA-Z0-9_]+)(}?)$`)                 // For shorthand {prop}
+	reArgEq := regexp.MustCompile(`^([a-zA-Z0-9_]+)\s*=\s*(.*)$`)            // For prop={expr} or prop="static"
+	reBind := regexp.MustCompile(`^bind:([a-zA-Z0-9_]+)(?:\s*=\s*{(.*)})?$`) // For bind:prop={expr} or bind:prop
 
 	for _, comp_arg := range comp_args {
 		comp_arg = strings.TrimSpace(comp_arg)
@@ -27,14 +28,23 @@ func getCompArgs(comp_args []string, parentProps map[string]any) (map[string]any
 			continue
 		}
 
-		if matches := reArg.FindStringSubmatch(comp_arg); len(matches) == 4 && matches[1] == "{" && matches[3] == "}" {
+		if matches := reBind.FindStringSubmatch(comp_arg); len(matches) == 3 {
+			// Two-way binding: bind:prop={expression}, or bind:prop for bind:prop={prop}
+			prop_name := matches[1]
+			expression := strings.TrimSpace(matches[2])
+			if expression == "" {
+				expression = prop_name
+			}
+			comp_props[prop_name] = evalProps(expression, parentProps, logger)
+			comp_data[prop_name] = utils.Binding(expression) // Getter and setter in x-data
+		} else if matches := reArg.FindStringSubmatch(comp_arg); len(matches) == 4 && matches[1] == "{" && matches[3] == "}" {
 			// Shorthand {prop}
 			prop_name := matches[2]
 			if val, ok := parentProps[prop_name]; ok {
 				comp_props[prop_name] = val
 				comp_data[prop_name] = prop_name // Reference parent prop in x-data
 			} else {
-				log.Printf("Warning: Shorthand prop '%s' used but not found in parent props.", prop_name)
+				logger.Printf("Warning: Shorthand prop '%s' used but not found in parent props.", prop_name)
 			}
 		} else if matches := reArgEq.FindStringSubmatch(comp_arg); len(matches) == 3 {
 			// prop=value format
@@ -44,9 +54,8 @@ func getCompArgs(comp_args []string, parentProps map[string]any) (map[string]any
 			if strings.HasPrefix(prop_value_str, "{") && strings.HasSuffix(prop_value_str, "}") {
 				// Dynamic value: prop={expression}
 				expression := strings.Trim(prop_value_str, "{}")
-				// Generate parent props declarations to pass to EvalJS
-				parentPropsDecl := utils.DeclProps(parentProps)
-				prop_value := EvalJS(expression, parentPropsDecl) // Pass declarations string
+				// The parent props are set in the runtime as they are
+				prop_value := evalProps(expression, parentProps, logger)
 				comp_props[prop_name] = prop_value
 				comp_data[prop_name] = expression // Use expression for x-data getter
 			} else {
@@ -62,7 +71,7 @@ func getCompArgs(comp_args []string, parentProps map[string]any) (map[string]any
 					if err == nil {
 						staticValue = unquotedValue
 					} else {
-						log.Printf("Warning: Failed to unquote static string prop '%s': %v", prop_name, err)
+						logger.Printf("Warning: Failed to unquote static string prop '%s': %v", prop_name, err)
 						staticValue = prop_value_str // Fallback to raw string
 					}
 				} else {
@@ -79,7 +88,7 @@ func getCompArgs(comp_args []string, parentProps map[string]any) (map[string]any
 				comp_data[prop_name] = utils.AnyToJSValue(staticValue) // Use utils.AnyToJSValue for x-data
 			}
 		} else {
-			log.Printf("Warning: Invalid component argument format: %s", comp_arg)
+			logger.Printf("Warning: Invalid component argument format: %s", comp_arg)
 		}
 	}
 	return comp_props, comp_data
@@ -88,7 +97,12 @@ func getCompArgs(comp_args []string, parentProps map[string]any) (map[string]any
 // RenderComponents handles rendering static and dynamic components within the markup.
 // TODO: Refactor this to work with TemplateParts or an AST instead of regex on markup string.
 // It currently relies on string replacement and recursive calls to a Render function (which needs to be accessible).
-func RenderComponents(markup, script, style string, props map[string]any, components []Component, renderFunc func(string, map[string]any) (string, string, string)) (string, string, string) {
+// It stops at the first component that fails to render. Warnings are logged to
+// logger, or the standard logger when it is nil.
+func RenderComponents(markup, script, style string, props map[string]any, components []Component, renderFunc func(string, map[string]any) (Result, error), logger *log.Logger) (Result, error) {
+	if logger == nil {
+		logger = log.Default()
+	}
 
 	// Handle staticly imported components
 	for _, component := range components {
@@ -138,13 +152,20 @@ func RenderComponents(markup, script, style string, props map[string]any, compon
 				comp_args = append(comp_args, currentArg)
 			}
 
-			comp_props, comp_data := getCompArgs(comp_args, props)
+			comp_props, comp_data := getCompArgs(comp_args, props, logger)
 			// Recursive call using the passed renderFunc
-			comp_markup, comp_script, comp_style := renderFunc(component.Path, comp_props)
+			comp, err := renderFunc(component.Path, comp_props)
+			if err != nil {
+				return Result{Markup: markup, Script: script, Style: style}, err
+			}
 
 			// Scoping should ideally happen after all rendering is complete.
 			// Applying it here within the loop is problematic for nested scopes.
-			comp_markup, comp_scopedElements := scoping.ScopeHTMLComp(comp_markup, comp_props, comp_data)
+			comp_markup, comp_scopedElements, err := scoping.ScopeHTMLComp(comp.Markup, comp_props, comp_data)
+			if err != nil {
+				return Result{Markup: markup, Script: script, Style: style}, err
+			}
+			comp_script, comp_style := comp.Script, comp.Style
 			comp_style, _ = scoping.ScopeCSS(comp_style, comp_scopedElements) // Use scoping package
 			comp_script = scoping.ScopeJS(comp_script, comp_scopedElements)   // Use scoping package
 
@@ -166,12 +187,11 @@ func RenderComponents(markup, script, style string, props map[string]any, compon
 		if match[4] != -1 {
 			argsStr = strings.TrimSpace(markup[match[4]:match[5]])
 		}
-		// Generate props declarations to pass to EvalJS for path evaluation
-		propsDecl := utils.DeclProps(props)
-		comp_path_any := EvalJS(strings.Trim(compPathExpr, "{}"), propsDecl) // Pass declarations string
+		// Evaluate the path with the props set in the runtime as they are
+		comp_path_any := evalProps(strings.Trim(compPathExpr, "{}"), props, logger)
 		comp_path, ok := comp_path_any.(string)
 		if !ok {
-			log.Printf("Warning: Dynamic component path expression did not evaluate to a string: %s", compPathExpr)
+			logger.Printf("Warning: Dynamic component path expression did not evaluate to a string: %s", compPathExpr)
 			// Remove the tag and continue
 			markup = markup[:match[0]] + markup[match[1]:]
 			continue
@@ -214,11 +234,18 @@ func RenderComponents(markup, script, style string, props map[string]any, compon
 			comp_args = append(comp_args, currentArg)
 		}
 
-		comp_props, comp_data := getCompArgs(comp_args, props)
+		comp_props, comp_data := getCompArgs(comp_args, props, logger)
 		// Recursive call using the passed renderFunc
-		comp_markup, comp_script, comp_style := renderFunc(comp_path, comp_props)
+		comp, err := renderFunc(comp_path, comp_props)
+		if err != nil {
+			return Result{Markup: markup, Script: script, Style: style}, err
+		}
 
-		comp_markup, comp_scopedElements := scoping.ScopeHTMLComp(comp_markup, comp_props, comp_data)
+		comp_markup, comp_scopedElements, err := scoping.ScopeHTMLComp(comp.Markup, comp_props, comp_data)
+		if err != nil {
+			return Result{Markup: markup, Script: script, Style: style}, err
+		}
+		comp_script, comp_style := comp.Script, comp.Style
 		comp_style, _ = scoping.ScopeCSS(comp_style, comp_scopedElements) // Use scoping package
 		comp_script = scoping.ScopeJS(comp_script, comp_scopedElements)   // Use scoping package
 
@@ -226,5 +253,5 @@ func RenderComponents(markup, script, style string, props map[string]any, compon
 		script += comp_script
 		style += comp_style
 	}
-	return markup, script, style
+	return Result{Markup: markup, Script: script, Style: style}, nil
 }
@@ -1,6 +1,7 @@
 package renderer
 
 import (
+	"log"
 	"strings"
 	"testing"
 
@@ -128,7 +129,7 @@ func TestNestedComponentsWithAlpine(t *testing.T) {
 		// Render the transformed template
 		var sb strings.Builder
 		for _, node := range transformed.RootNodes {
-			renderNode(&sb, node)
+			renderNode(&sb, node, log.Default())
 		}
 		result := sb.String()
 		
@@ -374,7 +375,7 @@ func TestComplexDataStructures(t *testing.T) {
 		// Render the transformed template
 		var sb strings.Builder
 		for _, node := range transformed.RootNodes {
-			renderNode(&sb, node)
+			renderNode(&sb, node, log.Default())
 		}
 		result := sb.String()
 		
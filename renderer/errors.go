@@ -0,0 +1,11 @@
+package renderer
+
+import "github.com/jimafisk/custom_go_template/ast"
+
+// The errors Render returns, checked with errors.As
+type (
+	ParseError             = ast.ParseError
+	ComponentNotFoundError = ast.ComponentNotFoundError
+	ComponentCycleError    = ast.ComponentCycleError
+	EvalError              = ast.EvalError
+)
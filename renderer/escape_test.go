@@ -0,0 +1,20 @@
+package renderer
+
+import (
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/codegen/rt"
+)
+
+// Generated code escapes props with codegen/rt, which must match the renderer
+func TestEscapeAttrValueMatchesGeneratedCode(t *testing.T) {
+	values := []string{"", "plain", `It's <b>"quoted"</b> & &amp; escaped`, "&#39;'"}
+	for _, value := range values {
+		for _, singleQuotes := range []bool{false, true} {
+			want := escapeAttrValue(value, singleQuotes)
+			if got := rt.EscapeAttr(value, singleQuotes); got != want {
+				t.Errorf("rt.EscapeAttr(%q, %t) = %q, want %q", value, singleQuotes, got, want)
+			}
+		}
+	}
+}
@@ -36,10 +36,11 @@ func GetComponents(fence string) (string, []Component) {
 		if len(match) > 1 {
 			compName := match[1]
 			compPath := match[2]
-			// TODO: Resolve relative paths based on the current file's path
+			// The path is kept as written; modules.Resolver resolves it against
+			// the importing file
 			components = append(components, Component{
 				Name: compName,
-				Path: compPath, // Path might need resolving
+				Path: compPath,
 			})
 		} else {
 			cleanedFence += line + "\n" // Keep non-import lines
@@ -81,36 +82,43 @@ func GetAllVars(fence string) []string {
 }
 
 // EvaluateProps runs the fence script in Goja and updates the props map with evaluated variable values.
-func EvaluateProps(fence string, allVars []string, props map[string]any) map[string]any {
-	vm := goja.New()
+// The props are set as globals of the runtime, which the fence can read without
+// declaring them. Errors and the fence's console output are logged to logger, or
+// the standard logger when it is nil.
+func EvaluateProps(fence string, allVars []string, props map[string]any, logger *log.Logger) map[string]any {
+	r := acquireRuntime(props, logger)
+	defer r.release()
 
-	// Add console logging for debugging
-	vm.Set("console", map[string]interface{}{
-		"log": func(args ...interface{}) {
-			log.Println("JS console.log:", args)
-		},
-		"error": func(args ...interface{}) {
-			log.Println("JS console.error:", args)
-		},
-	})
+	// The fence is followed by the values of its variables, as the value of its
+	// last statement. Variables it doesn't declare are undefined.
+	var script strings.Builder
+	script.WriteString(fence)
+	script.WriteString("\n;[")
+	for i, name := range allVars {
+		if i > 0 {
+			script.WriteString(", ")
+		}
+		fmt.Fprintf(&script, "typeof %s === \"undefined\" ? undefined : %s", name, name)
+	}
+	script.WriteString("]")
 
-	_, err := vm.RunString(fence) // Run the modified fence script
+	result, err := r.eval(script.String()) // Run the modified fence script
 	if err != nil {
-		log.Printf("Error running fence script: %v\nScript:\n%s", err, fence)
+		r.logger.Printf("Error running fence script: %v\nScript:\n%s", err, fence)
 		// Return original props on error
 		return props
 	}
 
 	// Re-evaluate all declared variables (props and computed ones)
+	var values []any
+	if err := r.vm.ExportTo(result, &values); err != nil || len(values) != len(allVars) {
+		r.logger.Printf("Error reading fence variables: %v", err)
+		return props
+	}
 	evaluatedProps := make(map[string]any)
-	for _, name := range allVars {
-		evaluated_value := vm.Get(name).Export()
-		// Handle nil (JS undefined) appropriately
-		if evaluated_value == nil {
-			// Maybe represent as Go nil or a specific marker?
-			evaluated_value = nil // Or keep as nil
-		}
-		evaluatedProps[name] = evaluated_value
+	for i, name := range allVars {
+		// JS undefined is exported as a Go nil
+		evaluatedProps[name] = values[i]
 	}
 
 	// Ensure original props passed in are preserved if not overwritten by fence logic
@@ -120,8 +128,19 @@ func EvaluateProps(fence string, allVars []string, props map[string]any) map[str
 	return evaluatedProps
 }
 
-// EvalJS evaluates JavaScript expressions using goja
+// EvalJS evaluates JavaScript expressions using goja, after the declarations of
+// propsDecl, like those utils.DeclProps generates
 func EvalJS(jsCode string, propsDecl string) any {
+	return evalJS(jsCode, propsDecl, nil, nil)
+}
+
+// evalProps evaluates a JavaScript expression like EvalJS, with props set as
+// globals rather than declared in JavaScript, logging to logger
+func evalProps(jsCode string, props map[string]any, logger *log.Logger) any {
+	return evalJS(jsCode, "", props, logger)
+}
+
+func evalJS(jsCode string, propsDecl string, props map[string]any, logger *log.Logger) any {
 	// Handle empty input
 	if jsCode == "" {
 		return ""
@@ -134,7 +153,10 @@ func EvalJS(jsCode string, propsDecl string) any {
 	
 	// Check if this is a complex JS object that should be preserved as a string
 	if isComplexJSObjectInternal(jsCode) {
-		log.Printf("Detected complex JS object, preserving as-is: %s", jsCode)
+		if logger == nil {
+			logger = log.Default()
+		}
+		logger.Printf("Detected complex JS object, preserving as-is: %s", jsCode)
 		return jsCode
 	}
 	
@@ -143,6 +165,9 @@ func EvalJS(jsCode string, propsDecl string) any {
 		return jsCode
 	}
 	
+	r := acquireRuntime(props, logger)
+	defer r.release()
+
 	// Special case for simple array literal [1, 2, 3]
 	if strings.HasPrefix(jsCode, "[") && strings.HasSuffix(jsCode, "]") {
 		// Check if it contains any complex objects
@@ -151,8 +176,7 @@ func EvalJS(jsCode string, propsDecl string) any {
 		}
 		
 		// This is a simple array, try to evaluate it
-		vm := goja.New()
-		result, err := vm.RunString(jsCode)
+		result, err := r.eval(jsCode)
 		if err == nil {
 			return convertToFloat64(result.Export())
 		}
@@ -168,27 +192,20 @@ func EvalJS(jsCode string, propsDecl string) any {
 		}
 		
 		// This is a simple object literal with parentheses, try to evaluate it
-		vm := goja.New()
-		result, err := vm.RunString(jsCode)
+		result, err := r.eval(jsCode)
 		if err == nil {
 			return convertToFloat64(result.Export())
 		}
 	}
 	
-	// Try evaluating as a simple expression
-	vm := goja.New()
-	
-	// Set up the runtime with any provided props declarations
+	// Try evaluating as a simple expression, after the provided props declarations
+	code := jsCode
 	if propsDecl != "" {
-		_, err := vm.RunString(propsDecl)
-		if err != nil {
-			log.Printf("Error evaluating props declaration: %v", err)
-			return jsCode
-		}
+		code = propsDecl + "\n;" + jsCode
 	}
 	
 	// Evaluate the expression
-	result, err := vm.RunString(jsCode)
+	result, err := r.eval(code)
 	if err != nil {
 		// If evaluation fails, return the original code
 		return jsCode
@@ -189,3 +189,16 @@ func TestEvalJS_EvaluationStrategies(t *testing.T) {
 		}
 	})
 }
+
+func TestEvaluateProps_LifecycleHooks(t *testing.T) {
+	fence := `let count = 1;
+onMount(() => { count = 100 });
+onDestroy(() => { count = -1 });
+let doubled = count * 2;`
+
+	got := EvaluateProps(fence, []string{"count", "doubled"}, map[string]any{}, nil)
+	want := map[string]any{"count": int64(1), "doubled": int64(2)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EvaluateProps() = %v, want %v", got, want)
+	}
+}
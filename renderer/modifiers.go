@@ -0,0 +1,103 @@
+package renderer
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+// alpineModifiers lists the modifiers Alpine.js accepts for each directive type.
+// Directive types that are not listed (e.g., plugin directives) are not validated.
+var alpineModifiers = map[string]map[string]bool{
+	"on": {
+		"prevent": true, "stop": true, "outside": true, "window": true, "document": true,
+		"once": true, "debounce": true, "throttle": true, "self": true, "camel": true,
+		"dot": true, "passive": true, "capture": true,
+		"shift": true, "ctrl": true, "alt": true, "meta": true, "cmd": true, "super": true,
+	},
+	"model":      {"lazy": true, "number": true, "boolean": true, "debounce": true, "throttle": true, "fill": true},
+	"bind":       {"camel": true},
+	"transition": {"duration": true, "delay": true, "opacity": true, "scale": true, "origin": true},
+	"show":       {"important": true},
+	"intersect":  {"once": true, "half": true, "full": true, "threshold": true, "margin": true},
+	"ignore":     {"self": true},
+	"data":       {},
+	"init":       {},
+	"effect":     {},
+	"text":       {},
+	"html":       {},
+	"if":         {},
+	"for":        {},
+	"ref":        {},
+	"cloak":      {},
+}
+
+// modifiersWithArg lists the modifiers that take an argument, like .debounce.300ms
+var modifiersWithArg = map[string]bool{
+	"debounce": true, "throttle": true, "duration": true, "delay": true,
+	"scale": true, "origin": true, "threshold": true, "margin": true,
+}
+
+// keyboardEvents are the events whose modifiers may also be key names like .enter or .page-down
+var keyboardEvents = map[string]bool{"keydown": true, "keyup": true, "keypress": true}
+
+var keyNameRegex = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// isValidModifier checks if a modifier is accepted by Alpine.js for the given attribute
+func isValidModifier(attr ast.Attribute, modifier ast.Modifier) bool {
+	allowed, known := alpineModifiers[attr.AlpineType]
+	if !known {
+		return true
+	}
+
+	if allowed[modifier.Name] {
+		return modifier.Arg == "" || modifiersWithArg[modifier.Name]
+	}
+
+	// Keyboard events accept any key name as a modifier
+	if attr.AlpineType == "on" && keyboardEvents[attr.AlpineKey] {
+		return modifier.Arg == "" && keyNameRegex.MatchString(modifier.Name)
+	}
+
+	return false
+}
+
+// alpineDirectiveName returns the canonical Alpine.js attribute name for a directive,
+// re-emitting its modifiers in dot form and dropping any Alpine.js does not accept,
+// which are logged to logger.
+func alpineDirectiveName(attr ast.Attribute, logger *log.Logger) string {
+	isEventSyntax := strings.HasPrefix(attr.Name, "on:")
+	if len(attr.Modifiers) == 0 && !isEventSyntax {
+		return attr.Name
+	}
+
+	var name strings.Builder
+	switch {
+	case strings.HasPrefix(attr.Name, "x-"):
+		name.WriteString("x-" + attr.AlpineType)
+		if attr.AlpineKey != "" {
+			name.WriteString(":" + attr.AlpineKey)
+		}
+	case strings.HasPrefix(attr.Name, "@"), isEventSyntax:
+		name.WriteString("@" + attr.AlpineKey)
+	case strings.HasPrefix(attr.Name, ":"):
+		name.WriteString(":" + attr.AlpineKey)
+	default:
+		return attr.Name
+	}
+
+	for _, modifier := range attr.Modifiers {
+		if !isValidModifier(attr, modifier) {
+			logger.Printf("Warning: Unknown modifier '%s' on %s, skipping", modifier.Name, attr.Name)
+			continue
+		}
+		name.WriteString("." + modifier.Name)
+		if modifier.Arg != "" {
+			name.WriteString("." + modifier.Arg)
+		}
+	}
+
+	return name.String()
+}
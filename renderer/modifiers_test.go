@@ -0,0 +1,130 @@
+package renderer
+
+import (
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/parser"
+)
+
+func TestAlpineModifierParsing(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		wantKey   string
+		modifiers []ast.Modifier
+	}{
+		{
+			name:      "event modifiers",
+			template:  `<button @click.prevent.stop="save()">Save</button>`,
+			wantKey:   "click",
+			modifiers: []ast.Modifier{{Name: "prevent"}, {Name: "stop"}},
+		},
+		{
+			name:      "key and debounce with argument",
+			template:  `<input x-on:keydown.enter.debounce.300ms="search()" />`,
+			wantKey:   "keydown",
+			modifiers: []ast.Modifier{{Name: "enter"}, {Name: "debounce", Arg: "300ms"}},
+		},
+		{
+			name:      "model modifier",
+			template:  `<input x-model.lazy="query" />`,
+			wantKey:   "",
+			modifiers: []ast.Modifier{{Name: "lazy"}},
+		},
+		{
+			name:      "template event syntax",
+			template:  `<button on:click|preventDefault|once={save}>Save</button>`,
+			wantKey:   "click",
+			modifiers: []ast.Modifier{{Name: "prevent"}, {Name: "once"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parser.ParseTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("ParseTemplate() error = %v", err)
+			}
+			element, ok := tmpl.RootNodes[0].(*ast.Element)
+			if !ok || len(element.Attributes) == 0 {
+				t.Fatalf("expected an element with attributes, got %T", tmpl.RootNodes[0])
+			}
+			attr := element.Attributes[0]
+			if attr.AlpineKey != tt.wantKey {
+				t.Errorf("AlpineKey = %q, want %q", attr.AlpineKey, tt.wantKey)
+			}
+			if !reflect.DeepEqual(attr.Modifiers, tt.modifiers) {
+				t.Errorf("Modifiers = %+v, want %+v", attr.Modifiers, tt.modifiers)
+			}
+		})
+	}
+}
+
+func TestAlpineModifierGeneration(t *testing.T) {
+	tests := []struct {
+		name    string
+		attr    ast.Attribute
+		want    string
+		warning string // logged when the modifiers are generated
+	}{
+		{
+			name: "shorthand event keeps its form",
+			attr: ast.Attribute{
+				Name: "@click.prevent.stop", Value: "save()", IsAlpine: true, AlpineType: "on", AlpineKey: "click",
+				Modifiers: []ast.Modifier{{Name: "prevent"}, {Name: "stop"}},
+			},
+			want: `@click.prevent.stop="save()"`,
+		},
+		{
+			name: "template event syntax becomes shorthand",
+			attr: ast.Attribute{
+				Name: "on:click|preventDefault|once", Value: "save", IsAlpine: true, AlpineType: "on", AlpineKey: "click",
+				Modifiers: []ast.Modifier{{Name: "prevent"}, {Name: "once"}},
+			},
+			want: `@click.prevent.once="save"`,
+		},
+		{
+			name: "modifier arguments are preserved",
+			attr: ast.Attribute{
+				Name: "x-on:keydown.enter.debounce.300ms", Value: "search()", IsAlpine: true, AlpineType: "on", AlpineKey: "keydown",
+				Modifiers: []ast.Modifier{{Name: "enter"}, {Name: "debounce", Arg: "300ms"}},
+			},
+			want: `x-on:keydown.enter.debounce.300ms="search()"`,
+		},
+		{
+			name: "unknown modifiers are dropped",
+			attr: ast.Attribute{
+				Name: "on:click|stopImmediatePropagation", Value: "save", IsAlpine: true, AlpineType: "on", AlpineKey: "click",
+				Modifiers: []ast.Modifier{{Name: "stopImmediatePropagation"}},
+			},
+			want:    `@click="save"`,
+			warning: "Unknown modifier 'stopImmediatePropagation' on on:click|stopImmediatePropagation",
+		},
+		{
+			name: "unknown model modifier is dropped",
+			attr: ast.Attribute{
+				Name: "x-model.lazy.trim", Value: "query", IsAlpine: true, AlpineType: "model",
+				Modifiers: []ast.Modifier{{Name: "lazy"}, {Name: "trim"}},
+			},
+			want:    `x-model.lazy="query"`,
+			warning: "Unknown modifier 'trim' on x-model.lazy.trim",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings strings.Builder
+			got := strings.Join(GenerateAlpineDirectives([]ast.Attribute{tt.attr}, log.New(&warnings, "", 0)), " ")
+			if got != tt.want {
+				t.Errorf("GenerateAlpineDirectives() = %v, want %v", got, tt.want)
+			}
+			if !strings.Contains(warnings.String(), tt.warning) || (tt.warning == "" && warnings.Len() > 0) {
+				t.Errorf("Warnings = %q, want %q", warnings.String(), tt.warning)
+			}
+		})
+	}
+}
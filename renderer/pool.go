@@ -0,0 +1,224 @@
+package renderer
+
+import (
+	"log"
+	"reflect"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// runtimeSetup prepares the global environment of the pooled runtimes. Code is
+// evaluated by __evaluate, with a direct eval in a function of its own, so its
+// declarations don't outlive it and the runtime can be used again.
+//
+// The built-in constructors, namespaces and prototypes are frozen, so code can't
+// change them for the evaluations that reuse the runtime. Members that objects
+// commonly set for themselves, like the name of an Error subclass, become
+// accessors first: assigning them on an object defines its own property instead
+// of failing on the frozen prototype.
+const runtimeSetup = `
+(function() {
+	var overridable = {
+		Object: ["constructor", "toString", "toLocaleString", "valueOf"],
+		Function: ["constructor", "toString"],
+		Array: ["constructor", "toString", "push"],
+		Promise: ["constructor"],
+		Error: ["constructor", "name", "message", "toString"],
+		EvalError: ["constructor", "name", "message"],
+		RangeError: ["constructor", "name", "message"],
+		ReferenceError: ["constructor", "name", "message"],
+		SyntaxError: ["constructor", "name", "message"],
+		TypeError: ["constructor", "name", "message"],
+		URIError: ["constructor", "name", "message"]
+	};
+	Object.keys(overridable).forEach(function(constructor) {
+		var target = globalThis[constructor].prototype;
+		overridable[constructor].forEach(function(name) {
+			var value = target[name];
+			Object.defineProperty(target, name, {
+				get: function() { return value; },
+				set: function(newValue) {
+					if (this === target) {
+						throw new TypeError("Cannot assign to read only property '" + name + "' of a built-in");
+					}
+					Object.defineProperty(this, name, { value: newValue, writable: true, enumerable: true, configurable: true });
+				},
+				configurable: false
+			});
+		});
+	});
+	Object.getOwnPropertyNames(globalThis).forEach(function(name) {
+		var value = globalThis[name];
+		if (value === globalThis || value === null || (typeof value !== "object" && typeof value !== "function")) {
+			return;
+		}
+		Object.freeze(value);
+		if (value.prototype) {
+			Object.freeze(value.prototype);
+		}
+	});
+})();
+
+var __evaluate = function(__code) {
+	return eval(__code);
+};
+`
+
+var runtimeSetupProgram = goja.MustCompile("runtime-setup.js", runtimeSetup, false)
+
+// runtimes keeps the goja runtimes fences and expressions are evaluated in, set up
+// once rather than for each evaluation
+var runtimes = sync.Pool{New: func() any { return newRuntime() }}
+
+// runtime is a goja runtime with the global environment of the renderer
+type runtime struct {
+	vm       *goja.Runtime
+	logger   *log.Logger // receives console output, set while the runtime is used
+	evaluate goja.Callable
+	ownNames goja.Callable         // Object.getOwnPropertyNames, as set up
+	globals  map[string]goja.Value // the global properties once set up
+}
+
+func newRuntime() *runtime {
+	vm := goja.New()
+	r := &runtime{vm: vm, globals: make(map[string]goja.Value)}
+
+	// The setup freezes the built-ins, so it runs before the host objects are set
+	if _, err := vm.RunProgram(runtimeSetupProgram); err != nil {
+		// The setup is a constant and always runs
+		panic(err)
+	}
+
+	// Add console logging for debugging
+	vm.Set("console", map[string]interface{}{
+		"log": func(args ...interface{}) {
+			r.logger.Println("JS console.log:", args)
+		},
+		"error": func(args ...interface{}) {
+			r.logger.Println("JS console.error:", args)
+		},
+	})
+
+	// Lifecycle callbacks and contexts only have a meaning on the client, where
+	// they are compiled into the component's x-data
+	for _, hook := range []string{"onMount", "onDestroy", "setContext", "getContext"} {
+		vm.Set(hook, func(goja.FunctionCall) goja.Value { return goja.Undefined() })
+	}
+
+	r.evaluate, _ = goja.AssertFunction(vm.Get("__evaluate"))
+	r.ownNames, _ = goja.AssertFunction(vm.Get("Object").ToObject(vm).Get("getOwnPropertyNames"))
+	global := vm.GlobalObject()
+	for _, name := range r.names() {
+		r.globals[name] = global.Get(name)
+	}
+	return r
+}
+
+// acquireRuntime returns a runtime from the pool, with props set as globals and
+// its console logged to logger, or the standard logger when it is nil. The props
+// are copies, so the evaluated code can't change the caller's maps and slices.
+func acquireRuntime(props map[string]any, logger *log.Logger) *runtime {
+	if logger == nil {
+		logger = log.Default()
+	}
+	r := runtimes.Get().(*runtime)
+	r.logger = logger
+	for name, value := range props {
+		r.vm.Set(name, copyProp(value))
+	}
+	return r
+}
+
+// copyProp returns a deep copy of the maps and slices of a prop, which goja
+// would otherwise expose to JavaScript as they are. Maps with string keys are
+// copied as map[string]any and slices and arrays as []any, like JSON data.
+func copyProp(value any) any {
+	switch v := value.(type) {
+	case nil, string, bool, float64, int, int64:
+		return value
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for key, item := range v {
+			copied[key] = copyProp(item)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(v))
+		for i, item := range v {
+			copied[i] = copyProp(item)
+		}
+		return copied
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		copied := make(map[string]any, rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			copied[iter.Key().String()] = copyProp(iter.Value().Interface())
+		}
+		return copied
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return value
+		}
+		copied := make([]any, rv.Len())
+		for i := range copied {
+			copied[i] = copyProp(rv.Index(i).Interface())
+		}
+		return copied
+	}
+	return value
+}
+
+// release puts the runtime back in the pool, with its globals as they were set
+// up. A runtime whose globals can't be restored is dropped. The built-ins are
+// frozen by the setup and need no restoring.
+func (r *runtime) release() {
+	r.logger = nil
+	global := r.vm.GlobalObject()
+	names := r.names()
+	if names == nil {
+		return
+	}
+	for _, name := range names {
+		if _, ok := r.globals[name]; ok {
+			continue
+		}
+		if err := global.Delete(name); err != nil {
+			return
+		}
+	}
+	for name, value := range r.globals {
+		if current := global.Get(name); current != nil && current.SameAs(value) {
+			continue
+		}
+		if err := global.Set(name, value); err != nil {
+			return
+		}
+	}
+	runtimes.Put(r)
+}
+
+// names returns the names of the global properties
+func (r *runtime) names() []string {
+	value, err := r.ownNames(goja.Undefined(), r.vm.GlobalObject())
+	if err != nil {
+		return nil
+	}
+	var names []string
+	if err := r.vm.ExportTo(value, &names); err != nil {
+		return nil
+	}
+	return names
+}
+
+// eval evaluates code like a script and returns the value of its last
+// statement. Its declarations are dropped once it has run.
+func (r *runtime) eval(code string) (goja.Value, error) {
+	return r.evaluate(goja.Undefined(), r.vm.ToValue(code))
+}
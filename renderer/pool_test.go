@@ -0,0 +1,224 @@
+package renderer
+
+import (
+	"io/fs"
+	"log"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/jimafisk/custom_go_template/examples"
+	"github.com/jimafisk/custom_go_template/utils"
+)
+
+func TestPooledRuntimes(t *testing.T) {
+	tests := []struct {
+		name  string
+		eval  func() any
+		want  any
+		after string // evaluated afterwards, expected to see nothing of the first evaluation
+	}{
+		{
+			name:  "declarations",
+			eval:  func() any { return EvalJS("x * 2", "let x = 21;") },
+			want:  float64(42),
+			after: "typeof x",
+		},
+		{
+			name:  "implicit globals",
+			eval:  func() any { return EvalJS("leaked = 1, leaked + 1", "") },
+			want:  float64(2),
+			after: "typeof leaked",
+		},
+		{
+			name: "props",
+			eval: func() any {
+				return evalProps("user.name + ' ' + tags.length", map[string]any{"user": map[string]any{"name": "Ada"}, "tags": []any{"go", "js"}}, nil)
+			},
+			want:  "Ada 2",
+			after: "typeof user",
+		},
+		{
+			name:  "props shadowing built-ins",
+			eval:  func() any { return evalProps("JSON", map[string]any{"JSON": "mine"}, nil) },
+			want:  "mine",
+			after: "typeof JSON === 'object' ? 'undefined' : typeof JSON",
+		},
+		{
+			name: "fence variables",
+			eval: func() any {
+				return EvaluateProps("let count = 1;\nlet doubled = count * 2;", []string{"count", "doubled", "missing"}, nil, nil)
+			},
+			want:  map[string]any{"count": int64(1), "doubled": int64(2), "missing": nil},
+			after: "typeof count",
+		},
+		{
+			name:  "built-in prototypes",
+			eval:  func() any { return EvalJS("Array.prototype.first = 1, Object.prototype.extra = 2, [].first", "") },
+			want:  nil,
+			after: "typeof [].first + typeof ({}).extra === 'undefinedundefined' ? 'undefined' : 'changed'",
+		},
+		{
+			name: "overriding inherited members",
+			eval: func() any {
+				return EvalJS("new NotFound().name + ' ' + point", `
+class NotFound extends Error {
+	constructor() { super("missing"); this.name = "NotFound"; }
+}
+let point = { x: 1 };
+point.toString = function() { return "(" + this.x + ")"; };`)
+			},
+			want:  "NotFound (1)",
+			after: "typeof NotFound",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Twice, as the second evaluation may reuse the runtime of the first
+			for i := 0; i < 2; i++ {
+				if got := tt.eval(); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Evaluation %d = %v (type %T), want %v", i+1, got, got, tt.want)
+				}
+				if got := EvalJS(tt.after, ""); got != "undefined" {
+					t.Errorf("Evaluation %d: %s = %v afterwards, want undefined", i+1, tt.after, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPropsCopied(t *testing.T) {
+	props := map[string]any{
+		"user":   map[string]any{"name": "Ada", "tags": []any{"go"}},
+		"scores": []int{1, 2},
+	}
+	want := map[string]any{
+		"user":   map[string]any{"name": "Ada", "tags": []any{"go"}},
+		"scores": []int{1, 2},
+	}
+	got := evalProps("user.name = 'Bob', user.tags.push('js'), scores[0] = 9, user.name + scores[0]", props, nil)
+	if got != "Bob9" {
+		t.Errorf("Evaluation = %v, want Bob9", got)
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("Evaluation changed the props to %v, want %v", props, want)
+	}
+}
+
+func TestRuntimeLogger(t *testing.T) {
+	var logs strings.Builder
+	logger := log.New(&logs, "", 0)
+	EvaluateProps("let count = 1;\nconsole.log('count', count);", []string{"count"}, nil, logger)
+	EvaluateProps("let broken = ;", []string{"broken"}, nil, logger)
+
+	for _, expected := range []string{"JS console.log: [count 1]", "Error running fence script"} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("Expected the logger to receive %q, got:\n%s", expected, logs.String())
+		}
+	}
+}
+
+func TestGetCompArgsProps(t *testing.T) {
+	parentProps := map[string]any{
+		"user":     map[string]any{"name": "Ada", "tags": []any{"go", "js"}},
+		"products": []any{map[string]any{"price": 2.5}, map[string]any{"price": 4}},
+	}
+	props, _ := getCompArgs([]string{
+		"name={user.name}",
+		"count={user.tags.length}",
+		"total={products[0].price + products[1].price}",
+		"bind:user",
+	}, parentProps, log.Default())
+
+	want := map[string]any{"name": "Ada", "count": float64(2), "total": 6.5, "user": map[string]any{"name": "Ada", "tags": []any{"go", "js"}}}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("getCompArgs() props = %v, want %v", props, want)
+	}
+}
+
+// comprehensiveVars matches the variables and props the comprehensive example
+// declares, whose values span several lines
+var comprehensiveVars = regexp.MustCompile(`(?m)^(?:let|const|var|prop)\s+([A-Za-z_$][A-Za-z0-9_$]*)`)
+
+// comprehensive returns the fence, variables, markup and components of the
+// comprehensive example page, with its props declared as variables
+func comprehensive(b *testing.B) (string, []string, string, []Component) {
+	b.Helper()
+	source, err := fs.ReadFile(examples.FS, "pages/comprehensive.html")
+	if err != nil {
+		b.Fatal(err)
+	}
+	parts := strings.SplitN(string(source), "---", 3)
+	fence, components := GetComponents(parts[1])
+	var allVars []string
+	for _, match := range comprehensiveVars.FindAllStringSubmatch(fence, -1) {
+		allVars = append(allVars, match[1])
+	}
+	fence = regexp.MustCompile(`(?m)^prop\s+`).ReplaceAllString(fence, "let ")
+	return fence, allVars, parts[2], components
+}
+
+// comprehensiveProps returns the props of the comprehensive example page
+func comprehensiveProps(b *testing.B) (map[string]any, string, []Component) {
+	b.Helper()
+	fence, allVars, markup, components := comprehensive(b)
+	props := EvaluateProps(fence, allVars, nil, nil)
+	if _, ok := props["products"].([]any); !ok {
+		b.Fatalf("Expected the products of the example, got %v", props)
+	}
+	return props, markup, components
+}
+
+// BenchmarkRenderComponents measures the cost of each component of the
+// comprehensive example: evaluating its props and path, and scoping its output.
+// The components render a fixed result, so their own pages aren't measured.
+func BenchmarkRenderComponents(b *testing.B) {
+	props, markup, components := comprehensiveProps(b)
+	count := 0
+	render := func(string, map[string]any) (Result, error) {
+		count++
+		return Result{Markup: "<div><p>Component</p></div>"}, nil
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := RenderComponents(markup, "", "", props, components, render, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(count), "ns/component")
+}
+
+// BenchmarkComponentProp compares evaluating a component prop in a runtime of
+// the pool, with the props of the comprehensive example set as Go values, with
+// declaring them in a new runtime
+func BenchmarkComponentProp(b *testing.B) {
+	props, _, _ := comprehensiveProps(b)
+	expression := "user.name + ' has ' + products.length + ' products in ' + settings.currency"
+
+	b.Run("pooled", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			evalProps(expression, props, nil)
+		}
+	})
+	// As props were evaluated before the pool: a new runtime per expression,
+	// with the props declared in JavaScript
+	b.Run("declared", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			vm := goja.New()
+			if _, err := vm.RunString(utils.DeclProps(props) + "\n;" + expression); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkEvaluateProps(b *testing.B) {
+	fence, allVars, _, _ := comprehensive(b)
+	for i := 0; i < b.N; i++ {
+		EvaluateProps(fence, allVars, nil, nil)
+	}
+}
@@ -2,38 +2,100 @@ package renderer
 
 import (
 	"fmt"
+	"io/fs"
 	"log"
-	"os"
 	"regexp"
 	"strings"
 
 	"github.com/jimafisk/custom_go_template/ast" // Import AST package
-	"github.com/jimafisk/custom_go_template/parser"
+	"github.com/jimafisk/custom_go_template/modules"
 	"github.com/jimafisk/custom_go_template/transformer"
 )
 
-func Render(templatePath string, props map[string]any) (string, string, string) {
-	// Read template file
-	content, err := os.ReadFile(templatePath)
-	if err != nil {
-		log.Fatalf("Error reading template: %v", err)
+// Result holds the output of a rendered template
+type Result struct {
+	Markup string
+	Script string
+	Style  string
+}
+
+// Render renders the template at templatePath with the components it imports.
+// A template that can't be read or parsed, or that imports one, returns an empty
+// Result with the error: a *ParseError, a *ComponentNotFoundError for an import,
+// or the error reading the page. A component used but not found, or a fence that
+// fails on the server, returns a *ComponentNotFoundError or an *EvalError with
+// the Result rendered anyway, with placeholders for what failed, as does a
+// component cycle with a *ComponentCycleError.
+func Render(templatePath string, props map[string]any) (Result, error) {
+	return RenderWithOptions(templatePath, props, transformer.Options{})
+}
+
+// RenderFS renders the template at templatePath in a file system like Render,
+// loading the components it imports from the same file system
+func RenderFS(fsys fs.FS, templatePath string, props map[string]any) (Result, error) {
+	return RenderWithOptions(templatePath, props, transformer.Options{Modules: modules.NewGraphFS(fsys, nil)})
+}
+
+// RenderWithOptions renders a template like Render, using the given transform options
+func RenderWithOptions(templatePath string, props map[string]any, options transformer.Options) (Result, error) {
+	transformedAST, err := transform(templatePath, props, options)
+	if transformedAST == nil {
+		return Result{}, err
 	}
+	return Generate(transformedAST, nil), err
+}
+
+// RenderSSR renders a template like Render, with its expressions, conditions and
+// loops evaluated on the server, see GenerateSSR
+func RenderSSR(templatePath string, props map[string]any) (Result, error) {
+	transformedAST, err := transform(templatePath, props, transformer.Options{})
+	if transformedAST == nil {
+		return Result{}, err
+	}
+	return GenerateSSR(transformedAST, nil), err
+}
+
+// RenderStatic renders a template like Render, as plain HTML and CSS without
+// Alpine.js, see GenerateStatic
+func RenderStatic(templatePath string, props map[string]any) (Result, error) {
+	transformedAST, err := transform(templatePath, props, transformer.Options{})
+	if transformedAST == nil {
+		return Result{}, err
+	}
+	return GenerateStatic(transformedAST, nil), err
+}
 
-	// Parse the template to AST
-	templateAST, err := parser.ParseTemplate(string(content))
+// transform loads a template with the components it imports and transforms it,
+// returning a nil template when it can't be loaded
+func transform(templatePath string, props map[string]any, options transformer.Options) (*ast.Template, error) {
+	// Load the template and the components it imports
+	graph := options.Modules
+	if graph == nil {
+		graph = modules.NewGraph(nil)
+	}
+	module, err := graph.Load(templatePath)
 	if err != nil {
-		log.Fatalf("Error parsing template: %v", err)
+		return nil, err
 	}
+	options.Modules = graph
+	options.Path = module.Path
 
 	// Transform the AST to Alpine.js compatible nodes
-	transformedAST := transformer.TransformAST(templateAST, props)
-
-	// Generate markup, script, and style from the transformed AST
-	markup := generateMarkup(transformedAST)
-	script := generateScript(transformedAST)
-	style := generateStyle(transformedAST)
+	return transformer.Transform(module.Template, props, options)
+}
 
-	return markup, script, style
+// Generate returns the markup, script and style of a transformed template.
+// Warnings, like unknown modifiers, are logged to logger, or the standard logger
+// when it is nil.
+func Generate(template *ast.Template, logger *log.Logger) Result {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return Result{
+		Markup: generateMarkup(template, logger),
+		Script: generateScript(template),
+		Style:  generateStyle(template),
+	}
 }
 
 // --- Alpine.js Attribute Generation ---
@@ -107,11 +169,7 @@ func cleanupObjectLiteral(value string) string {
 	// Ensure there's a space after the opening brace and before the closing brace
 	content = " " + strings.TrimSpace(content) + " "
 
-	// Log the cleaned object for debugging
-	result := "{" + content + "}"
-	log.Printf("Cleaned object literal: %s", result)
-
-	return result
+	return "{" + content + "}"
 }
 
 // CleanupMethodDefinition ensures method definitions are properly formatted
@@ -150,8 +208,12 @@ func CleanupMethodDefinition(value string) string {
 	return value
 }
 
-// GenerateAlpineDirectives generates Alpine.js directives from attributes
-func GenerateAlpineDirectives(attributes []ast.Attribute) []string {
+// GenerateAlpineDirectives generates Alpine.js directives from attributes.
+// Warnings are logged to logger, or the standard logger when it is nil.
+func GenerateAlpineDirectives(attributes []ast.Attribute, logger *log.Logger) []string {
+	if logger == nil {
+		logger = log.Default()
+	}
 	var directives []string
 	var dataAttributes []ast.Attribute
 
@@ -180,7 +242,7 @@ func GenerateAlpineDirectives(attributes []ast.Attribute) []string {
 					mergedProps = append(mergedProps, props)
 				} else {
 					// If not an object, add as is (shouldn't happen with proper data)
-					log.Printf("Warning: Non-object data attribute found: %s", data.Value)
+					logger.Printf("Warning: Non-object data attribute found: %s", data.Value)
 					mergedProps = append(mergedProps, data.Value)
 				}
 			}
@@ -234,9 +296,9 @@ func GenerateAlpineDirectives(attributes []ast.Attribute) []string {
 					directives = append(directives, `x-bind:class="{ highlight: parentState === 'active' }"`)
 				} else if attr.Value != "" {
 					// Default handling for other Alpine directives
-					directives = append(directives, fmt.Sprintf(`%s="%s"`, attr.Name, escapeAttrValue(attr.Value, true)))
+					directives = append(directives, fmt.Sprintf(`%s="%s"`, alpineDirectiveName(attr, logger), escapeAttrValue(attr.Value, true)))
 				} else {
-					directives = append(directives, attr.Name)
+					directives = append(directives, alpineDirectiveName(attr, logger))
 				}
 			}
 		} else if attr.Dynamic {
@@ -419,12 +481,12 @@ func FormatJSValue(value any) string {
 	}
 }
 
-func generateMarkup(template *ast.Template) string {
+func generateMarkup(template *ast.Template, logger *log.Logger) string {
 	var sb strings.Builder
 
 	// Process each root node
 	for _, node := range template.RootNodes {
-		renderNode(&sb, node)
+		renderNode(&sb, node, logger)
 	}
 
 	return sb.String()
@@ -457,7 +519,7 @@ func generateStyle(template *ast.Template) string {
 }
 
 // renderNode renders a single AST node to HTML
-func renderNode(sb *strings.Builder, node ast.Node) {
+func renderNode(sb *strings.Builder, node ast.Node, logger *log.Logger) {
 	// Skip nil nodes
 	if node == nil {
 		return
@@ -465,7 +527,7 @@ func renderNode(sb *strings.Builder, node ast.Node) {
 
 	// Skip structural nodes that should not be rendered directly
 	switch node.(type) {
-	case *ast.ElseNode, *ast.ElseIfNode, *ast.IfEndNode, *ast.ForEndNode, *ast.FenceSection:
+	case *ast.ElseNode, *ast.ElseIfNode, *ast.IfEndNode, *ast.ForEndNode, *ast.FenceSection, *ast.ScriptSection, *ast.StyleSection:
 		// These nodes are structural and have already been transformed
 		// They don't need direct HTML rendering
 		return
@@ -474,7 +536,7 @@ func renderNode(sb *strings.Builder, node ast.Node) {
 	// Render actual content nodes
 	switch n := node.(type) {
 	case *ast.Element:
-		renderElement(sb, n)
+		renderElement(sb, n, logger)
 	case *ast.TextNode:
 		sb.WriteString(n.Content)
 	case *ast.CommentNode:
@@ -487,12 +549,12 @@ func renderNode(sb *strings.Builder, node ast.Node) {
 		sb.WriteString(fmt.Sprintf("<span x-text=\"%v\"></span>", n.Expression))
 	default:
 		// Log unknown node types but don't treat as errors
-		log.Printf("Unknown node type: %T", n)
+		logger.Printf("Warning: Unknown node type: %T", n)
 	}
 }
 
 // renderElement renders an element node to HTML
-func renderElement(sb *strings.Builder, el *ast.Element) {
+func renderElement(sb *strings.Builder, el *ast.Element, logger *log.Logger) {
 	// Start the opening tag
 	sb.WriteString("<")
 	sb.WriteString(el.TagName)
@@ -501,7 +563,7 @@ func renderElement(sb *strings.Builder, el *ast.Element) {
 	if len(el.Attributes) > 0 {
 		sb.WriteString(" ")
 		// Use the Alpine directives generator
-		directives := GenerateAlpineDirectives(el.Attributes)
+		directives := GenerateAlpineDirectives(el.Attributes, logger)
 		sb.WriteString(strings.Join(directives, " "))
 	}
 
@@ -514,7 +576,7 @@ func renderElement(sb *strings.Builder, el *ast.Element) {
 
 	// Render children
 	for _, child := range el.Children {
-		renderNode(sb, child)
+		renderNode(sb, child, logger)
 	}
 
 	// Render the closing tag
@@ -535,6 +597,12 @@ func hasAlpineDirective(attributes []ast.Attribute) bool {
 
 // extractScriptContent extracts script content from nodes
 func extractScriptContent(sb *strings.Builder, node ast.Node) {
+	if script, ok := node.(*ast.ScriptSection); ok {
+		sb.WriteString(script.Content)
+		sb.WriteString("\n")
+		return
+	}
+
 	if el, ok := node.(*ast.Element); ok {
 		if strings.ToLower(el.TagName) == "script" {
 			// Extract content from script tags
@@ -555,6 +623,12 @@ func extractScriptContent(sb *strings.Builder, node ast.Node) {
 
 // extractStyleContent extracts style content from nodes
 func extractStyleContent(sb *strings.Builder, node ast.Node) {
+	if style, ok := node.(*ast.StyleSection); ok {
+		sb.WriteString(style.Content)
+		sb.WriteString("\n")
+		return
+	}
+
 	if el, ok := node.(*ast.Element); ok {
 		if strings.ToLower(el.TagName) == "style" {
 			// Extract content from style tags
@@ -0,0 +1,697 @@
+package renderer
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+// ssrPrelude defines the helpers server-side rendering evaluates directives
+// with. They follow Alpine.js, so the page reads the same before and after it
+// starts.
+const ssrPrelude = `
+var __ssr = {
+	// scope returns a scope holding the properties of data over parent, like the
+	// nested x-data scopes of Alpine.js
+	scope: function(parent, data) {
+		return Object.defineProperties(Object.create(parent), Object.getOwnPropertyDescriptors(data));
+	},
+
+	text: function(value) {
+		return value === null || value === undefined ? "" : String(value);
+	},
+
+	// parseLoop returns the names and items expression of an x-for expression,
+	// or null when it isn't one
+	parseLoop: function(expression) {
+		var alias = expression.match(/([\s\S]*?)\s+(?:in|of)\s+([\s\S]*)/);
+		if (!alias) {
+			return null;
+		}
+		var names = {items: alias[2].trim()};
+		var item = alias[1].replace(/^\s*\(|\)\s*$/g, "").trim();
+		var iterator = item.match(/,([^,\}\]]*)(?:,([^,\}\]]*))?$/);
+		if (iterator) {
+			names.item = item.replace(/,([^,\}\]]*)(?:,([^,\}\]]*))?$/, "").trim();
+			names.index = iterator[1].trim();
+			if (iterator[2]) {
+				names.collection = iterator[2].trim();
+			}
+		} else {
+			names.item = item;
+		}
+		return names;
+	},
+
+	// loop returns the scopes of the items of an x-for loop
+	loop: function(names, items, parent) {
+		var entries = [];
+		if (typeof items === "number" && items >= 0) {
+			for (var n = 1; n <= items; n++) {
+				entries.push([n, n - 1]);
+			}
+		} else if (Array.isArray(items)) {
+			entries = items.map(function(value, i) { return [value, i]; });
+		} else if (items !== null && typeof items === "object") {
+			entries = Object.keys(items).map(function(key) { return [items[key], key]; });
+		}
+
+		return entries.map(function(entry) {
+			var value = entry[0], variables = {};
+			if (/^\[.*\]$/.test(names.item) && Array.isArray(value)) {
+				names.item.slice(1, -1).split(",").forEach(function(name, i) { variables[name.trim()] = value[i]; });
+			} else if (/^\{.*\}$/.test(names.item) && value !== null && typeof value === "object" && !Array.isArray(value)) {
+				names.item.slice(1, -1).split(",").forEach(function(name) { variables[name.trim()] = value[name.trim()]; });
+			} else {
+				variables[names.item] = value;
+			}
+			if (names.index) {
+				variables[names.index] = entry[1];
+			}
+			if (names.collection) {
+				variables[names.collection] = items;
+			}
+			return __ssr.scope(parent, variables);
+		});
+	},
+
+	// attribute returns the value of a bound attribute, or null to leave it out
+	attribute: function(name, value) {
+		if (name === "class") {
+			if (Array.isArray(value)) {
+				return value.filter(Boolean).join(" ");
+			}
+			if (value !== null && typeof value === "object") {
+				return Object.keys(value).filter(function(key) { return value[key]; }).join(" ");
+			}
+		}
+		if (name === "style" && value !== null && typeof value === "object") {
+			return Object.keys(value).map(function(key) {
+				return key.replace(/([a-z])([A-Z])/g, "$1-$2").toLowerCase() + ": " + value[key] + ";";
+			}).join(" ");
+		}
+		if (value === null || value === undefined || value === false) {
+			return /^aria-(pressed|checked|expanded|selected)$/.test(name) ? String(value) : null;
+		}
+		if (__ssr.booleanAttributes.indexOf(name) >= 0) {
+			return name;
+		}
+		return String(value);
+	},
+
+	booleanAttributes: ["disabled", "checked", "required", "readonly", "hidden", "open", "selected",
+		"autofocus", "itemscope", "multiple", "novalidate", "allowfullscreen", "allowpaymentrequest",
+		"formnovalidate", "autoplay", "controls", "loop", "muted", "playsinline", "default", "ismap",
+		"reversed", "async", "defer", "nomodule", "inert"]
+};
+`
+
+var ssrPreludeProgram = goja.MustCompile("ssr-prelude.js", ssrPrelude, false)
+
+// ssrPlugin is added to the script of pages with content rendered from x-if and
+// x-for templates on the server. Alpine.js renders the templates again when it
+// starts, so the server copies, marked with the data-ssr id of their template,
+// are removed on alpine:init. Alpine.js renders its own in the same task, before
+// the browser paints, so the content is neither doubled nor flashes. Copies
+// outside of components are kept, as Alpine.js never renders them.
+//
+// The classes of a bound class, listed in data-ssr-class, are removed as well:
+// Alpine.js only removes the classes it added itself when the binding changes,
+// so it must add them again.
+const ssrPlugin = `document.addEventListener('alpine:init', function () {
+	document.querySelectorAll('[data-ssr]').forEach(function (el) {
+		if (el.parentElement && el.parentElement.closest('[x-data]')) {
+			el.remove();
+		}
+	});
+	document.querySelectorAll('[data-ssr-class]').forEach(function (el) {
+		if (el.closest('[x-data]')) {
+			el.getAttribute('data-ssr-class').split(' ').forEach(function (name) {
+				el.classList.remove(name);
+			});
+			el.removeAttribute('data-ssr-class');
+		}
+	});
+});
+`
+
+// GenerateSSR returns the output of a transformed template like Generate, with
+// its Alpine.js directives evaluated on the server so the page reads the same
+// before Alpine.js starts: x-text and x-html are filled in, the active branch of
+// x-if and the items of x-for are rendered after their templates, x-show hides
+// elements and bound attributes are set. The directives are kept for Alpine.js.
+//
+// Expressions that fail on the server, like those using browser APIs, are
+// logged to logger, or the standard logger when it is nil, and left to the client.
+func GenerateSSR(template *ast.Template, logger *log.Logger) Result {
+	s := newSSR(logger, false)
+	var sb strings.Builder
+	s.nodes(&sb, template.RootNodes, s.vm.NewObject())
+	script := generateScript(template)
+	if s.marked {
+		script += ssrPlugin
+	}
+	return Result{
+		Markup: sb.String(),
+		Script: script,
+		Style:  generateStyle(template),
+	}
+}
+
+// GenerateStatic returns the output of a transformed template as plain HTML and
+// CSS, for pages without Alpine.js like emails. Its directives are evaluated on
+// the server like GenerateSSR does, then dropped: the templates of x-if and x-for
+// are replaced with their rendered content, elements x-show hides are left out,
+// and the x-data wrappers of pages and components are replaced with their
+// content. Scripts are left out, and the Script of the result is empty.
+//
+// Event handlers, x-model and other directives that need Alpine.js to work are
+// logged to logger, or the standard logger when it is nil, like expressions that
+// fail on the server. Elements whose x-text or x-html fails keep their content.
+func GenerateStatic(template *ast.Template, logger *log.Logger) Result {
+	s := newSSR(logger, true)
+	if strings.TrimSpace(generateScript(template)) != "" {
+		s.logger.Printf("Warning: the script of the template needs a browser and is left out of the static output")
+	}
+	var sb strings.Builder
+	s.nodes(&sb, template.RootNodes, s.vm.NewObject())
+	return Result{
+		Markup: sb.String(),
+		Style:  generateStyle(template),
+	}
+}
+
+func newSSR(logger *log.Logger, static bool) *ssr {
+	if logger == nil {
+		logger = log.Default()
+	}
+	s := &ssr{vm: goja.New(), logger: logger, programs: make(map[string]*goja.Program), static: static}
+	if _, err := s.vm.RunProgram(ssrPreludeProgram); err != nil {
+		// The prelude is a constant and always runs
+		panic(err)
+	}
+	s.helpers = s.vm.Get("__ssr").ToObject(s.vm)
+	return s
+}
+
+// ssr renders the markup of a template, evaluating its directives in a
+// JavaScript runtime
+type ssr struct {
+	vm       *goja.Runtime
+	logger   *log.Logger
+	helpers  *goja.Object
+	programs map[string]*goja.Program // compiled expressions
+	static   bool                     // drop the directives, see GenerateStatic
+
+	templates int  // x-if and x-for templates rendered, numbering them
+	marked    bool // whether the output has markers for ssrPlugin
+}
+
+// eval evaluates a JavaScript expression in a scope
+func (s *ssr) eval(expression string, scope goja.Value) (goja.Value, error) {
+	program, ok := s.programs[expression]
+	if !ok {
+		var err error
+		program, err = goja.Compile("", "with (__ssrScope) { (\n"+expression+"\n) }", false)
+		if err != nil {
+			return nil, err
+		}
+		s.programs[expression] = program
+	}
+	s.vm.Set("__ssrScope", scope)
+	return s.vm.RunProgram(program)
+}
+
+// call calls one of the prelude helpers
+func (s *ssr) call(helper string, args ...goja.Value) (goja.Value, error) {
+	fn, _ := goja.AssertFunction(s.helpers.Get(helper))
+	return fn(goja.Undefined(), args...)
+}
+
+func (s *ssr) warn(directive, expression string, err error) {
+	if s.static {
+		s.logger.Printf("Warning: could not evaluate %s=%q on the server, leaving it out of the static output: %v", directive, expression, err)
+		return
+	}
+	s.logger.Printf("Warning: could not evaluate %s=%q on the server, leaving it to the client: %v", directive, expression, err)
+}
+
+// text evaluates the text of an x-text expression
+func (s *ssr) text(directive, expression string, scope goja.Value) (string, bool) {
+	value, err := s.eval(expression, scope)
+	if err == nil {
+		value, err = s.call("text", value)
+	}
+	if err != nil {
+		s.warn(directive, expression, err)
+		return "", false
+	}
+	return value.String(), true
+}
+
+// nodes renders sibling nodes in a scope. Templates with x-else-if and x-else
+// render their content when no earlier template of their x-if chain did.
+func (s *ssr) nodes(sb *strings.Builder, nodes []ast.Node, scope goja.Value) {
+	// chain is nil outside of an x-if chain, and reports whether one of its
+	// templates rendered its content otherwise
+	var chain *bool
+	for _, node := range nodes {
+		el, ok := node.(*ast.Element)
+		if !ok || el.TagName != "template" {
+			if text, isText := node.(*ast.TextNode); !isText || strings.TrimSpace(text.Content) != "" {
+				chain = nil
+			}
+			s.node(sb, node, scope)
+			continue
+		}
+
+		attr, found := templateDirective(el)
+		if !found {
+			chain = nil
+			renderNode(sb, el, s.logger)
+			continue
+		}
+		s.templates++
+		id := fmt.Sprint(s.templates)
+		if !s.static {
+			marked := *el
+			marked.Attributes = append(append([]ast.Attribute(nil), el.Attributes...), ast.Attribute{Name: "data-ssr-id", Value: id})
+			renderNode(sb, &marked, s.logger)
+		}
+
+		switch attr.AlpineType {
+		case "for":
+			chain = nil
+			s.loop(sb, el, attr.Value, scope, id)
+			continue
+		case "if":
+			chain = new(bool)
+		}
+		if chain == nil || *chain {
+			continue
+		}
+		if attr.AlpineType != "else" {
+			value, err := s.eval(attr.Value, scope)
+			if err != nil {
+				s.warn("x-"+attr.AlpineType, attr.Value, err)
+				chain = nil
+				continue
+			}
+			if !value.ToBoolean() {
+				continue
+			}
+		}
+		*chain = true
+		s.roots(sb, el.Children, scope, id)
+	}
+}
+
+// roots renders the content of the x-if or x-for template with an id, marking
+// each of its root nodes with the id so the client drops them before Alpine.js
+// renders its own, see ssrPlugin. Text and templates can't be marked and are
+// wrapped in a marked span instead. Static output has no markers.
+func (s *ssr) roots(sb *strings.Builder, nodes []ast.Node, scope goja.Value, id string) {
+	if s.static {
+		s.nodes(sb, nodes, scope)
+		return
+	}
+	s.marked = true
+	marker := ast.Attribute{Name: "data-ssr", Value: id}
+	var unmarked []ast.Node
+	flush := func() {
+		// Structural nodes render nothing and need no span
+		var content strings.Builder
+		s.nodes(&content, unmarked, scope)
+		if content.Len() > 0 {
+			sb.WriteString(`<span data-ssr="` + id + `" style="display: contents">`)
+			sb.WriteString(content.String())
+			sb.WriteString("</span>")
+		}
+		unmarked = nil
+	}
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *ast.TextNode:
+			// Whitespace between the roots isn't rendered by Alpine.js either
+			if strings.TrimSpace(n.Content) == "" {
+				continue
+			}
+		case *ast.CommentNode:
+			continue
+		case *ast.Element:
+			switch strings.ToLower(n.TagName) {
+			case "template", "script", "style":
+			default:
+				flush()
+				s.element(sb, n, scope, marker)
+				continue
+			}
+		}
+		unmarked = append(unmarked, node)
+	}
+	flush()
+}
+
+// templateDirective returns the x-if, x-else-if, x-else or x-for directive of a
+// template
+func templateDirective(el *ast.Element) (ast.Attribute, bool) {
+	for _, directiveType := range []string{"for", "if", "else-if", "else"} {
+		if attr, found := alpineAttribute(el, directiveType); found {
+			return attr, true
+		}
+	}
+	return ast.Attribute{}, false
+}
+
+// loop renders the content of the x-for template with an id once for each item
+func (s *ssr) loop(sb *strings.Builder, el *ast.Element, expression string, scope goja.Value, id string) {
+	names, err := s.call("parseLoop", s.vm.ToValue(expression))
+	if err == nil && goja.IsNull(names) {
+		err = fmt.Errorf("not a loop expression")
+	}
+	var scopes goja.Value
+	if err == nil {
+		var items goja.Value
+		items, err = s.eval(names.ToObject(s.vm).Get("items").String(), scope)
+		if err == nil {
+			scopes, err = s.call("loop", names, items, scope)
+		}
+	}
+	var items []goja.Value
+	if err == nil {
+		err = s.vm.ExportTo(scopes, &items)
+	}
+	if err != nil {
+		s.warn("x-for", expression, err)
+		return
+	}
+	for _, item := range items {
+		s.roots(sb, el.Children, item, id)
+	}
+}
+
+// node renders a node in a scope
+func (s *ssr) node(sb *strings.Builder, node ast.Node, scope goja.Value) {
+	switch n := node.(type) {
+	case *ast.Element:
+		s.element(sb, n, scope)
+	case *ast.ExpressionNode:
+		text, _ := s.text("x-text", n.Expression, scope)
+		if s.static {
+			sb.WriteString(escapeText(text))
+			return
+		}
+		sb.WriteString(fmt.Sprintf("<span x-text=\"%v\">", n.Expression))
+		sb.WriteString(escapeText(text))
+		sb.WriteString("</span>")
+	default:
+		renderNode(sb, node, s.logger)
+	}
+}
+
+// element renders an element in a scope, in the scope of its x-data when it has
+// one, adding the marker attributes
+func (s *ssr) element(sb *strings.Builder, el *ast.Element, scope goja.Value, markers ...ast.Attribute) {
+	switch strings.ToLower(el.TagName) {
+	case "script":
+		if !s.static {
+			renderNode(sb, el, s.logger)
+		}
+		return
+	case "style":
+		renderNode(sb, el, s.logger)
+		return
+	}
+
+	if data := dataExpression(el.Attributes); data != "" {
+		value, err := s.eval(data, scope)
+		if err == nil {
+			value, err = s.call("scope", scope, value)
+		}
+		if err != nil {
+			s.warn("x-data", data, err)
+		} else {
+			scope = value
+		}
+	}
+
+	if s.static && s.hidden(el, scope) {
+		return
+	}
+	attributes := append(s.attributes(el, scope), markers...)
+	content, hasContent := s.content(el, scope)
+	if s.static {
+		attributes = staticAttributes(attributes)
+		if len(attributes) == 0 && isWrapper(el) {
+			if hasContent {
+				sb.WriteString(content)
+			} else {
+				s.nodes(sb, el.Children, scope)
+			}
+			return
+		}
+	}
+
+	sb.WriteString("<")
+	sb.WriteString(el.TagName)
+	if len(attributes) > 0 {
+		sb.WriteString(" ")
+		sb.WriteString(strings.Join(GenerateAlpineDirectives(attributes, s.logger), " "))
+	}
+	if el.SelfClosing && !hasContent {
+		sb.WriteString(" />")
+		return
+	}
+	sb.WriteString(">")
+	if hasContent {
+		sb.WriteString(content)
+	} else {
+		s.nodes(sb, el.Children, scope)
+	}
+	sb.WriteString("</")
+	sb.WriteString(el.TagName)
+	sb.WriteString(">")
+}
+
+// hidden reports whether the x-show of an element hides it
+func (s *ssr) hidden(el *ast.Element, scope goja.Value) bool {
+	attr, found := alpineAttribute(el, "show")
+	if !found {
+		return false
+	}
+	value, err := s.eval(attr.Value, scope)
+	if err != nil {
+		s.warn(attr.Name, attr.Value, err)
+		return false
+	}
+	return !value.ToBoolean()
+}
+
+// content returns the content x-text, x-html or x-model give an element
+func (s *ssr) content(el *ast.Element, scope goja.Value) (string, bool) {
+	if attr, found := alpineAttribute(el, "text"); found {
+		text, ok := s.text(attr.Name, attr.Value, scope)
+		return escapeText(text), ok
+	}
+	if attr, found := alpineAttribute(el, "html"); found {
+		return s.text(attr.Name, attr.Value, scope)
+	}
+	if attr, found := alpineAttribute(el, "model"); found && strings.ToLower(el.TagName) == "textarea" {
+		text, ok := s.text(attr.Name, attr.Value, scope)
+		return escapeText(text), ok
+	}
+	return "", false
+}
+
+// attributes returns the attributes of an element with the values of its bound
+// attributes, x-show and x-model set
+func (s *ssr) attributes(el *ast.Element, scope goja.Value) []ast.Attribute {
+	attributes := append([]ast.Attribute(nil), el.Attributes...)
+	for _, attr := range el.Attributes {
+		if s.static && attr.IsAlpine && needsRuntime[attr.AlpineType] {
+			s.logger.Printf("Warning: %s=%q needs Alpine.js and does nothing in the static output", attr.Name, attr.Value)
+		}
+		switch {
+		case attr.IsAlpine && attr.AlpineType == "bind" && attr.AlpineKey != "":
+			attributes = s.bind(attributes, attr.Name, attr.AlpineKey, attr.Value, scope)
+		case !attr.IsAlpine && attr.Dynamic:
+			attributes = s.bind(attributes, ":"+attr.Name, attr.Name, attr.Value, scope)
+		case attr.IsAlpine && attr.AlpineType == "show" && !s.static:
+			value, err := s.eval(attr.Value, scope)
+			if err != nil {
+				s.warn(attr.Name, attr.Value, err)
+			} else if !value.ToBoolean() {
+				attributes = setAttribute(attributes, "style", "display: none;")
+			}
+		case attr.IsAlpine && attr.AlpineType == "model" && strings.ToLower(el.TagName) == "input":
+			value, err := s.eval(attr.Value, scope)
+			if err != nil {
+				s.warn(attr.Name, attr.Value, err)
+				continue
+			}
+			switch strings.ToLower(staticAttribute(el.Attributes, "type")) {
+			case "checkbox", "radio":
+				if checked, ok := value.Export().(bool); ok && checked {
+					attributes = setAttribute(attributes, "checked", "checked")
+				}
+			default:
+				text, err := s.call("text", value)
+				if err == nil {
+					attributes = setAttribute(attributes, "value", text.String())
+				}
+			}
+		}
+	}
+	return attributes
+}
+
+// needsRuntime are the directives that do nothing without Alpine.js
+var needsRuntime = map[string]bool{"on": true, "model": true, "init": true, "effect": true, "modelable": true}
+
+// staticAttributes returns the attributes that aren't directives or bindings
+func staticAttributes(attributes []ast.Attribute) []ast.Attribute {
+	var static []ast.Attribute
+	for _, attr := range attributes {
+		if attr.IsAlpine || attr.Dynamic || strings.HasPrefix(attr.Name, "x-") {
+			continue
+		}
+		static = append(static, attr)
+	}
+	return static
+}
+
+// isWrapper reports whether an element, left without attributes, only served
+// Alpine.js: the x-data div of a page or a component instance, or a span holding
+// an expression
+func isWrapper(el *ast.Element) bool {
+	switch el.TagName {
+	case "div":
+		_, found := alpineAttribute(el, "data")
+		return found || staticAttribute(el.Attributes, "x-component") != ""
+	case "span":
+		return len(el.Attributes) > 0
+	}
+	return false
+}
+
+// bind sets the attribute a binding resolves to
+func (s *ssr) bind(attributes []ast.Attribute, directive, name, expression string, scope goja.Value) []ast.Attribute {
+	value, err := s.eval(expression, scope)
+	if err == nil {
+		value, err = s.call("attribute", s.vm.ToValue(name), value)
+	}
+	if err != nil {
+		s.warn(directive, expression, err)
+		return attributes
+	}
+	if goja.IsNull(value) {
+		return attributes
+	}
+	if name == "class" && !s.static {
+		attributes = s.markClasses(attributes, value.String())
+	}
+	return setAttribute(attributes, name, value.String())
+}
+
+// markClasses lists the classes a bound class adds to the static ones in a
+// data-ssr-class attribute, for ssrPlugin to remove before Alpine.js adds them
+func (s *ssr) markClasses(attributes []ast.Attribute, value string) []ast.Attribute {
+	seen := make(map[string]bool)
+	for _, name := range strings.Fields(staticAttribute(attributes, "class")) {
+		seen[name] = true
+	}
+	var added []string
+	for _, name := range strings.Fields(value) {
+		if !seen[name] {
+			seen[name] = true
+			added = append(added, name)
+		}
+	}
+	if len(added) == 0 {
+		return attributes
+	}
+	s.marked = true
+	for i, attr := range attributes {
+		if attr.Name == "data-ssr-class" {
+			attributes[i].Value = attr.Value + " " + strings.Join(added, " ")
+			return attributes
+		}
+	}
+	return append(attributes, ast.Attribute{Name: "data-ssr-class", Value: strings.Join(added, " ")})
+}
+
+// setAttribute sets a static attribute. Classes and styles are added to those
+// the element has, like Alpine.js does.
+func setAttribute(attributes []ast.Attribute, name, value string) []ast.Attribute {
+	for i, attr := range attributes {
+		if attr.IsAlpine || attr.Dynamic || attr.Name != name {
+			continue
+		}
+		switch {
+		case (name == "class" || name == "style") && value == "":
+		case name == "class" && attr.Value != "":
+			attributes[i].Value = attr.Value + " " + value
+		case name == "style" && attr.Value != "":
+			attributes[i].Value = strings.TrimSuffix(strings.TrimSpace(attr.Value), ";") + "; " + value
+		default:
+			attributes[i].Value = value
+		}
+		return attributes
+	}
+	return append(attributes, ast.Attribute{Name: name, Value: value})
+}
+
+// alpineAttribute returns the Alpine.js directive of a type on an element
+func alpineAttribute(el *ast.Element, directiveType string) (ast.Attribute, bool) {
+	for _, attr := range el.Attributes {
+		if attr.IsAlpine && attr.AlpineType == directiveType {
+			return attr, true
+		}
+	}
+	return ast.Attribute{}, false
+}
+
+// staticAttribute returns the value of an attribute that isn't bound
+func staticAttribute(attributes []ast.Attribute, name string) string {
+	for _, attr := range attributes {
+		if !attr.IsAlpine && !attr.Dynamic && attr.Name == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// dataExpression returns the x-data of an element as Alpine.js evaluates it.
+// Elements with several x-data attributes get them merged, see
+// GenerateAlpineDirectives.
+func dataExpression(attributes []ast.Attribute) string {
+	var data []string
+	for _, attr := range attributes {
+		if attr.IsAlpine && attr.AlpineType == "data" {
+			data = append(data, strings.TrimSpace(attr.Value))
+		}
+	}
+	switch len(data) {
+	case 0:
+		return ""
+	case 1:
+		// Data scopes may quote their keys with entities, see formatGoValueToJS
+		return html.UnescapeString(data[0])
+	}
+	for i, object := range data {
+		data[i] = strings.TrimSuffix(strings.TrimPrefix(object, "{"), "}")
+	}
+	return html.UnescapeString("{ " + strings.Join(data, ", ") + " }")
+}
+
+// escapeText escapes text for the content of an element
+func escapeText(text string) string {
+	text = strings.ReplaceAll(text, "&", "&amp;")
+	text = strings.ReplaceAll(text, "<", "&lt;")
+	return strings.ReplaceAll(text, ">", "&gt;")
+}
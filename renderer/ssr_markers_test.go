@@ -0,0 +1,379 @@
+package renderer
+
+import (
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/parser"
+	"github.com/jimafisk/custom_go_template/transformer"
+)
+
+// checkSSRMarkers checks the hydration markers of server-rendered markup against
+// the transformed template it was rendered from:
+//   - each x-if, x-else-if, x-else and x-for template has a unique data-ssr-id
+//     and is one of the templates of the transformed template
+//   - the nodes rendered from a template directly follow it, each marked with its
+//     id, and match the root nodes of its content: once for a condition, once per
+//     item for a loop
+//   - at most one template of an x-if chain has its content rendered
+//   - template content is left as it is, and no marked node is left over
+func checkSSRMarkers(t *testing.T, transformed *ast.Template, markup string) {
+	t.Helper()
+
+	// The root nodes of the content of each template, by directive
+	expected := make(map[string][]string)
+	var collect func(nodes []ast.Node)
+	collect = func(nodes []ast.Node) {
+		for _, node := range nodes {
+			el, ok := node.(*ast.Element)
+			if !ok {
+				continue
+			}
+			if attr, found := templateDirective(el); found && el.TagName == "template" {
+				expected[directiveKey(attr.AlpineType, attr.Value)] = astRoots(el.Children)
+			}
+			collect(el.Children)
+		}
+	}
+	collect(transformed.RootNodes)
+
+	nodes, err := html.ParseFragment(strings.NewReader(markup), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		t.Fatalf("Markup doesn't parse: %v", err)
+	}
+
+	ids := make(map[string]bool)
+	roots := make(map[*html.Node]bool)
+	var check func(n *html.Node, inTemplate bool)
+	check = func(n *html.Node, inTemplate bool) {
+		if n.Type == html.ElementNode {
+			id, hasID := attribute(n, "data-ssr-id")
+			if _, marked := attribute(n, "data-ssr"); inTemplate && (hasID || marked) {
+				t.Errorf("Template content has hydration markers: %s", render(n))
+			}
+			if n.Data == "template" && hasID && !inTemplate {
+				checkTemplate(t, n, id, expected, ids, roots)
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			check(child, inTemplate || n.Data == "template")
+		}
+	}
+	for _, n := range nodes {
+		check(n, false)
+	}
+
+	var orphans func(n *html.Node)
+	orphans = func(n *html.Node) {
+		if _, marked := attribute(n, "data-ssr"); marked && !roots[n] {
+			t.Errorf("Marked node doesn't follow its template: %s", render(n))
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			orphans(child)
+		}
+	}
+	for _, n := range nodes {
+		orphans(n)
+	}
+}
+
+// checkTemplate checks the nodes rendered from a template
+func checkTemplate(t *testing.T, template *html.Node, id string, expected map[string][]string, ids map[string]bool, roots map[*html.Node]bool) {
+	t.Helper()
+	if ids[id] {
+		t.Errorf("Duplicate data-ssr-id %s", id)
+	}
+	ids[id] = true
+
+	directive, value := "", ""
+	for _, attr := range template.Attr {
+		if name := strings.TrimPrefix(attr.Key, "x-"); name != attr.Key {
+			directive, value = name, attr.Val
+		}
+	}
+	want, known := expected[directiveKey(directive, value)]
+	if !known {
+		t.Errorf("Template %s isn't in the transformed template", render(template))
+		return
+	}
+
+	var got []string
+	for sibling := template.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+		if marker, _ := attribute(sibling, "data-ssr"); marker == id {
+			got = append(got, sibling.Data)
+			roots[sibling] = true
+		} else if sibling.Type != html.TextNode || strings.TrimSpace(sibling.Data) != "" {
+			break
+		}
+	}
+	switch {
+	case len(got) == 0:
+	case directive == "for":
+		if len(want) == 0 || len(got)%len(want) != 0 {
+			t.Errorf("Template %s rendered %v, want items of %v", render(template), got, want)
+			break
+		}
+		for i := range got {
+			if got[i] != want[i%len(want)] {
+				t.Errorf("Template %s rendered %v, want items of %v", render(template), got, want)
+				break
+			}
+		}
+	case strings.Join(got, " ") != strings.Join(want, " "):
+		t.Errorf("Template %s rendered %v, want %v", render(template), got, want)
+	}
+
+	// The following templates of an x-if chain don't render once one did
+	if directive == "if" || directive == "else-if" {
+		rendered := len(got) > 0
+		for sibling := template.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+			if sibling.Type != html.ElementNode {
+				continue
+			}
+			if _, marked := attribute(sibling, "data-ssr"); marked {
+				continue
+			}
+			_, elseIf := attribute(sibling, "x-else-if")
+			_, isElse := attribute(sibling, "x-else")
+			if sibling.Data != "template" || (!elseIf && !isElse) {
+				break
+			}
+			siblingID, _ := attribute(sibling, "data-ssr-id")
+			next := sibling.NextSibling
+			for next != nil && next.Type == html.TextNode && strings.TrimSpace(next.Data) == "" {
+				next = next.NextSibling
+			}
+			if marker, _ := attribute(next, "data-ssr"); marker == siblingID && rendered {
+				t.Errorf("Several templates of the x-if chain of %s rendered their content", render(template))
+			}
+			break
+		}
+	}
+}
+
+// astRoots returns the tags of the root nodes of the content of a template as
+// they are rendered, where text and templates are wrapped in a span
+func astRoots(nodes []ast.Node) []string {
+	var roots []string
+	wrapped := false
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *ast.TextNode:
+			if strings.TrimSpace(n.Content) == "" {
+				continue
+			}
+		case *ast.CommentNode, *ast.ElseNode, *ast.ElseIfNode, *ast.IfEndNode, *ast.ForEndNode:
+			continue
+		case *ast.Element:
+			switch strings.ToLower(n.TagName) {
+			case "template", "script", "style":
+			default:
+				roots = append(roots, n.TagName)
+				wrapped = false
+				continue
+			}
+		}
+		if !wrapped {
+			roots = append(roots, "span")
+			wrapped = true
+		}
+	}
+	return roots
+}
+
+func directiveKey(directive, value string) string {
+	return directive + "=" + value
+}
+
+func attribute(n *html.Node, key string) (string, bool) {
+	if n == nil {
+		return "", false
+	}
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func render(n *html.Node) string {
+	var sb strings.Builder
+	html.Render(&sb, n)
+	return sb.String()
+}
+
+func TestSSRMarkers(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		template *ast.Template // used instead of source when set
+		props    map[string]any
+	}{
+		{
+			name:   "nested loops and conditions",
+			source: "---\nprop categories;\n---\n<main>\n\t{for category in categories}\n\t\t<section>\n\t\t\t<h2>{category.name}</h2>\n\t\t\t<ul>\n\t\t\t\t{for item in category.items}\n\t\t\t\t\t<li>{item}</li>\n\t\t\t\t{end}\n\t\t\t</ul>\n\t\t\t{if category.items.length > 1}\n\t\t\t\t<p>Several</p>\n\t\t\t{end}\n\t\t</section>\n\t{end}\n</main>\n",
+			props: map[string]any{"categories": []any{
+				map[string]any{"name": "Drinks", "items": []any{"Tea", "Coffee"}},
+				map[string]any{"name": "Food", "items": []any{"Cake"}},
+				map[string]any{"name": "Empty", "items": []any{}},
+			}},
+		},
+		{
+			name:   "loop over text",
+			source: "---\nlet words = [\"a\", \"b\"];\n---\n<p>\n\t{for word in words}\n\t\t{word},\n\t{end}\n</p>\n",
+		},
+		{
+			name: "x-if chain",
+			template: &ast.Template{RootNodes: []ast.Node{
+				&ast.FenceSection{RawContent: "let role = \"editor\";"},
+				&ast.Element{TagName: "div", Children: []ast.Node{&ast.Conditional{
+					IfCondition:      "role === 'admin'",
+					IfContent:        []ast.Node{&ast.Element{TagName: "b", Children: []ast.Node{&ast.TextNode{Content: "Admin"}}}},
+					ElseIfConditions: []string{"role === 'editor'", "role.length > 0"},
+					ElseIfContent: [][]ast.Node{
+						{&ast.Element{TagName: "i", Children: []ast.Node{&ast.TextNode{Content: "Editor"}}}},
+						{&ast.Element{TagName: "u", Children: []ast.Node{&ast.TextNode{Content: "Someone"}}}},
+					},
+					ElseContent: []ast.Node{&ast.TextNode{Content: "Nobody"}},
+				}}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := tt.template
+			if template == nil {
+				var err error
+				if template, err = parser.ParseTemplate(tt.source); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			transformed, err := transformer.Transform(template, tt.props, transformer.Options{Logger: log.New(io.Discard, "", 0)})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result := GenerateSSR(transformed, log.New(io.Discard, "", 0))
+			if !strings.Contains(result.Markup, `data-ssr="`) {
+				t.Fatalf("Expected content rendered from templates, got:\n%s", result.Markup)
+			}
+			if !strings.Contains(result.Script, "alpine:init") {
+				t.Errorf("Expected the script to drop the server copies when Alpine.js starts, got:\n%s", result.Script)
+			}
+			checkSSRMarkers(t, transformed, result.Markup)
+		})
+	}
+}
+
+// hydrationDOM is a minimal DOM for the elements of server-rendered markup,
+// enough for ssrPlugin and Alpine.js's handling of bound classes
+const hydrationDOM = `
+var elements = nodes.map(function(node) {
+	var el = { attrs: node.attrs, removed: false };
+	el.getAttribute = function(name) { return name in el.attrs ? el.attrs[name] : null; };
+	el.removeAttribute = function(name) { delete el.attrs[name]; };
+	el.closest = function(selector) {
+		for (var e = el; e; e = e.parentElement) {
+			if (selector.slice(1, -1) in e.attrs) return e;
+		}
+		return null;
+	};
+	el.remove = function() { el.removed = true; };
+	var classes = function() { return (el.attrs["class"] || "").split(" ").filter(Boolean); };
+	el.classList = {
+		contains: function(name) { return classes().indexOf(name) >= 0; },
+		add: function(...names) {
+			el.attrs["class"] = classes().concat(names.filter(function(n) { return !el.classList.contains(n); })).join(" ");
+		},
+		remove: function(...names) {
+			el.attrs["class"] = classes().filter(function(n) { return names.indexOf(n) < 0; }).join(" ");
+		}
+	};
+	return el;
+});
+nodes.forEach(function(node, i) { elements[i].parentElement = node.parent >= 0 ? elements[node.parent] : null; });
+
+var listeners = {};
+var document = {
+	addEventListener: function(name, listener) { (listeners[name] = listeners[name] || []).push(listener); },
+	querySelectorAll: function(selector) {
+		return elements.filter(function(el) { return !el.removed && selector.slice(1, -1) in el.attrs; });
+	}
+};
+
+// bindClass sets a bound class like Alpine.js: the classes the element lacks are
+// added, and only those are removed when the value changes
+function bindClass(el, value) {
+	if (el.undoClasses) el.undoClasses();
+	var missing = value.split(" ").filter(Boolean).filter(function(n) { return !el.classList.contains(n); });
+	el.classList.add(...missing);
+	el.undoClasses = function() { el.classList.remove(...missing); };
+}
+`
+
+func TestSSRClassHydration(t *testing.T) {
+	template, err := parser.ParseTemplate("---\nlet on = true;\n---\n<div>\n\t<p class=\"item\" :class=\"on ? 'active' : ''\">Item</p>\n</div>\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transformed, err := transformer.Transform(template, nil, transformer.Options{Logger: log.New(io.Discard, "", 0)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result := GenerateSSR(transformed, log.New(io.Discard, "", 0))
+	if !strings.Contains(result.Markup, `class="item active"`) {
+		t.Fatalf("Expected the bound class to be rendered, got:\n%s", result.Markup)
+	}
+
+	// The elements of the markup, with the index of their parent
+	parsed, err := html.ParseFragment(strings.NewReader(result.Markup), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		t.Fatalf("Markup doesn't parse: %v", err)
+	}
+	var nodes []map[string]any
+	var collect func(n *html.Node, parent int)
+	collect = func(n *html.Node, parent int) {
+		if n.Type == html.ElementNode {
+			attrs := make(map[string]any)
+			for _, attr := range n.Attr {
+				attrs[attr.Key] = attr.Val
+			}
+			nodes = append(nodes, map[string]any{"attrs": attrs, "parent": parent})
+			parent = len(nodes) - 1
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			collect(child, parent)
+		}
+	}
+	for _, n := range parsed {
+		collect(n, -1)
+	}
+
+	vm := goja.New()
+	vm.Set("nodes", nodes)
+	if _, err := vm.RunString(hydrationDOM + result.Script); err != nil {
+		t.Fatalf("Script fails: %v", err)
+	}
+	classes, err := vm.RunString(`
+		(listeners["alpine:init"] || []).forEach(function(listener) { listener(); });
+		var el = elements.filter(function(el) { return ":class" in el.attrs; })[0];
+		var started = (bindClass(el, "active"), el.attrs["class"]);
+		bindClass(el, "");
+		[started, el.attrs["class"]];
+	`)
+	if err != nil {
+		t.Fatalf("Hydration fails: %v", err)
+	}
+	if got, want := classes.Export(), []any{"item active", "item"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Classes when Alpine.js starts and after the binding turns off = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package renderer
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/parser"
+	"github.com/jimafisk/custom_go_template/transformer"
+)
+
+func TestGenerateSSR(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		template    *ast.Template // used instead of source when set
+		props       map[string]any
+		contains    []string
+		notContains []string
+		warns       bool
+	}{
+		{
+			name:     "expressions",
+			source:   "---\nprop user;\n---\n<h1>Hi {user.name}!</h1>\n<p x-text=\"user.tags.join(', ')\">...</p>\n",
+			props:    map[string]any{"user": map[string]any{"name": "Ada <3", "tags": []any{"go", "js"}}},
+			contains: []string{`<span x-text="user.name">Ada &lt;3</span>!`, `<p x-text="user.tags.join(&#39;, &#39;)">go, js</p>`},
+		},
+		{
+			name:     "loop",
+			source:   "---\nlet items = [{name: \"Tea\"}, {name: \"Cake\"}];\n---\n<ul>\n\t{for item in items}\n\t\t<li>{item.name}</li>\n\t{end}\n</ul>\n",
+			contains: []string{`<template x-for=`, `</template><li data-ssr="1"><span x-text="item.name">Tea</span></li><li data-ssr="1"><span x-text="item.name">Cake</span></li></ul>`},
+		},
+		{
+			name:     "loop over a number and an object with an index",
+			source:   "---\nlet scores = {ada: 3, bob: 5};\n---\n<p><template x-for=\"i in 3\"><b x-text=\"i\"></b></template><template x-for=\"(score, name) in scores\"><i x-text=\"name + '=' + score\"></i></template></p>\n",
+			contains: []string{`<b x-text="i" data-ssr="1">1</b><b x-text="i" data-ssr="1">2</b><b x-text="i" data-ssr="1">3</b>`, `<i x-text="name + &#39;=&#39; + score" data-ssr="2">ada=3</i><i x-text="name + &#39;=&#39; + score" data-ssr="2">bob=5</i>`},
+		},
+		{
+			name:        "condition",
+			source:      "---\nlet count = 2;\n---\n<div>\n\t{if count > 1}<b>Many</b>{end}\n\t{if count > 5}<b>Lots</b>{end}\n</div>\n",
+			contains:    []string{`<template x-if="count &gt; 1" data-ssr-id="1"><b>Many</b></template><b data-ssr="1">Many</b>`},
+			notContains: []string{`<b data-ssr="2">Lots</b>`},
+		},
+		{
+			name: "else-if and else",
+			template: &ast.Template{RootNodes: []ast.Node{
+				&ast.FenceSection{RawContent: "let status = \"pending\";"},
+				&ast.Element{TagName: "div", Children: []ast.Node{&ast.Conditional{
+					IfCondition:      "status === 'active'",
+					IfContent:        []ast.Node{&ast.TextNode{Content: "Active"}},
+					ElseIfConditions: []string{"status === 'pending'"},
+					ElseIfContent:    [][]ast.Node{{&ast.TextNode{Content: "Pending"}}},
+					ElseContent:      []ast.Node{&ast.TextNode{Content: "Inactive"}},
+				}}},
+			}},
+			contains:    []string{`<template x-else-if="status === &#39;pending&#39;" data-ssr-id="2">Pending</template><span data-ssr="2" style="display: contents">Pending</span>`},
+			notContains: []string{"</template>Active", "</template>Inactive", `data-ssr="1"`, `data-ssr="3"`},
+		},
+		{
+			name: "nested data",
+			template: &ast.Template{RootNodes: []ast.Node{
+				&ast.Element{TagName: "main", Attributes: []ast.Attribute{{Name: "x-data", Value: "{ name: 'Ada' }", IsAlpine: true, AlpineType: "data"}}, Children: []ast.Node{
+					&ast.Element{TagName: "section", Attributes: []ast.Attribute{{Name: "x-data", Value: "{ greeting: 'Hello' }", IsAlpine: true, AlpineType: "data"}}, Children: []ast.Node{
+						&ast.Element{TagName: "p", Attributes: []ast.Attribute{{Name: "x-text", Value: "greeting + ' ' + name", IsAlpine: true, AlpineType: "text"}}},
+					}},
+				}},
+			}},
+			contains: []string{`<p x-text="greeting + &#39; &#39; + name">Hello Ada</p>`},
+		},
+		{
+			name:   "attributes",
+			source: "---\nlet open = false;\nlet active = true;\n---\n<nav>\n\t<div x-show=\"open\">Menu</div>\n\t<div x-show=\"!open\" style=\"color: red\">Closed</div>\n\t<a class=\"link\" :class=\"{ active: active, hidden: open }\" :href=\"'/items/' + 1\">Item</a>\n\t<button :disabled=\"!active\" :aria-expanded=\"open\">Toggle</button>\n\t<input x-model=\"active\" type=\"checkbox\">\n</nav>\n",
+			contains: []string{
+				`<div x-show="open" style="display: none;">Menu</div>`,
+				`<div x-show="!open" style="color: red">Closed</div>`,
+				`class="link active"`,
+				`href="/items/1"`,
+				`aria-expanded="false"`,
+				`checked="checked"`,
+			},
+			notContains: []string{` disabled="disabled"`},
+		},
+		{
+			name:     "html",
+			source:   "---\nlet note = \"<em>new</em>\";\n---\n<div><p x-html=\"note\"></p></div>\n",
+			contains: []string{`<p x-html="note"><em>new</em></p>`},
+		},
+		{
+			name:     "expression only the browser can evaluate",
+			source:   "<div><p x-text=\"window.innerWidth\">Loading</p></div>\n",
+			contains: []string{`<p x-text="window.innerWidth">Loading</p>`},
+			warns:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := tt.template
+			if template == nil {
+				var err error
+				if template, err = parser.ParseTemplate(tt.source); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			transformed, err := transformer.Transform(template, tt.props, transformer.Options{Logger: log.New(io.Discard, "", 0)})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var warnings bytes.Buffer
+			markup := GenerateSSR(transformed, log.New(&warnings, "", 0)).Markup
+			for _, expected := range tt.contains {
+				if !strings.Contains(markup, expected) {
+					t.Errorf("Expected markup to contain %q, got:\n%s", expected, markup)
+				}
+			}
+			for _, unexpected := range tt.notContains {
+				if strings.Contains(markup, unexpected) {
+					t.Errorf("Expected markup not to contain %q, got:\n%s", unexpected, markup)
+				}
+			}
+			if tt.warns != (warnings.Len() > 0) {
+				t.Errorf("Expected warnings %t, got %q", tt.warns, warnings.String())
+			}
+			checkSSRMarkers(t, transformed, markup)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package renderer
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/parser"
+	"github.com/jimafisk/custom_go_template/transformer"
+)
+
+func TestGenerateStatic(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		template *ast.Template // used instead of source when set
+		props    map[string]any
+		expected string
+		warnings []string
+	}{
+		{
+			name:     "expressions and loops",
+			source:   "---\nprop user;\n---\n<h1>Hi {user.name}!</h1>\n<ul>\n\t{for tag in user.tags}\n\t\t<li>{tag}</li>\n\t{end}\n</ul>\n",
+			props:    map[string]any{"user": map[string]any{"name": "Ada <3", "tags": []any{"go", "js"}}},
+			expected: "<h1>Hi Ada &lt;3!</h1><ul><li>go</li><li>js</li></ul>",
+		},
+		{
+			name: "conditions",
+			template: &ast.Template{RootNodes: []ast.Node{
+				&ast.FenceSection{RawContent: "let status = \"pending\";"},
+				&ast.Element{TagName: "p", Children: []ast.Node{&ast.Conditional{
+					IfCondition:      "status === 'active'",
+					IfContent:        []ast.Node{&ast.TextNode{Content: "Active"}},
+					ElseIfConditions: []string{"status === 'pending'"},
+					ElseIfContent:    [][]ast.Node{{&ast.Element{TagName: "b", Children: []ast.Node{&ast.TextNode{Content: "Pending"}}}}},
+					ElseContent:      []ast.Node{&ast.TextNode{Content: "Inactive"}},
+				}}},
+			}},
+			expected: "<p><b>Pending</b></p>",
+		},
+		{
+			name:     "attributes",
+			source:   "---\nlet open = false;\nlet active = true;\nlet url = \"/items/1\";\n---\n<nav>\n\t<div x-show=\"open\">Menu</div>\n\t<div x-show=\"!open\" style=\"color: red\">Closed</div>\n\t<a class=\"link\" :class=\"{ active: active, hidden: open }\" :href=\"url\">Item</a>\n\t<p x-html=\"'<em>' + url + '</em>'\" x-cloak></p>\n</nav>\n",
+			expected: `<nav>    <div style="color: red">Closed</div>  <a class="link active" href="/items/1">Item</a>  <p><em>/items/1</em></p> </nav>`,
+		},
+		{
+			name: "x-data wrappers",
+			template: &ast.Template{RootNodes: []ast.Node{
+				&ast.Element{TagName: "div", Attributes: []ast.Attribute{{Name: "x-data", Value: "{ name: 'Ada' }", IsAlpine: true, AlpineType: "data"}}, Children: []ast.Node{
+					&ast.Element{TagName: "section", Attributes: []ast.Attribute{{Name: "x-data", Value: "{ greeting: 'Hello' }", IsAlpine: true, AlpineType: "data"}, {Name: "id", Value: "greeting"}}, Children: []ast.Node{
+						&ast.Element{TagName: "p", Attributes: []ast.Attribute{{Name: "x-text", Value: "greeting + ' ' + name", IsAlpine: true, AlpineType: "text"}}},
+					}},
+				}},
+			}},
+			expected: "<section id=\"greeting\"><p>Hello Ada</p></section>",
+		},
+		{
+			name:     "interactive constructs",
+			source:   "---\nlet count = 1;\n---\n<div>\n\t<button @click=\"count++\">Add</button>\n\t<input x-model=\"count\" type=\"number\">\n</div>\n",
+			expected: `<button>Add</button>  <input type="number" value="1">`,
+			warnings: []string{`@click="count++" needs Alpine.js`, `x-model="count" needs Alpine.js`},
+		},
+		{
+			name:     "expression only the browser can evaluate",
+			source:   "<div><p x-text=\"window.innerWidth\">Loading</p></div>\n",
+			expected: "<p>Loading</p>",
+			warnings: []string{"leaving it out of the static output"},
+		},
+		{
+			name:     "script",
+			source:   "<div><p>Hi</p></div>\n<script>console.log(1)</script>\n<style>p { color: red; }</style>\n",
+			expected: "<p>Hi</p>",
+			warnings: []string{"script of the template"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := tt.template
+			if template == nil {
+				var err error
+				if template, err = parser.ParseTemplate(tt.source); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			transformed, err := transformer.Transform(template, tt.props, transformer.Options{Logger: log.New(io.Discard, "", 0)})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var warnings bytes.Buffer
+			result := GenerateStatic(transformed, log.New(&warnings, "", 0))
+			if !strings.Contains(result.Markup, tt.expected) {
+				t.Errorf("Expected markup to contain %q, got:\n%s", tt.expected, result.Markup)
+			}
+			if result.Script != "" {
+				t.Errorf("Expected no script, got %q", result.Script)
+			}
+			checkStatic(t, result.Markup)
+			for _, expected := range tt.warnings {
+				if !strings.Contains(warnings.String(), expected) {
+					t.Errorf("Expected a warning containing %q, got %q", expected, warnings.String())
+				}
+			}
+			if len(tt.warnings) == 0 && warnings.Len() > 0 {
+				t.Errorf("Expected no warnings, got %q", warnings.String())
+			}
+		})
+	}
+}
+
+// checkStatic checks that markup has no templates or Alpine.js attributes left
+func checkStatic(t *testing.T, markup string) {
+	t.Helper()
+	tokenizer := html.NewTokenizer(strings.NewReader(markup))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data == "template" || token.Data == "script" {
+				t.Errorf("Static markup has a <%s>:\n%s", token.Data, markup)
+			}
+			for _, attr := range token.Attr {
+				if strings.HasPrefix(attr.Key, "x-") || strings.HasPrefix(attr.Key, "@") || strings.HasPrefix(attr.Key, ":") || strings.HasPrefix(attr.Key, "data-ssr") {
+					t.Errorf("Static markup has the attribute %s:\n%s", attr.Key, markup)
+				}
+			}
+		}
+	}
+}
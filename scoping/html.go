@@ -1,6 +1,7 @@
 package scoping
 
 import (
+	"fmt"
 	"log"
 	"strings"
 
@@ -21,33 +22,37 @@ type ScopedElement struct {
 
 // ScopeHTML adds scoped classes to a full HTML document string and returns the modified markup
 // and a list of elements that were scoped. It also handles adding x-data and x-text attributes.
-func ScopeHTML(markup string, props map[string]any) (string, []ScopedElement) {
+// Markup that can't be parsed is returned unchanged.
+func ScopeHTML(markup string, props map[string]any) (string, []ScopedElement, error) {
 	scopedElements := []ScopedElement{}
 	node, err := html.Parse(strings.NewReader(markup))
 	if err != nil {
 		// Handle parsing error more gracefully?
 		log.Printf("Warning: Failed to parse HTML for scoping: %v", err)
-		return markup, scopedElements // Return original markup on error
+		return markup, scopedElements, nil // Return original markup on error
 	}
 
-	node, scopedElements = traverse(node, scopedElements, props)
+	node, scopedElements, err = traverse(node, scopedElements, props)
+	if err != nil {
+		return markup, scopedElements, err
+	}
 
 	// Render the modified HTML back to a string
 	buf := &strings.Builder{}
 	err = html.Render(buf, node)
 	if err != nil {
-		log.Fatal(err) // Consider returning error instead of fatal
+		return markup, scopedElements, fmt.Errorf("rendering scoped HTML: %w", err)
 	}
 	// The default html.Render escapes entities like quotes in attributes.
 	// We might need selective unescaping or different rendering if it causes issues.
 	markup = buf.String() // html.UnescapeString might be too broad
 
-	return markup, scopedElements
+	return markup, scopedElements, nil
 }
 
 // ScopeHTMLComp adds scoped classes to an HTML fragment (component) string.
 // It uses html.ParseFragment to avoid adding <html><body> tags.
-func ScopeHTMLComp(comp_markup string, comp_props map[string]any, comp_data map[string]any) (string, []ScopedElement) {
+func ScopeHTMLComp(comp_markup string, comp_props map[string]any, comp_data map[string]any) (string, []ScopedElement, error) {
 	scopedElements := []ScopedElement{}
 	fragments := []string{}
 	// Use a dummy context node (like body) for ParseFragment
@@ -59,11 +64,14 @@ func ScopeHTMLComp(comp_markup string, comp_props map[string]any, comp_data map[
 	nodes, err := html.ParseFragment(strings.NewReader(comp_markup), contextNode)
 	if err != nil {
 		log.Printf("Warning: Failed to parse HTML fragment for scoping: %v", err)
-		return comp_markup, scopedElements // Return original on error
+		return comp_markup, scopedElements, nil // Return original on error
 	}
 
 	for _, node := range nodes {
-		node, scopedElements = traverse(node, scopedElements, comp_props) // Pass comp_props here
+		node, scopedElements, err = traverse(node, scopedElements, comp_props) // Pass comp_props here
+		if err != nil {
+			return comp_markup, scopedElements, err
+		}
 
 		// Add x-data for component props if needed
 		if len(comp_data) > 0 { // Use comp_data which contains expressions/literals for x-data
@@ -87,20 +95,25 @@ func ScopeHTMLComp(comp_markup string, comp_props map[string]any, comp_data map[
 		buf := &strings.Builder{}
 		err := html.Render(buf, node)
 		if err != nil {
-			log.Fatal(err) // Consider returning error
+			return comp_markup, scopedElements, fmt.Errorf("rendering scoped component HTML: %w", err)
 		}
 		fragments = append(fragments, buf.String()) // Don't unescape fragment render
 	}
 	comp_markup = strings.Join(fragments, "")
 
-	return comp_markup, scopedElements
+	return comp_markup, scopedElements, nil
 }
 
 // traverse walks the HTML node tree, applying scoping and transformations.
-func traverse(node *html.Node, scopedElements []ScopedElement, props map[string]any) (*html.Node, []ScopedElement) {
+// It stops at the first error.
+func traverse(node *html.Node, scopedElements []ScopedElement, props map[string]any) (*html.Node, []ScopedElement, error) {
 	// Use a closure for recursion to easily pass scopedElements
+	var walkErr error
 	var walk func(*html.Node)
 	walk = func(n *html.Node) {
+		if walkErr != nil {
+			return
+		}
 		if n.Type == html.ElementNode && n.Data == "html" {
 			// Add top-level x-data if props exist (only for full document scopeHTML)
 			if len(props) > 0 {
@@ -156,7 +169,8 @@ func traverse(node *html.Node, scopedElements []ScopedElement, props map[string]
 			if !hasScopeClass {
 				randomStr, err := utils.GenerateRandom() // Use utils.GenerateRandom
 				if err != nil {
-					log.Fatal(err) // Consider returning error
+					walkErr = fmt.Errorf("generating a scope class: %w", err)
+					return
 				}
 				scopedClass = "plenti-" + randomStr
 			}
@@ -231,7 +245,7 @@ func traverse(node *html.Node, scopedElements []ScopedElement, props map[string]
 	}
 
 	walk(node)
-	return node, scopedElements
+	return node, scopedElements, walkErr
 }
 
 // GetScopedClass finds the generated scope class for a given target (tag, id, class).
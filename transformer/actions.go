@@ -0,0 +1,84 @@
+package transformer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+// transformActionAttributes compiles use:action={params} directives into Alpine.js
+// x-init and x-effect attributes. The action is called with the element and its
+// params on init, its update hook runs when the params change, and its destroy
+// hook is registered with Alpine's cleanup for when the element is removed.
+func (st *state) transformActionAttributes(attributes []ast.Attribute, dataScope map[string]any) []ast.Attribute {
+	var result []ast.Attribute
+	var inits, effects []string
+
+	for _, attr := range attributes {
+		if !strings.HasPrefix(attr.Name, "use:") {
+			result = append(result, attr)
+			continue
+		}
+
+		name := strings.TrimPrefix(attr.Name, "use:")
+		if !isValidIdentifier(name) {
+			st.logger.Printf("Warning: Invalid action name '%s', skipping", name)
+			continue
+		}
+
+		params := strings.TrimSpace(attr.Value)
+		if strings.HasPrefix(params, "{") && strings.HasSuffix(params, "}") {
+			params = strings.TrimSpace(params[1 : len(params)-1])
+		}
+
+		// The params are evaluated in the component's scope
+		if params != "" {
+			extractVariablesFromExpr(params, dataScope)
+		}
+
+		action := fmt.Sprintf("$el._x_actions.%s", name)
+		call := fmt.Sprintf("%s($el)", name)
+		if params != "" {
+			call = fmt.Sprintf("%s($el, %s)", name, params)
+		}
+		inits = append(inits, fmt.Sprintf("($el._x_actions ||= {}).%s = %s || {}; "+
+			"Alpine.onElRemoved($el, () => %s.destroy && %s.destroy())", name, call, action, action))
+
+		// x-effect runs once right after x-init, so the update hook is skipped on
+		// that first run and only called when the params change afterwards
+		if params != "" {
+			ready := fmt.Sprintf("$el._x_actions['%s:ready']", name)
+			effects = append(effects, fmt.Sprintf("(params => { if (%s && %s.update) %s.update(params); %s = true })(%s)",
+				ready, action, action, ready, params))
+		}
+	}
+
+	result = appendToDirective(result, "init", inits)
+	result = appendToDirective(result, "effect", effects)
+	return result
+}
+
+// appendToDirective adds statements to an existing x-<directiveType> attribute,
+// or creates the attribute if the element does not have one yet
+func appendToDirective(attributes []ast.Attribute, directiveType string, statements []string) []ast.Attribute {
+	if len(statements) == 0 {
+		return attributes
+	}
+
+	value := strings.Join(statements, "; ")
+	for i, attr := range attributes {
+		if attr.IsAlpine && attr.AlpineType == directiveType {
+			attributes[i].Value = strings.TrimSuffix(strings.TrimSpace(attr.Value), ";") + "; " + value
+			return attributes
+		}
+	}
+
+	return append(attributes, ast.Attribute{
+		Name:       "x-" + directiveType,
+		Value:      value,
+		Dynamic:    true,
+		IsAlpine:   true,
+		AlpineType: directiveType,
+	})
+}
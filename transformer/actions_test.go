@@ -0,0 +1,87 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+func TestTransformActionAttributes(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes []ast.Attribute
+		wantInit   []string
+		wantEffect []string
+		wantScope  []string
+		notInScope []string
+	}{
+		{
+			name:       "action with params",
+			attributes: []ast.Attribute{{Name: "use:tooltip", Value: "{opts}"}},
+			wantInit: []string{
+				"($el._x_actions ||= {}).tooltip = tooltip($el, opts) || {}",
+				"Alpine.onElRemoved($el, () => $el._x_actions.tooltip.destroy && $el._x_actions.tooltip.destroy())",
+			},
+			wantEffect: []string{"$el._x_actions.tooltip.update(params)", "})(opts)"},
+			wantScope:  []string{"opts"},
+			notInScope: []string{"tooltip"},
+		},
+		{
+			name:       "action without params has no update effect",
+			attributes: []ast.Attribute{{Name: "use:autofocus"}},
+			wantInit:   []string{"($el._x_actions ||= {}).autofocus = autofocus($el) || {}"},
+			notInScope: []string{"autofocus"},
+		},
+		{
+			name: "existing x-init is kept",
+			attributes: []ast.Attribute{
+				{Name: "x-init", Value: "ready = true", IsAlpine: true, AlpineType: "init"},
+				{Name: "use:clickOutside", Value: "{close}"},
+			},
+			wantInit:   []string{"ready = true; ($el._x_actions ||= {}).clickOutside = clickOutside($el, close) || {}"},
+			wantEffect: []string{"})(close)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataScope := map[string]any{}
+			result := newState(Options{}).transformActionAttributes(tt.attributes, dataScope)
+
+			values := map[string]string{}
+			for _, attr := range result {
+				if strings.HasPrefix(attr.Name, "use:") {
+					t.Errorf("Expected %s to be compiled away", attr.Name)
+				}
+				values[attr.Name] = attr.Value
+			}
+
+			for _, s := range tt.wantInit {
+				if !strings.Contains(values["x-init"], s) {
+					t.Errorf("Expected x-init to contain %q, got %q", s, values["x-init"])
+				}
+			}
+			if len(tt.wantEffect) == 0 {
+				if _, ok := values["x-effect"]; ok {
+					t.Errorf("Expected no x-effect, got %q", values["x-effect"])
+				}
+			}
+			for _, s := range tt.wantEffect {
+				if !strings.Contains(values["x-effect"], s) {
+					t.Errorf("Expected x-effect to contain %q, got %q", s, values["x-effect"])
+				}
+			}
+			for _, key := range tt.wantScope {
+				if _, ok := dataScope[key]; !ok {
+					t.Errorf("Expected %q in data scope, got %v", key, dataScope)
+				}
+			}
+			for _, key := range tt.notInScope {
+				if _, ok := dataScope[key]; ok {
+					t.Errorf("Expected %q not to be in data scope, got %v", key, dataScope)
+				}
+			}
+		})
+	}
+}
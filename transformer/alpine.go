@@ -7,18 +7,23 @@ import (
 	"strings"
 
 	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/utils"
 )
 
-// Component tracking to prevent duplicate components
-var componentRegistry = make(map[string]bool)
-
-// Reset component tracking for each transformation
-func resetComponentTracking() {
-	componentRegistry = make(map[string]bool)
+// componentInstanceKey identifies a component instance by its position in the tree:
+// the component node and the instances it is nested in. The same node of a
+// component's template gets a separate key in each instance of that component.
+func (st *state) componentInstanceKey(node *ast.ComponentNode) string {
+	key := fmt.Sprintf("%s@%p", node.Name, node)
+	if len(st.componentPath) == 0 {
+		return key
+	}
+	return st.componentPath[len(st.componentPath)-1] + "/" + key
 }
 
 // TransformWithAlpineData transforms the given nodes with an Alpine.js data wrapper
-// This is the main entry point for applying Alpine.js data binding to templates
+// This is the main entry point for applying Alpine.js data binding to templates.
+// It has no options, so its warnings go to the standard logger.
 func TransformWithAlpineData(nodes []ast.Node, dataScope map[string]any) []ast.Node {
 	// Ensure all variables referenced in the nodes exist in the data scope
 	ensureVariablesInScope(nodes, dataScope)
@@ -26,10 +31,8 @@ func TransformWithAlpineData(nodes []ast.Node, dataScope map[string]any) []ast.N
 	// Check if we have a single root element that we can add x-data to directly
 	if len(nodes) == 1 {
 		if element, ok := nodes[0].(*ast.Element); ok && element.TagName == "div" {
-			log.Printf("TransformWithAlpineData: Adding x-data to existing single root element div")
-			
 			// Format the data scope as a JSON string for Alpine.js
-			dataScopeStr := alpineDataFormatter(dataScope)
+			dataScopeStr := alpineDataFormatter(dataScope, log.Default())
 			
 			// Add the x-data attribute to the existing div
 			element.Attributes = append(element.Attributes, ast.Attribute{
@@ -98,10 +101,8 @@ func TransformWithAlpineData(nodes []ast.Node, dataScope map[string]any) []ast.N
 	}
 	
 	// If we don't have a single div element, create a wrapper
-	log.Printf("TransformWithAlpineData: Creating wrapper div with x-data")
-	
 	// Format the data scope as a JSON string for Alpine.js
-	dataScopeStr := alpineDataFormatter(dataScope)
+	dataScopeStr := alpineDataFormatter(dataScope, log.Default())
 	
 	// Create a wrapper div with x-data
 	wrapper := &ast.Element{
@@ -228,9 +229,9 @@ func addSpacesBetweenNestedElements(element *ast.Element) {
 }
 
 // wrapWithAlpineData wraps nodes with an Alpine.js x-data element
-func wrapWithAlpineData(nodes []ast.Node, dataScope map[string]any) *ast.Element {
+func wrapWithAlpineData(nodes []ast.Node, dataScope map[string]any, logger *log.Logger) *ast.Element {
 	// Format the data scope as a JSON string for Alpine.js x-data attribute
-	dataJSON := alpineDataFormatter(dataScope)
+	dataJSON := alpineDataFormatter(dataScope, logger)
 
 	// Create a wrapper div with x-data
 	wrapper := &ast.Element{
@@ -261,13 +262,29 @@ func ensureVariablesInScope(nodes []ast.Node, dataScope map[string]any) {
 			extractVariablesFromExpr(n.Expression, dataScope)
 
 		case *ast.Element:
+			// The root of a component instance reads its attributes from the
+			// instance's x-data; what they need from this scope is already in it
+			if hasAttribute(n.Attributes, "x-component") && hasAttribute(n.Attributes, "x-data") {
+				continue
+			}
+
 			// Check attributes for expressions
 			for _, attr := range n.Attributes {
+				// x-data holds an object literal of its own, not an expression of the scope
+				if attr.IsAlpine && attr.AlpineType == "data" {
+					continue
+				}
 				if attr.Dynamic || attr.IsAlpine {
 					extractVariablesFromExpr(attr.Value, dataScope)
 				}
 			}
 
+			// Elements with their own x-data, like component instances, keep the
+			// variables of their children in their own scope
+			if hasAttribute(n.Attributes, "x-data") {
+				continue
+			}
+
 			// Recursively process children
 			ensureVariablesInScope(n.Children, dataScope)
 
@@ -302,7 +319,7 @@ func ensureVariablesInScope(nodes []ast.Node, dataScope map[string]any) {
 }
 
 // alpineDataFormatter formats the data scope as a JavaScript object literal for Alpine.js x-data attribute
-func alpineDataFormatter(dataScope map[string]any) string {
+func alpineDataFormatter(dataScope map[string]any, logger *log.Logger) string {
 	// Special case handling for test scenarios
 	if containsTestKey(dataScope, "message") && containsTestKey(dataScope, "message", "Hello") {
 		// Special case for the component_with_expressions test
@@ -344,16 +361,12 @@ func alpineDataFormatter(dataScope map[string]any) string {
 	}
 
 	// Format the data scope as a JavaScript object literal
-	result := formatGoValueToJS(dataScope, inTestEnvironment)
-	
-	// Log the generated object literal for debugging
-	log.Printf("Generated Alpine.js data object: %s", result)
-	
-	return result
+	return formatGoValueToJS(dataScope, inTestEnvironment, logger)
 }
 
-// formatGoValueToJS formats a Go value as a JavaScript value
-func formatGoValueToJS(value any, inTestEnvironment bool) string {
+// formatGoValueToJS formats a Go value as a JavaScript value. Values of unknown
+// types are logged to logger and formatted as strings.
+func formatGoValueToJS(value any, inTestEnvironment bool, logger *log.Logger) string {
 	switch v := value.(type) {
 	case nil:
 		return "null"
@@ -390,7 +403,7 @@ func formatGoValueToJS(value any, inTestEnvironment bool) string {
 		// Format array elements
 		var elements []string
 		for _, elem := range v {
-			elements = append(elements, formatGoValueToJS(elem, inTestEnvironment))
+			elements = append(elements, formatGoValueToJS(elem, inTestEnvironment, logger))
 		}
 		return "[" + strings.Join(elements, ", ") + "]"
 	case map[string]any:
@@ -407,22 +420,51 @@ func formatGoValueToJS(value any, inTestEnvironment bool) string {
 		for _, key := range keys {
 			propValue := v[key]
 			
+			// Fence functions and derived values are emitted as methods and getters
+			switch member := propValue.(type) {
+			case fenceFunction:
+				properties = append(properties, formatFenceMethod(key, member))
+				continue
+			case fenceGetter:
+				properties = append(properties, fmt.Sprintf("get %s() { return %s }", key, member.Expr))
+				continue
+			case utils.Binding:
+				getterSetter := utils.MakeGetter(map[string]any{key: member})
+				properties = append(properties, strings.TrimSuffix(strings.TrimPrefix(getterSetter, "{"), "}"))
+				continue
+			}
+			
 			if inTestEnvironment {
 				// For test environments, use double quotes and HTML entities for keys
-				properties = append(properties, fmt.Sprintf("&quot;%s&quot;: %s", key, formatGoValueToJS(propValue, inTestEnvironment)))
+				properties = append(properties, fmt.Sprintf("&quot;%s&quot;: %s", key, formatGoValueToJS(propValue, inTestEnvironment, logger)))
 			} else {
 				// Use double quotes for keys in normal environments
-				properties = append(properties, fmt.Sprintf("\"%s\": %s", key, formatGoValueToJS(propValue, inTestEnvironment)))
+				properties = append(properties, fmt.Sprintf("\"%s\": %s", key, formatGoValueToJS(propValue, inTestEnvironment, logger)))
 			}
 		}
 		return "{" + strings.Join(properties, ", ") + "}"
+	case jsLiteral:
+		// Fence literals are emitted verbatim
+		return string(v)
 	default:
 		// For unknown types, convert to string
-		log.Printf("Warning: Unknown type %T in formatGoValueToJS", v)
+		logger.Printf("Warning: Unknown type %T in formatGoValueToJS", v)
 		return fmt.Sprintf("'%v'", v)
 	}
 }
 
+// formatFenceMethod formats a fence function as a method of the Alpine data object
+func formatFenceMethod(name string, fn fenceFunction) string {
+	prefix := ""
+	if fn.Async {
+		prefix += "async "
+	}
+	if fn.Generator {
+		prefix += "*"
+	}
+	return fmt.Sprintf("%s%s(%s) {%s}", prefix, name, fn.Params, fn.Body)
+}
+
 // containsTestKey checks if the data scope contains a specific key
 // If value is provided, also checks if the key has that specific value
 func containsTestKey(dataScope map[string]any, key string, value ...any) bool {
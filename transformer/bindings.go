@@ -0,0 +1,27 @@
+package transformer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/utils"
+)
+
+// assignableExprRegex matches the expressions a prop can be bound to: a variable
+// or a property of one, like email, user.email or rows[i].name
+var assignableExprRegex = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*(\s*(\.\s*[a-zA-Z_$][a-zA-Z0-9_$]*|\[[^\[\]]+\]))*$`)
+
+// componentBinding returns the parent expression a bind:prop={expression} prop is
+// bound to, or false when the expression cannot be written to
+func (st *state) componentBinding(prop ast.ComponentProp) (utils.Binding, bool) {
+	expr := strings.TrimSpace(prop.Value)
+	if strings.HasPrefix(expr, "{") && strings.HasSuffix(expr, "}") {
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+	if !assignableExprRegex.MatchString(expr) || isJSReservedKeyword(expr) {
+		st.logger.Printf("Warning: Cannot bind prop '%s' to '%s', it is passed one way instead", prop.Name, expr)
+		return "", false
+	}
+	return utils.Binding(expr), true
+}
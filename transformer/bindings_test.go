@@ -0,0 +1,122 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/parser"
+)
+
+func TestComponentBindings(t *testing.T) {
+	RegisterComponent("TextField", &ast.Template{RootNodes: []ast.Node{
+		&ast.Element{TagName: "input", Attributes: []ast.Attribute{
+			{Name: "x-model", Value: "value", IsAlpine: true, AlpineType: "model"},
+		}},
+	}}, []string{"value"})
+
+	tests := []struct {
+		name        string
+		prop        ast.ComponentProp
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "bound variable",
+			prop: ast.ComponentProp{Name: "value", Value: "email", IsDynamic: true, IsBinding: true},
+			contains: []string{
+				`x-data="{get value() { return Alpine.$data(this.$el.parentElement).email },set value(value) { Alpine.$data(this.$el.parentElement).email = value }}"`,
+			},
+		},
+		{
+			name: "bound property of the same name",
+			prop: ast.ComponentProp{Name: "value", Value: "form.value", IsDynamic: true, IsBinding: true},
+			contains: []string{
+				"get value() { return Alpine.$data(this.$el.parentElement).form.value }",
+				"set value(value) { Alpine.$data(this.$el.parentElement).form.value = value }",
+			},
+		},
+		{
+			name:        "expressions that cannot be written are passed one way",
+			prop:        ast.ComponentProp{Name: "value", Value: "first + last", IsDynamic: true, IsBinding: true},
+			notContains: []string{"set value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &ast.Template{RootNodes: []ast.Node{
+				&ast.ComponentNode{Name: "TextField", Props: []ast.ComponentProp{tt.prop}},
+			}}
+			result := TransformAST(template, map[string]any{"email": "", "form": map[string]any{"value": ""}})
+
+			var sb strings.Builder
+			for _, node := range result.RootNodes {
+				renderTestNode(&sb, node)
+			}
+			output := sb.String()
+
+			if strings.Count(output, "x-data=") < 2 && len(tt.contains) > 0 {
+				t.Errorf("Expected the parent scope to wrap the component.\nOutput: %s", output)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(output, s) {
+					t.Errorf("Expected output to contain %q, but it doesn't.\nOutput: %s", s, output)
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(output, s) {
+					t.Errorf("Expected output not to contain %q, but it does.\nOutput: %s", s, output)
+				}
+			}
+		})
+	}
+}
+
+func TestParsedComponentBindings(t *testing.T) {
+	RegisterComponent("TextField", &ast.Template{RootNodes: []ast.Node{
+		&ast.Element{TagName: "input", Attributes: []ast.Attribute{
+			{Name: "x-model", Value: "value", IsAlpine: true, AlpineType: "model"},
+		}},
+	}}, []string{"value"})
+
+	tests := []struct {
+		name     string
+		source   string
+		contains string
+	}{
+		{
+			name:     "bound variable",
+			source:   "<form>\n\t<TextField bind:value={email} />\n</form>",
+			contains: "get value() { return Alpine.$data(this.$el.parentElement).email },set value(value) { Alpine.$data(this.$el.parentElement).email = value }",
+		},
+		{
+			name:     "bound property",
+			source:   "<form>\n\t<TextField bind:value={user.email} />\n</form>",
+			contains: "set value(value) { Alpine.$data(this.$el.parentElement).user.email = value }",
+		},
+		{
+			name:     "shorthand",
+			source:   "<form>\n\t<TextField bind:value />\n</form>",
+			contains: "set value(value) { Alpine.$data(this.$el.parentElement).value = value }",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := parser.ParseTemplate(tt.source)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result := TransformAST(template, map[string]any{"email": "", "value": "", "user": map[string]any{"email": ""}})
+
+			var sb strings.Builder
+			for _, node := range result.RootNodes {
+				renderTestNode(&sb, node)
+			}
+			if output := sb.String(); !strings.Contains(output, tt.contains) {
+				t.Errorf("Expected output to contain %q, but it doesn't.\nOutput: %s", tt.contains, output)
+			}
+		})
+	}
+}
@@ -2,8 +2,9 @@ package transformer
 
 import (
 	"fmt"
-	"log"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/jimafisk/custom_go_template/ast"
 )
@@ -11,38 +12,85 @@ import (
 // ComponentTemplate represents a registered component template
 type ComponentTemplate struct {
 	Name     string
+	Path     string // Path of the component's module, empty for registered components
 	Template *ast.Template
 	Props    []string // List of prop names this component accepts
 }
 
-// componentTemplateRegistry stores registered component templates
-var componentTemplateRegistry = make(map[string]*ComponentTemplate)
+// id identifies the component: by its module when it has one, by its name otherwise
+func (c *ComponentTemplate) id() string {
+	if c.Path != "" {
+		return c.Path
+	}
+	return c.Name
+}
 
-// Track rendered component instances to prevent duplication
-// This is now managed by the componentRegistry in alpine.go
+// Registry holds component templates by name. It is safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	components map[string]*ComponentTemplate
+}
 
-// resetComponentTemplateRegistry resets the component template registry
-// Note: This doesn't clear registered templates, only the instance tracking
-func resetComponentTemplateRegistry() {
-	// In a more complex implementation, we might want to clear
-	// certain aspects of the registry but keep the templates
-	log.Printf("Component template registry reset")
+// NewRegistry creates an empty component registry
+func NewRegistry() *Registry {
+	return &Registry{components: make(map[string]*ComponentTemplate)}
 }
 
-// RegisterComponent registers a component template for later use
-func RegisterComponent(name string, template *ast.Template, props []string) {
-	componentTemplateRegistry[name] = &ComponentTemplate{
+// Register adds a component template under a name, replacing any template
+// registered under it before
+func (r *Registry) Register(name string, template *ast.Template, props []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.components[name] = &ComponentTemplate{
 		Name:     name,
 		Template: template,
 		Props:    props,
 	}
-	log.Printf("Registered component template: %s with %d props", name, len(props))
+}
+
+// Get retrieves a component template by name
+func (r *Registry) Get(name string) (*ComponentTemplate, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	template, exists := r.components[name]
+	return template, exists
+}
+
+// Unregister removes the component template registered under a name
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.components, name)
+}
+
+// all returns the registered component templates, sorted by name
+func (r *Registry) all() []*ComponentTemplate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.components))
+	for name := range r.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	templates := make([]*ComponentTemplate, len(names))
+	for i, name := range names {
+		templates[i] = r.components[name]
+	}
+	return templates
+}
+
+// defaultRegistry holds the components registered with RegisterComponent, which
+// transformations use unless their options name another registry
+var defaultRegistry = NewRegistry()
+
+// RegisterComponent registers a component template for later use
+func RegisterComponent(name string, template *ast.Template, props []string) {
+	defaultRegistry.Register(name, template, props)
 }
 
 // GetComponentTemplate retrieves a component template by name
 func GetComponentTemplate(name string) (*ComponentTemplate, bool) {
-	template, exists := componentTemplateRegistry[name]
-	return template, exists
+	return defaultRegistry.Get(name)
 }
 
 // formatComponentData formats the component data scope for the x-data attribute
@@ -157,32 +205,49 @@ func isValidVariableName(s string) bool {
 }
 
 // transformComponent transforms a component node into an Alpine.js compatible structure
-func transformComponent(node *ast.ComponentNode, dataScope map[string]any) []ast.Node {
-	// Create a unique key for this component based on name and props
-	componentKey := node.Name
-	for _, prop := range node.Props {
-		// Use the cleaned prop value for the key to avoid duplicates with different brace formats
-		cleanedPropValue := strings.TrimSpace(strings.TrimPrefix(strings.TrimSuffix(prop.Value, "}"), "{"))
-		componentKey += ":" + prop.Name + "=" + cleanedPropValue
-	}
+func (st *state) transformComponent(node *ast.ComponentNode, dataScope map[string]any) []ast.Node {
+	// Identify this component instance by its position in the tree
+	componentKey := st.componentInstanceKey(node)
 	
-	// Check if we've rendered this exact component before in the current transformation
-	if isDuplicate := componentRegistry[componentKey]; isDuplicate {
-		log.Printf("Warning: Duplicate component detected: %s", componentKey)
+	// Check if we've rendered this exact instance before in the current transformation
+	if isDuplicate := st.componentRegistry[componentKey]; isDuplicate {
+		st.logger.Printf("Warning: Duplicate component detected: %s", componentKey)
 		// Return empty node to avoid duplication
 		return []ast.Node{}
 	}
 	
 	// Mark this component as rendered
-	componentRegistry[componentKey] = true
+	st.componentRegistry[componentKey] = true
+	
+	if len(st.componentStack) >= maxComponentDepth {
+		st.logger.Printf("Warning: Component %s is nested more than %d levels deep, skipping it", node.Name, maxComponentDepth)
+		return []ast.Node{}
+	}
 	
 	// Create a child scope for this component
 	// Note: We're creating an empty scope rather than inheriting from parent
-	// This ensures only explicitly passed props are included
+	// This ensures only explicitly passed props are included, and the scope
+	// becomes the component instance's own x-data
 	componentScope := make(map[string]any)
 	
 	// Add props to the component scope
+	var listeners []ast.Attribute
 	for _, prop := range node.Props {
+		// Event listeners belong to the wrapper, not to the component's data
+		if prop.IsEvent {
+			listeners = append(listeners, componentEventListener(prop, dataScope))
+			continue
+		}
+		
+		// Bound props read and write the parent's data
+		if prop.IsBinding {
+			if binding, ok := st.componentBinding(prop); ok {
+				extractVariablesFromExpr(string(binding), dataScope)
+				componentScope[prop.Name] = binding
+				continue
+			}
+		}
+		
 		propName := prop.Name
 		propValue := prop.Value
 		
@@ -197,13 +262,13 @@ func transformComponent(node *ast.ComponentNode, dataScope map[string]any) []ast
 			// but don't add them to the component scope
 			extractVariablesFromExpr(cleanedExpr, dataScope)
 			
-			// For dynamic props, we set the value to the expression itself
-			// This will be evaluated in the Alpine.js context
-			componentScope[propName] = cleanedExpr
+			// Dynamic props are getters evaluated in the parent's scope, so
+			// they stay live when the parent's data changes
+			componentScope[propName] = fenceGetter{Expr: cleanedExpr}
 		} else if prop.IsShorthand {
-			// For shorthand props like {propName}, use the prop name as the value
-			// This is a reference to a variable in the parent scope
-			componentScope[propName] = propName
+			// For shorthand props like {propName}, the getter reads the
+			// variable of the same name in the parent scope
+			componentScope[propName] = fenceGetter{Expr: propName}
 			
 			// Also add to parent scope
 			extractVariablesFromExpr(propName, dataScope)
@@ -215,19 +280,53 @@ func transformComponent(node *ast.ComponentNode, dataScope map[string]any) []ast
 	
 	// Try to find the component template
 	var componentChildren []ast.Node
+	var effects []string
+	var recursion []ast.Attribute
 	
 	// Check if this is a registered component
-	if componentTemplate, exists := GetComponentTemplate(node.Name); exists {
-		log.Printf("Found registered component template: %s", node.Name)
-		
-		// Transform the component template with the component scope
-		// We need to avoid calling TransformAST directly to prevent circular dependency
-		// Instead, transform the nodes directly
+	if componentTemplate, exists := st.lookupComponent(node.Name); exists {
+		// The component's fence state is private to this instance, and the
+		// contexts it provides are visible to its descendants only
+		st.pushContextFrame()
 		childNodes := componentTemplate.Template.RootNodes
-		transformedNodes := transformNodes(childNodes, componentScope, false)
-		componentChildren = transformedNodes
+		if fence := FindFenceSection(childNodes); fence != nil {
+			st.moduleStack = append(st.moduleStack, componentTemplate.Path)
+			effects = st.collectFenceData(fence, componentScope, Options{})
+			st.moduleStack = st.moduleStack[:len(st.moduleStack)-1]
+		}
+		
+		if _, _, recursive := st.componentCycle(componentTemplate.id()); recursive {
+			// A component nested in itself is cloned from its shared template
+			// instead of being inlined again
+			reference, ok := st.recursiveComponentReference(node, componentTemplate)
+			st.popContextFrame()
+			if !ok {
+				return []ast.Node{}
+			}
+			recursion = append(recursion, reference)
+			componentChildren = st.recursiveInstance(componentTemplate, componentScope)
+		} else {
+			// Transform the component template with the component scope
+			// We need to avoid calling TransformAST directly to prevent circular dependency
+			// Instead, transform the nodes directly
+			st.componentPath = append(st.componentPath, componentKey)
+			st.componentStack = append(st.componentStack, componentFrame{Name: node.Name, ID: componentTemplate.id(), Guards: st.componentGuards})
+			st.moduleStack = append(st.moduleStack, componentTemplate.Path)
+			transformedNodes := st.transformNodes(childNodes, componentScope, false)
+			st.moduleStack = st.moduleStack[:len(st.moduleStack)-1]
+			st.componentStack = st.componentStack[:len(st.componentStack)-1]
+			st.componentPath = st.componentPath[:len(st.componentPath)-1]
+			st.popContextFrame()
+			componentChildren = transformedNodes
+		}
 	} else {
-		log.Printf("Component template not found: %s, using placeholder", node.Name)
+		st.logger.Printf("Component template not found: %s, using placeholder", node.Name)
+		
+		// Paths built from expressions are resolved by the client
+		if !strings.ContainsAny(node.Name, "{}`") {
+			file, importChain := st.location()
+			st.fail(&ast.ComponentNotFoundError{Name: node.Name, File: file, Pos: node.Pos, ImportChain: importChain})
+		}
 		
 		// Create a placeholder for unknown components
 		placeholder := &ast.Element{
@@ -249,39 +348,20 @@ func transformComponent(node *ast.ComponentNode, dataScope map[string]any) []ast
 		componentChildren = []ast.Node{placeholder}
 	}
 	
-	// Create the component wrapper element
-	element := &ast.Element{
-		TagName: "div",
-		Attributes: []ast.Attribute{
-			{
-				Name:  "x-component",
-				Value: node.Name,
-			},
-		},
-		Children:    componentChildren,
-		SelfClosing: false,
-	}
-	
-	// Add props as data attributes for debugging and reference
-	for propName, propValue := range componentScope {
-		// Skip internal Alpine.js variables
-		if strings.HasPrefix(propName, "$") {
-			continue
-		}
-		
-		// Add the prop as a data attribute
-		attrName := fmt.Sprintf("data-prop-%s", propName)
-		attrValue := fmt.Sprintf("%v", propValue)
-		
-		element.Attributes = append(element.Attributes, ast.Attribute{
-			Name:  attrName,
-			Value: attrValue,
-		})
+	// The attributes identifying the instance and holding its x-data
+	scope := []ast.Attribute{{Name: "x-component", Value: node.Name}}
+	if len(componentScope) > 0 {
+		scope = append([]ast.Attribute{{
+			Name:       "x-data",
+			Value:      formatGoValueToJS(componentScope, false, st.logger),
+			Dynamic:    true,
+			IsAlpine:   true,
+			AlpineType: "data",
+		}}, scope...)
 	}
+	scope = appendToDirective(scope, "effect", effects)
+	scope = append(scope, listeners...)
+	scope = append(scope, recursion...)
 	
-	// Merge any new variables from the component scope back to the parent scope
-	// This allows child components to affect parent state if needed
-	MergeScopes(dataScope, componentScope)
-	
-	return []ast.Node{element}
+	return st.placeComponent(node.Name, scope, componentChildren)
 }
\ No newline at end of file
@@ -0,0 +1,290 @@
+package transformer
+
+import (
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/parser"
+)
+
+func TestComponentInstanceScopes(t *testing.T) {
+	RegisterComponent("Counter", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "prop label;\nprop step = 1;\nlet count = 0;\nfunction increment() { count += step }"},
+		&ast.Element{TagName: "button", Attributes: []ast.Attribute{
+			{Name: "@click", Value: "increment()", IsAlpine: true, AlpineType: "on", AlpineKey: "click"},
+		}, Children: []ast.Node{
+			&ast.ExpressionNode{Expression: "label"},
+			&ast.ExpressionNode{Expression: "count"},
+		}},
+	}}, []string{"label", "step"})
+
+	template := &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "let title = 'Counters';\nlet size = 2;"},
+		&ast.ExpressionNode{Expression: "title"},
+		&ast.ComponentNode{Name: "Counter", Props: []ast.ComponentProp{
+			{Name: "label", Value: "First"},
+		}},
+		&ast.ComponentNode{Name: "Counter", Props: []ast.ComponentProp{
+			{Name: "label", Value: "Second"},
+			{Name: "step", Value: "size", IsDynamic: true},
+		}},
+	}}
+	result := TransformAST(template, map[string]any{})
+
+	root, ok := result.RootNodes[0].(*ast.Element)
+	if !ok {
+		t.Fatalf("Expected a root element, got %T", result.RootNodes[0])
+	}
+	var pageData string
+	for _, attr := range root.Attributes {
+		if attr.Name == "x-data" {
+			pageData = attr.Value
+		}
+	}
+
+	// The component state stays out of the page's x-data
+	for _, key := range []string{"count", "increment", "label", "step"} {
+		if strings.Contains(pageData, key) {
+			t.Errorf("Expected page x-data not to contain %q, got %s", key, pageData)
+		}
+	}
+	for _, key := range []string{"title", "size"} {
+		if !strings.Contains(pageData, key) {
+			t.Errorf("Expected page x-data to contain %q, got %s", key, pageData)
+		}
+	}
+
+	var instances []string
+	for _, child := range root.Children {
+		element, ok := child.(*ast.Element)
+		if !ok || !hasAttribute(element.Attributes, "x-component") {
+			continue
+		}
+		for _, attr := range element.Attributes {
+			if strings.HasPrefix(attr.Name, "data-prop-") {
+				t.Errorf("Expected no data-prop attributes, got %s", attr.Name)
+			}
+			if attr.Name == "x-data" {
+				instances = append(instances, attr.Value)
+			}
+		}
+	}
+	if len(instances) != 2 {
+		t.Fatalf("Expected 2 component instances with x-data, got %d", len(instances))
+	}
+
+	wants := [][]string{
+		{`"count": 0`, `"label": 'First'`, `"step": 1`, "increment() { this.count += this.step }"},
+		{`"count": 0`, `"label": 'Second'`, "get step() { return size }", "increment() { this.count += this.step }"},
+	}
+	for i, want := range wants {
+		for _, s := range want {
+			if !strings.Contains(instances[i], s) {
+				t.Errorf("Expected instance %d x-data to contain %q, got %s", i+1, s, instances[i])
+			}
+		}
+	}
+}
+
+func TestComponentsInLoops(t *testing.T) {
+	RegisterComponent("ProductCard", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "prop product;\nprop position = 0;\nlet expanded = false;"},
+		&ast.Element{TagName: "article", Children: []ast.Node{
+			&ast.ExpressionNode{Expression: "product.name"},
+		}},
+	}}, []string{"product", "position"})
+	RegisterComponent("Notification", &ast.Template{RootNodes: []ast.Node{
+		&ast.Element{TagName: "p", Children: []ast.Node{&ast.TextNode{Content: "Saved"}}},
+	}}, nil)
+	RegisterComponent("Badge", &ast.Template{RootNodes: []ast.Node{
+		&ast.Element{TagName: "span", Children: []ast.Node{&ast.TextNode{Content: "New"}}},
+	}}, nil)
+	RegisterComponent("Tile", &ast.Template{RootNodes: []ast.Node{
+		&ast.Element{TagName: "div", Children: []ast.Node{&ast.ComponentNode{Name: "Badge"}}},
+	}}, nil)
+
+	tests := []struct {
+		name      string
+		nodes     []ast.Node
+		dataScope map[string]any
+		contains  []string
+		instances map[string]int
+	}{
+		{
+			name: "prop bound to the loop variable",
+			nodes: []ast.Node{&ast.Element{TagName: "ul", Children: []ast.Node{
+				&ast.Loop{Iterator: "p", Collection: "products", Content: []ast.Node{
+					&ast.ComponentNode{Name: "ProductCard", Props: []ast.ComponentProp{
+						{Name: "product", Value: "p", IsDynamic: true},
+					}},
+				}},
+			}}},
+			dataScope: map[string]any{"products": []any{}},
+			contains: []string{
+				`<template x-for="p in products"><article x-data="{"expanded": false, "position": 0, get product() { return p }}" x-component="ProductCard">`,
+				`<span x-text="product.name"></span></article>`,
+			},
+			instances: map[string]int{"ProductCard": 1},
+		},
+		{
+			name: "props bound to the index and the value",
+			nodes: []ast.Node{&ast.Element{TagName: "ul", Children: []ast.Node{
+				&ast.Loop{Iterator: "i", Value: "p", Collection: "products", Content: []ast.Node{
+					&ast.ComponentNode{Name: "ProductCard", Props: []ast.ComponentProp{
+						{Name: "product", Value: "p", IsDynamic: true},
+						{Name: "position", Value: "i + 1", IsDynamic: true},
+					}},
+				}},
+			}}},
+			dataScope: map[string]any{"products": []any{}},
+			contains: []string{
+				`<template x-for="(i, p) in products">`,
+				"get position() { return i + 1 }, get product() { return p }",
+			},
+			instances: map[string]int{"ProductCard": 1},
+		},
+		{
+			name: "shorthand prop of the loop variable",
+			nodes: []ast.Node{&ast.Element{TagName: "ul", Children: []ast.Node{
+				&ast.Loop{Iterator: "product", Collection: "products", Content: []ast.Node{
+					&ast.ComponentNode{Name: "ProductCard", Props: []ast.ComponentProp{
+						{Name: "product", Value: "product", IsShorthand: true, IsDynamic: true},
+					}},
+				}},
+			}}},
+			dataScope: map[string]any{"products": []any{}},
+			contains:  []string{"get product() { return product }"},
+			instances: map[string]int{"ProductCard": 1},
+		},
+		{
+			name: "components with identical props in a loop",
+			nodes: []ast.Node{&ast.Element{TagName: "div", Children: []ast.Node{
+				&ast.Loop{Iterator: "p", Collection: "products", Content: []ast.Node{
+					&ast.ComponentNode{Name: "ProductCard", Props: []ast.ComponentProp{{Name: "product", Value: "p", IsDynamic: true}}},
+					&ast.ComponentNode{Name: "ProductCard", Props: []ast.ComponentProp{{Name: "product", Value: "p", IsDynamic: true}}},
+				}},
+			}}},
+			dataScope: map[string]any{"products": []any{}},
+			instances: map[string]int{"ProductCard": 2},
+		},
+		{
+			name: "component in a nested loop",
+			nodes: []ast.Node{&ast.Element{TagName: "section", Children: []ast.Node{
+				&ast.Loop{Iterator: "group", Collection: "groups", Content: []ast.Node{
+					&ast.Element{TagName: "ul", Children: []ast.Node{
+						&ast.Loop{Iterator: "p", Collection: "group.products", Content: []ast.Node{
+							&ast.ComponentNode{Name: "ProductCard", Props: []ast.ComponentProp{{Name: "product", Value: "p", IsDynamic: true}}},
+						}},
+					}},
+				}},
+			}}},
+			dataScope: map[string]any{"groups": []any{}},
+			contains: []string{
+				`<template x-for="group in groups">`,
+				`<template x-for="p in group.products"><article x-data=`,
+			},
+			instances: map[string]int{"ProductCard": 1},
+		},
+		{
+			name: "identical components without props",
+			nodes: []ast.Node{
+				&ast.ComponentNode{Name: "Notification"},
+				&ast.ComponentNode{Name: "Notification"},
+			},
+			instances: map[string]int{"Notification": 2},
+		},
+		{
+			name: "nested component in every instance",
+			nodes: []ast.Node{&ast.Element{TagName: "ul", Children: []ast.Node{
+				&ast.Loop{Iterator: "p", Collection: "products", Content: []ast.Node{
+					&ast.ComponentNode{Name: "Tile"},
+					&ast.ComponentNode{Name: "Tile"},
+				}},
+			}}},
+			dataScope: map[string]any{"products": []any{}},
+			instances: map[string]int{"Tile": 2, "Badge": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := TransformAST(&ast.Template{RootNodes: tt.nodes}, tt.dataScope)
+
+			var sb strings.Builder
+			for _, node := range result.RootNodes {
+				renderTestNode(&sb, node)
+			}
+			output := sb.String()
+
+			for _, s := range tt.contains {
+				if !strings.Contains(output, s) {
+					t.Errorf("Expected output to contain %q, but it doesn't.\nOutput: %s", s, output)
+				}
+			}
+			for name, want := range tt.instances {
+				if got := strings.Count(output, `x-component="`+name+`"`); got != want {
+					t.Errorf("Expected %d %s instances, got %d.\nOutput: %s", want, name, got, output)
+				}
+			}
+		})
+	}
+}
+
+func TestParsedComponentProps(t *testing.T) {
+	card, err := parser.ParseTemplate("---\nprop product;\nprop position = 0;\n---\n<article>{position}. {product.name}</article>\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterComponent("ProductCard", card, []string{"product", "position"})
+	defer defaultRegistry.Unregister("ProductCard")
+
+	// The loop of the documentation, with props holding spaces
+	page, err := parser.ParseTemplate("---\nlet products = [];\n---\n<ul>\n\t{for i, p in products}\n\t\t<ProductCard product={p} position={i + 1} on:select={() => pick(p, { at: i })} {products} />\n\t{end}\n</ul>\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var component *ast.ComponentNode
+	var find func(nodes []ast.Node)
+	find = func(nodes []ast.Node) {
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case *ast.ComponentNode:
+				component = n
+			case *ast.Element:
+				find(n.Children)
+			case *ast.Loop:
+				find(n.Content)
+			}
+		}
+	}
+	find(page.RootNodes)
+	if component == nil {
+		t.Fatal("Expected the page to contain the component")
+	}
+	want := []ast.ComponentProp{
+		{Name: "product", Value: "p", IsDynamic: true},
+		{Name: "position", Value: "i + 1", IsDynamic: true},
+		{Name: "select", Value: "() => pick(p, { at: i })", IsDynamic: true, IsEvent: true},
+		{Name: "products", Value: "products", IsDynamic: true, IsShorthand: true},
+	}
+	if !reflect.DeepEqual(component.Props, want) {
+		t.Errorf("Props = %+v, want %+v", component.Props, want)
+	}
+
+	result, err := Transform(page, map[string]any{}, Options{Logger: log.New(io.Discard, "", 0)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var sb strings.Builder
+	for _, node := range result.RootNodes {
+		renderTestNode(&sb, node)
+	}
+	if expected := "get position() { return i + 1 }, get product() { return p }"; !strings.Contains(sb.String(), expected) {
+		t.Errorf("Expected output to contain %q, got:\n%s", expected, sb.String())
+	}
+}
@@ -1,16 +1,14 @@
 package transformer
 
 import (
-	"log"
-
 	"github.com/jimafisk/custom_go_template/ast"
 )
 
 // transformConditional transforms a Conditional node into an Alpine.js compatible structure
-func transformConditional(node *ast.Conditional, dataScope map[string]any) []ast.Node {
+func (st *state) transformConditional(node *ast.Conditional, dataScope map[string]any) []ast.Node {
 	// Special case for isAdmin conditions
 	if node.IfCondition == "isAdmin" {
-		return handleAdminConditional(node, dataScope)
+		return st.handleAdminConditional(node, dataScope)
 	}
 
 	// Extract variables from the condition
@@ -26,12 +24,12 @@ func transformConditional(node *ast.Conditional, dataScope map[string]any) []ast
 	elseScope := CreateChildScope(dataScope)
 
 	// Transform the content for each branch
-	ifContent := transformNodes(node.IfContent, ifScope, false)
+	ifContent := st.transformNodes(node.IfContent, ifScope, false)
 	elseIfContents := make([][]ast.Node, len(node.ElseIfConditions))
 	for i := range node.ElseIfConditions {
-		elseIfContents[i] = transformNodes(node.ElseIfContent[i], elseIfScopes[i], false)
+		elseIfContents[i] = st.transformNodes(node.ElseIfContent[i], elseIfScopes[i], false)
 	}
-	elseContent := transformNodes(node.ElseContent, elseScope, false)
+	elseContent := st.transformNodes(node.ElseContent, elseScope, false)
 
 	// Create the if template
 	ifTemplate := &ast.Element{
@@ -106,14 +104,14 @@ func transformConditional(node *ast.Conditional, dataScope map[string]any) []ast
 	MergeScopes(dataScope, elseScope)
 
 	// Log the transformation for debugging
-	log.Printf("Transformed conditional with condition: %s", node.IfCondition)
+	st.logger.Printf("Transformed conditional with condition: %s", node.IfCondition)
 
 	return result
 }
 
 // handleAdminConditional handles the special case for isAdmin conditions
 // This is used to separate AdminPanel and UserProfile components
-func handleAdminConditional(node *ast.Conditional, dataScope map[string]any) []ast.Node {
+func (st *state) handleAdminConditional(node *ast.Conditional, dataScope map[string]any) []ast.Node {
 	// Extract variables from the condition
 	extractVariablesFromExpr(node.IfCondition, dataScope)
 
@@ -122,11 +120,11 @@ func handleAdminConditional(node *ast.Conditional, dataScope map[string]any) []a
 	userScope := CreateChildScope(dataScope)
 
 	// Transform the content for each branch
-	adminContent := transformNodes(node.IfContent, adminScope, false)
+	adminContent := st.transformNodes(node.IfContent, adminScope, false)
 	userContent := []ast.Node{}
 	
 	if len(node.ElseContent) > 0 {
-		userContent = transformNodes(node.ElseContent, userScope, false)
+		userContent = st.transformNodes(node.ElseContent, userScope, false)
 	}
 
 	// Create the admin template
@@ -175,7 +173,7 @@ func handleAdminConditional(node *ast.Conditional, dataScope map[string]any) []a
 	MergeScopes(dataScope, userScope)
 
 	// Log the special case transformation for debugging
-	log.Printf("Transformed admin conditional with condition: %s", node.IfCondition)
+	st.logger.Printf("Transformed admin conditional with condition: %s", node.IfCondition)
 
 	return result
 }
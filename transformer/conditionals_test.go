@@ -137,7 +137,7 @@ func TestTransformConditional(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Transform the conditional
-			result := transformConditional(tt.condition, tt.dataScope)
+			result := newState(Options{}).transformConditional(tt.condition, tt.dataScope)
 			
 			// Convert to string for easier testing
 			var sb strings.Builder
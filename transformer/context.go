@@ -0,0 +1,115 @@
+package transformer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// getContextRegex matches a getContext('key') call used as a declaration initializer
+var getContextRegex = regexp.MustCompile(`^getContext\(\s*(?:'([^'\\]*)'|"([^"\\]*)")\s*\)$`)
+
+// pushContextFrame starts the contexts of a component instance; contexts it
+// provides are visible to its own fence and to its descendants until popped
+func (st *state) pushContextFrame() {
+	st.contextFrames = append(st.contextFrames, make(map[string]any))
+}
+
+// popContextFrame ends the contexts of the innermost component instance
+func (st *state) popContextFrame() {
+	if len(st.contextFrames) > 0 {
+		st.contextFrames = st.contextFrames[:len(st.contextFrames)-1]
+	}
+}
+
+// contextDataKey is the x-data key a reactive context is published under, so
+// descendants can read it through Alpine's nested x-data scope chain
+func contextDataKey(key string) string {
+	return "$context_" + escapeIdentifier(key)
+}
+
+// escapeIdentifier encodes s as the characters of a JavaScript identifier, with
+// a distinct result for each string: letters and digits are kept, an underscore
+// is doubled and any other character is written as its code point in hex between
+// underscores, so "user-settings" is user_2d_settings and "user_settings" is
+// user__settings
+func escapeIdentifier(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case r == '_':
+			sb.WriteString("__")
+		default:
+			sb.WriteString("_" + strconv.FormatInt(int64(r), 16) + "_")
+		}
+	}
+	return sb.String()
+}
+
+// provideContext handles a setContext(key, value) statement of a fence. A value that
+// doesn't reference any fence variable is static and is inlined into the readers at
+// compile time. Other values are reactive: they are published as a getter in the
+// provider's x-data, which the readers' getters resolve through the scope chain.
+func (st *state) provideContext(stmt fenceStatement, dataScope map[string]any, names map[string]bool) {
+	if len(st.contextFrames) == 0 {
+		st.pushContextFrame()
+	}
+	frame := st.contextFrames[len(st.contextFrames)-1]
+
+	refs := st.fenceReferences("("+stmt.Body+")", names)
+	if len(refs) == 0 {
+		frame[stmt.ContextKey] = jsLiteral(stmt.Body)
+		return
+	}
+
+	dataKey := contextDataKey(stmt.ContextKey)
+	dataScope[dataKey] = fenceGetter{Expr: rewriteFenceReferences(stmt.Body, refs, true)}
+	frame[stmt.ContextKey] = fenceGetter{Expr: dataKey}
+}
+
+// readContext resolves a getContext(key) call against the contexts provided by the
+// ancestors, the innermost first
+func (st *state) readContext(key string) any {
+	for i := len(st.contextFrames) - 1; i >= 0; i-- {
+		if value, ok := st.contextFrames[i][key]; ok {
+			return value
+		}
+	}
+
+	st.logger.Printf("Warning: Context '%s' is read but never provided by an ancestor", key)
+	return nil
+}
+
+// contextKeyOf returns the key of a getContext('key') initializer
+func contextKeyOf(init string) (string, bool) {
+	match := getContextRegex.FindStringSubmatch(init)
+	if match == nil {
+		return "", false
+	}
+	if match[1] != "" {
+		return match[1], true
+	}
+	return match[2], true
+}
+
+// parseSetContext reads the key and value of a setContext('key', value) call
+func (st *state) parseSetContext(src string, stmt *fenceStatement, args []fenceToken) {
+	parts := splitTopLevel(args, js.CommaToken)
+	if len(parts) != 2 || len(parts[0]) != 1 || parts[0][0].tt != js.StringToken || len(parts[1]) == 0 {
+		st.logger.Printf("Warning: setContext expects a string key and a value, skipping: %s", tokenText(src, args))
+		return
+	}
+
+	key, err := strconv.Unquote(parts[0][0].text)
+	if err != nil {
+		// Single quoted keys
+		key = parts[0][0].text[1 : len(parts[0][0].text)-1]
+	}
+	stmt.Kind = "context"
+	stmt.ContextKey = key
+	stmt.Body = tokenText(src, parts[1])
+}
@@ -0,0 +1,114 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+func TestContext(t *testing.T) {
+	RegisterComponent("UserProfile", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "const user = getContext('user');\nconst theme = getContext('theme');\nconst locale = getContext('locale');"},
+		&ast.Element{TagName: "p", Children: []ast.Node{&ast.ExpressionNode{Expression: "user.name"}}},
+	}}, nil)
+	RegisterComponent("UserDashboard", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "setContext('theme', 'light');"},
+		&ast.Element{TagName: "section", Children: []ast.Node{&ast.ComponentNode{Name: "UserProfile"}}},
+	}}, nil)
+
+	template := &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "let user = { name: 'Ada' };\nsetContext('user', user);\nsetContext('theme', 'dark');"},
+		&ast.ExpressionNode{Expression: "user.name"},
+		&ast.ComponentNode{Name: "UserDashboard"},
+		&ast.ComponentNode{Name: "UserProfile"},
+	}}
+	result := TransformAST(template, map[string]any{})
+
+	var data []string
+	var collect func(nodes []ast.Node)
+	collect = func(nodes []ast.Node) {
+		for _, node := range nodes {
+			if element, ok := node.(*ast.Element); ok {
+				for _, attr := range element.Attributes {
+					if attr.Name == "x-data" {
+						data = append(data, attr.Value)
+					}
+				}
+				collect(element.Children)
+			}
+		}
+	}
+	collect(result.RootNodes)
+	// UserDashboard only provides a static context, so it has no x-data of its own
+	if len(data) != 3 {
+		t.Fatalf("Expected x-data on the page and 2 component instances, got %d: %v", len(data), data)
+	}
+
+	tests := []struct {
+		name        string
+		data        string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "page publishes reactive contexts",
+			data:        data[0],
+			contains:    []string{"get $context_user() { return this.user }"},
+			notContains: []string{"$context_theme"},
+		},
+		{
+			name:     "nearest provider wins for static values",
+			data:     data[1],
+			contains: []string{"get user() { return $context_user }", `"theme": 'light'`, `"locale": null`},
+		},
+		{
+			name:     "page contexts reach direct children",
+			data:     data[2],
+			contains: []string{"get user() { return $context_user }", `"theme": 'dark'`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, s := range tt.contains {
+				if !strings.Contains(tt.data, s) {
+					t.Errorf("Expected x-data to contain %q, got %s", s, tt.data)
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(tt.data, s) {
+					t.Errorf("Expected x-data not to contain %q, got %s", s, tt.data)
+				}
+			}
+		})
+	}
+}
+
+func TestContextDataKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "user", want: "$context_user"},
+		{key: "user_settings", want: "$context_user__settings"},
+		{key: "user-settings", want: "$context_user_2d_settings"},
+		{key: "user.settings", want: "$context_user_2e_settings"},
+		{key: "user_2d_settings", want: "$context_user__2d__settings"},
+		{key: "thème", want: "$context_th_e8_me"},
+	}
+
+	seen := make(map[string]string)
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got := contextDataKey(tt.key)
+			if got != tt.want {
+				t.Errorf("contextDataKey(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+			if other, ok := seen[got]; ok {
+				t.Errorf("contextDataKey(%q) = %q, same as for %q", tt.key, got, other)
+			}
+			seen[got] = tt.key
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package transformer
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/modules"
+)
+
+func TestTransformErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		check func(t *testing.T, err error, root string)
+	}{
+		{
+			name: "no error",
+			files: map[string]string{
+				"index.html": "---\nimport Card from \"./Card.html\";\nlet a = 1;\nlet b = a + 1;\n---\n<main>\n\t<Card />\n</main>\n",
+				"Card.html":  "<p>Card</p>",
+			},
+			check: func(t *testing.T, err error, root string) {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "component not found",
+			files: map[string]string{
+				"index.html": "<main>\n\t<Missing />\n</main>\n",
+			},
+			check: func(t *testing.T, err error, root string) {
+				var notFound *ast.ComponentNotFoundError
+				if !errors.As(err, &notFound) {
+					t.Fatalf("Expected a ComponentNotFoundError, got %v", err)
+				}
+				if notFound.Name != "Missing" || notFound.File != root+"/index.html" || notFound.Pos.Line != 2 || notFound.Pos.Column != 2 {
+					t.Errorf("Expected Missing at index.html:2:2, got %v", err)
+				}
+			},
+		},
+		{
+			name: "component path built from an expression",
+			files: map[string]string{
+				"index.html": "---\nlet name = \"Card\";\n---\n<main>\n\t<={`./${name}.html`} />\n</main>\n",
+			},
+			check: func(t *testing.T, err error, root string) {
+				if err != nil {
+					t.Errorf("Expected paths resolved on the client not to fail, got %v", err)
+				}
+			},
+		},
+		{
+			name: "component cycle without a condition or loop",
+			files: map[string]string{
+				"index.html": "---\nimport Menu from \"./Menu.html\";\n---\n<main>\n\t<Menu />\n</main>\n",
+				"Menu.html":  "---\nimport Item from \"./Item.html\";\n---\n<nav>\n\t<Item />\n</nav>\n",
+				"Item.html":  "---\nimport Menu from \"./Menu.html\";\n---\n<p>\n\t<Menu />\n</p>\n",
+			},
+			check: func(t *testing.T, err error, root string) {
+				var cycle *ast.ComponentCycleError
+				if !errors.As(err, &cycle) {
+					t.Fatalf("Expected a ComponentCycleError, got %v", err)
+				}
+				if strings.Join(cycle.Cycle, " -> ") != "Menu -> Item -> Menu" {
+					t.Errorf("Expected the cycle Menu -> Item -> Menu, got %v", cycle.Cycle)
+				}
+				if cycle.File != root+"/Item.html" || cycle.Pos.Line != 5 || cycle.Pos.Column != 2 {
+					t.Errorf("Expected the error at Item.html:5:2, got %v", err)
+				}
+				if len(cycle.ImportChain) != 2 || cycle.ImportChain[0] != root+"/index.html" || cycle.ImportChain[1] != root+"/Menu.html" {
+					t.Errorf("Expected Item.html to be imported by index.html -> Menu.html, got %v", cycle.ImportChain)
+				}
+			},
+		},
+		{
+			name: "fence failing in a component",
+			files: map[string]string{
+				"index.html": "---\nimport Card from \"./Card.html\";\n---\n<main>\n\t<Card />\n</main>\n",
+				"Card.html":  "---\nlet a = 1;\nlet b = window.innerWidth + a;\n---\n<p>{b}</p>\n",
+			},
+			check: func(t *testing.T, err error, root string) {
+				var evalErr *ast.EvalError
+				if !errors.As(err, &evalErr) {
+					t.Fatalf("Expected an EvalError, got %v", err)
+				}
+				if evalErr.File != root+"/Card.html" || evalErr.Pos.Line != 3 || evalErr.Pos.Column != 1 {
+					t.Errorf("Expected the error at Card.html:3:1, got %v", err)
+				}
+				if len(evalErr.ImportChain) != 1 || evalErr.ImportChain[0] != root+"/index.html" {
+					t.Errorf("Expected Card.html to be imported by index.html, got %v", evalErr.ImportChain)
+				}
+				if !strings.Contains(err.Error(), "window is not defined") {
+					t.Errorf("Expected the error to include the cause, got %v", err)
+				}
+			},
+		},
+		{
+			name: "fence that doesn't parse",
+			files: map[string]string{
+				"index.html": "---\nlet a = 1;\n\tfunction double() { return a +* 2 }\nlet b = a + 1;\n---\n<p>{b}</p>\n",
+			},
+			check: func(t *testing.T, err error, root string) {
+				var evalErr *ast.EvalError
+				if !errors.As(err, &evalErr) {
+					t.Fatalf("Expected an EvalError, got %v", err)
+				}
+				if evalErr.File != root+"/index.html" || evalErr.Pos.Line != 3 || evalErr.Pos.Column != 2 || len(evalErr.ImportChain) != 0 {
+					t.Errorf("Expected the error at index.html:3:2, got %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := filepath.ToSlash(t.TempDir())
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			graph := modules.NewGraph(nil)
+			module, err := graph.Load(root + "/index.html")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			options := Options{Modules: graph, Path: module.Path, Logger: log.New(io.Discard, "", 0)}
+			result, err := Transform(module.Template, map[string]any{}, options)
+			if result == nil {
+				t.Fatalf("Expected a template even when the transformation fails")
+			}
+			tt.check(t, err, root)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package transformer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// componentGuard limits a listener on a component wrapper to the events dispatched
+// by that component instance, not those bubbling up from components nested inside it
+const componentGuard = "$event.target.closest('[x-component]') === $el"
+
+// compileDispatch replaces calls to the dispatch(name, detail) helper with the
+// given Alpine.js $dispatch magic, e.g. "$dispatch" in template expressions or
+// "this.$dispatch" in methods of the data object
+func compileDispatch(src string, magic string) string {
+	if !strings.Contains(src, "dispatch") {
+		return src
+	}
+
+	tokens, err := tokenizeJS(src)
+	if err != nil {
+		// Keep the source as written when it can't be tokenized
+		return src
+	}
+	var sb strings.Builder
+	prev := js.ErrorToken
+	for i, tok := range tokens {
+		if tok.tt == js.IdentifierToken && tok.text == "dispatch" &&
+			prev != js.DotToken && prev != js.OptChainToken && prev != js.FunctionToken {
+			if next, ok := nextSignificant(tokens, i+1); ok && next.tt == js.OpenParenToken {
+				sb.WriteString(magic)
+				prev = tok.tt
+				continue
+			}
+		}
+
+		sb.WriteString(tok.text)
+		if isSignificant(tok.tt) {
+			prev = tok.tt
+		}
+	}
+	return sb.String()
+}
+
+// transformDispatchCalls compiles dispatch calls in the event handlers of an element
+func transformDispatchCalls(attributes []ast.Attribute) []ast.Attribute {
+	for i, attr := range attributes {
+		if attr.IsAlpine && attr.AlpineType == "on" {
+			attributes[i].Value = compileDispatch(attr.Value, "$dispatch")
+		}
+	}
+	return attributes
+}
+
+// componentEventListener turns an on:event={handler} prop of a component tag into
+// an Alpine.js listener for the event on the component's wrapper element. The
+// wrapper holds the component's own x-data, so the handler is evaluated from the
+// wrapper's parent with Alpine.evaluate: it runs in the parent's scope, whatever
+// the component defines, and receives the event, with the dispatched detail in
+// event.detail.
+func componentEventListener(prop ast.ComponentProp, dataScope map[string]any) ast.Attribute {
+	handler := strings.TrimSpace(prop.Value)
+	if strings.HasPrefix(handler, "{") && strings.HasSuffix(handler, "}") {
+		handler = strings.TrimSpace(handler[1 : len(handler)-1])
+	}
+	if !strings.Contains(handler, "=>") {
+		extractVariablesFromExpr(handler, dataScope)
+	}
+
+	return ast.Attribute{
+		Name:       "@" + prop.Name,
+		Value:      fmt.Sprintf("%s && Alpine.evaluate($el.parentElement, %s, { scope: { $event: $event }, params: [$event] })", componentGuard, jsSingleQuoted(compileDispatch(handler, "$dispatch"))),
+		Dynamic:    true,
+		IsAlpine:   true,
+		AlpineType: "on",
+		AlpineKey:  prop.Name,
+		Modifiers:  prop.Modifiers,
+	}
+}
+
+// jsSingleQuoted returns a JavaScript string literal in single quotes
+func jsSingleQuoted(s string) string {
+	return "'" + strings.NewReplacer(
+		`\`, `\\`,
+		`'`, `\'`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\u2028", `\u2028`,
+		"\u2029", `\u2029`,
+	).Replace(s) + "'"
+}
@@ -0,0 +1,181 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+func TestCompileDispatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		magic string
+		want  string
+	}{
+		{
+			name:  "handler expression",
+			src:   "() => dispatch('select', item)",
+			magic: "$dispatch",
+			want:  "() => $dispatch('select', item)",
+		},
+		{
+			name:  "method body",
+			src:   " dispatch(\"select\", { id: this.id }) ",
+			magic: "this.$dispatch",
+			want:  " this.$dispatch(\"select\", { id: this.id }) ",
+		},
+		{
+			name:  "properties and strings are left alone",
+			src:   "bus.dispatch('x'); log('dispatch(')",
+			magic: "$dispatch",
+			want:  "bus.dispatch('x'); log('dispatch(')",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compileDispatch(tt.src, tt.magic); got != tt.want {
+				t.Errorf("compileDispatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComponentEventListeners(t *testing.T) {
+	RegisterComponent("ProductCard", &ast.Template{RootNodes: []ast.Node{
+		&ast.Element{TagName: "button", Attributes: []ast.Attribute{
+			{Name: "@click", Value: "dispatch('select', product)", IsAlpine: true, AlpineType: "on", AlpineKey: "click"},
+		}},
+	}}, []string{"product"})
+
+	template := &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "let picked;\nfunction handleSelect(e) { picked = e.detail }"},
+		&ast.ComponentNode{Name: "ProductCard", Props: []ast.ComponentProp{
+			{Name: "product", Value: "item", IsDynamic: true},
+			{Name: "select", Value: "handleSelect", IsDynamic: true, IsEvent: true, Modifiers: []ast.Modifier{{Name: "once"}}},
+		}},
+	}}
+	result := TransformAST(template, map[string]any{})
+
+	var sb strings.Builder
+	for _, node := range result.RootNodes {
+		renderTestNode(&sb, node)
+	}
+	output := sb.String()
+
+	var wrapper *ast.Element
+	var findWrapper func(nodes []ast.Node)
+	findWrapper = func(nodes []ast.Node) {
+		for _, node := range nodes {
+			if element, ok := node.(*ast.Element); ok {
+				if hasAttribute(element.Attributes, "x-component") {
+					wrapper = element
+					return
+				}
+				findWrapper(element.Children)
+			}
+		}
+	}
+	findWrapper(result.RootNodes)
+	if wrapper == nil {
+		t.Fatalf("Expected an x-component wrapper.\nOutput: %s", output)
+	}
+
+	var listener *ast.Attribute
+	for i, attr := range wrapper.Attributes {
+		if attr.Name == "@select" {
+			listener = &wrapper.Attributes[i]
+		}
+		if strings.Contains(attr.Name, "select") && attr.Name != "@select" {
+			t.Errorf("Expected the event prop not to be passed as %s", attr.Name)
+		}
+	}
+	if listener == nil {
+		t.Fatalf("Expected an @select listener on the wrapper.\nOutput: %s", output)
+	}
+	if want := "$event.target.closest('[x-component]') === $el && Alpine.evaluate($el.parentElement, 'handleSelect', { scope: { $event: $event }, params: [$event] })"; listener.Value != want {
+		t.Errorf("Expected listener %q, got %q", want, listener.Value)
+	}
+	if len(listener.Modifiers) != 1 || listener.Modifiers[0].Name != "once" {
+		t.Errorf("Expected the once modifier, got %v", listener.Modifiers)
+	}
+
+	for _, s := range []string{`$dispatch('select', product)`} {
+		if !strings.Contains(output, s) {
+			t.Errorf("Expected output to contain %q, but it doesn't.\nOutput: %s", s, output)
+		}
+	}
+	if strings.Contains(output, `"$dispatch"`) || strings.Contains(output, `"$el"`) {
+		t.Errorf("Expected Alpine magics not to be added to x-data.\nOutput: %s", output)
+	}
+}
+
+func TestComponentEventHandlerScope(t *testing.T) {
+	// The component defines a member named like the parent's handler
+	RegisterComponent("ShadowCard", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "prop product;\nfunction handleSelect() { return 'child' }"},
+		&ast.Element{TagName: "button", Attributes: []ast.Attribute{
+			{Name: "@click", Value: "dispatch('select', product)", IsAlpine: true, AlpineType: "on", AlpineKey: "click"},
+		}},
+	}}, []string{"product"})
+
+	template := &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "let picked;\nfunction handleSelect(e) { picked = e.detail }"},
+		&ast.ComponentNode{Name: "ShadowCard", Props: []ast.ComponentProp{
+			{Name: "product", Value: "item", IsDynamic: true},
+			{Name: "select", Value: "handleSelect", IsDynamic: true, IsEvent: true},
+		}},
+	}}
+	result := TransformAST(template, map[string]any{})
+
+	var listener string
+	var findListener func(nodes []ast.Node)
+	findListener = func(nodes []ast.Node) {
+		for _, node := range nodes {
+			if element, ok := node.(*ast.Element); ok {
+				for _, attr := range element.Attributes {
+					if attr.Name == "@select" {
+						listener = attr.Value
+					}
+				}
+				findListener(element.Children)
+			}
+		}
+	}
+	findListener(result.RootNodes)
+	if listener == "" {
+		t.Fatal("Expected an @select listener on the wrapper")
+	}
+
+	// Run the listener like Alpine.js would, in the component's scope, with an
+	// Alpine.evaluate resolving expressions in the scope of the given element
+	vm := goja.New()
+	_, err := vm.RunString(`
+		var parent = { picked: null, handleSelect: function(e) { this.picked = e.detail } };
+		var child = { product: 'book', handleSelect: function() { throw new Error('child handler called') } };
+		var $el = { parentElement: { scope: parent } };
+		var $event = { detail: 'book', target: { closest: function() { return $el } } };
+		var Alpine = {
+			evaluate: function(el, expression, options) {
+				with (el.scope) {
+					with (options.scope) {
+						var result = eval(expression);
+					}
+				}
+				return typeof result === 'function' ? result.apply(el.scope, options.params) : result;
+			}
+		};
+		with (child) {
+			eval(` + "`" + listener + "`" + `);
+		}
+	`)
+	if err != nil {
+		t.Fatalf("Listener %q failed: %v", listener, err)
+	}
+	if picked := vm.Get("parent").ToObject(vm).Get("picked").Export(); picked != "book" {
+		t.Errorf("Expected the parent's handler to set picked to book, got %v", picked)
+	}
+}
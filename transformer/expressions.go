@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/tdewolff/parse/v2/js"
 )
 
 // transformTextWithExpressions transforms text containing expressions like {name} or {{ name }}
@@ -238,7 +239,7 @@ func extractVariablesFromExpr(expr string, dataScope map[string]any) {
 			funcName := strings.TrimSpace(expr[:funcNameEnd])
 
 			// Add the function name to the data scope if it's a valid identifier
-			if isValidIdentifier(funcName) {
+			if isValidIdentifier(funcName) && !isAlpineMagic(funcName) {
 				if _, exists := dataScope[funcName]; !exists {
 					// Add function with a default implementation
 					dataScope[funcName] = fmt.Sprintf("function() { return null; }")
@@ -246,7 +247,7 @@ func extractVariablesFromExpr(expr string, dataScope map[string]any) {
 			} else if strings.Contains(funcName, ".") {
 				// Handle object method calls like obj.method()
 				parts := strings.Split(funcName, ".")
-				if len(parts) > 0 && isValidIdentifier(parts[0]) {
+				if len(parts) > 0 && isValidIdentifier(parts[0]) && !isAlpineMagic(parts[0]) {
 					rootVar := parts[0]
 					if _, exists := dataScope[rootVar]; !exists {
 						dataScope[rootVar] = getDefaultValueForVar(rootVar)
@@ -315,7 +316,7 @@ func extractVariablesFromExpr(expr string, dataScope map[string]any) {
 		}
 
 		// Add the root variable to the data scope
-		if rootVar != "" && isValidIdentifier(rootVar) {
+		if rootVar != "" && isValidIdentifier(rootVar) && !isAlpineMagic(rootVar) {
 			if _, exists := dataScope[rootVar]; !exists {
 				dataScope[rootVar] = getDefaultValueForVar(rootVar)
 			}
@@ -324,7 +325,7 @@ func extractVariablesFromExpr(expr string, dataScope map[string]any) {
 	}
 
 	// For simple variable names, add them to the data scope
-	if isValidIdentifier(expr) {
+	if isValidIdentifier(expr) && !isAlpineMagic(expr) {
 		if _, exists := dataScope[expr]; !exists {
 			dataScope[expr] = getDefaultValueForVar(expr)
 		}
@@ -602,6 +603,12 @@ func isValidIdentifier(s string) bool {
 	return regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*$`).MatchString(s)
 }
 
+// isAlpineMagic checks if a name is an Alpine.js magic property like $event or
+// $dispatch, which Alpine provides and must not be shadowed by the data scope
+func isAlpineMagic(s string) bool {
+	return strings.HasPrefix(s, "$")
+}
+
 // isStringLiteral checks if a string is enclosed in quotes
 func isStringLiteral(s string) bool {
 	return (strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'")) ||
@@ -629,12 +636,55 @@ func isJSReservedKeyword(s string) bool {
 	return keywords[s]
 }
 
-func transformExpression(expr string) string {
-	// Handle nested object access
-	parts := strings.Split(expr, ".")
-	if len(parts) > 1 {
-		// Verify parent objects exist
-		return fmt.Sprintf("(%s || {}).%s", parts[0], strings.Join(parts[1:], "."))
+// transformExpression rewrites the references to global stores in a client
+// expression, so {$cart.count} reads Alpine's $store.cart.count
+func (st *state) transformExpression(expr string) string {
+	if len(st.storeNames) == 0 || !strings.Contains(expr, "$") {
+		return expr
+	}
+
+	var sb strings.Builder
+	var objectBraces []bool
+	prev := js.ErrorToken
+	consumed := 0
+	tokens, _ := tokenizeJS(expr)
+	for i, tok := range tokens {
+		consumed += len(tok.text)
+
+		switch tok.tt {
+		case js.OpenBraceToken:
+			objectBraces = append(objectBraces, opensObjectLiteral(prev, true))
+		case js.CloseBraceToken:
+			if len(objectBraces) > 0 {
+				objectBraces = objectBraces[:len(objectBraces)-1]
+			}
+		}
+
+		// Properties named like a store, as in user.$cart or { $cart: 1 }, are
+		// left alone
+		if js.IsIdentifier(tok.tt) && strings.HasPrefix(tok.text, "$") && st.storeNames[tok.text[1:]] &&
+			prev != js.DotToken && prev != js.OptChainToken {
+			next, _ := nextSignificant(tokens, i+1)
+			inObject := len(objectBraces) > 0 && objectBraces[len(objectBraces)-1]
+			switch {
+			case !inObject || (prev != js.OpenBraceToken && prev != js.CommaToken):
+				sb.WriteString("$store." + tok.text[1:])
+			case next.tt == js.CommaToken || next.tt == js.CloseBraceToken:
+				sb.WriteString(tok.text + ": $store." + tok.text[1:])
+			default:
+				sb.WriteString(tok.text)
+			}
+		} else {
+			sb.WriteString(tok.text)
+		}
+		if isSignificant(tok.tt) {
+			prev = tok.tt
+		}
+	}
+
+	// Keep the expression as written when it couldn't be tokenized completely
+	if consumed < len(expr) {
+		return expr
 	}
-	return expr
+	return sb.String()
 }
@@ -0,0 +1,710 @@
+package transformer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/dop251/goja"
+	gojaparser "github.com/dop251/goja/parser"
+	"github.com/jimafisk/custom_go_template/utils"
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// fenceToken is a single lexer token from fence source, with its byte offset
+type fenceToken struct {
+	tt     js.TokenType
+	text   string
+	offset int
+}
+
+// fenceStatement is a top-level statement of a fence section
+type fenceStatement struct {
+	Text       string         // verbatim source, with prop declarations normalized to let
+	Offset     int            // byte offset of the statement in the fence
+	Kind       string         // "declaration", "function", "reactive", "lifecycle", "context", "store", "import" or "statement"
+	Body       string         // reactive statement after the $: label, the value of a setContext call or of a store
+	ContextKey string         // key of a setContext call
+	Store      string         // name of a global store
+	Persist    bool           // whether a store is persisted to localStorage
+	Hook       string         // onMount or onDestroy for a lifecycle statement
+	Callback   *fenceFunction // callback passed to a lifecycle hook
+	Decls      []fenceDecl    // declarations introduced by the statement
+	sig        []fenceToken   // significant tokens of the statement
+}
+
+// fenceDecl is a top-level declaration of a fence section
+type fenceDecl struct {
+	Name     string
+	Kind     string         // "prop", "let", "const", "var", "function" or "reactive"
+	Init     string         // verbatim initializer, empty when there is none
+	Function *fenceFunction // set when the declaration holds a function
+}
+
+// fenceFunction is a fence function serialized as a method of the Alpine data object
+type fenceFunction struct {
+	Params    string // verbatim parameter list, without the parentheses
+	Body      string // verbatim statements, without the outer braces
+	Async     bool
+	Generator bool
+}
+
+// fenceGetter is a derived fence declaration serialized as a getter of the Alpine data object
+type fenceGetter struct {
+	Expr string
+}
+
+// jsLiteral is JavaScript source emitted verbatim into the Alpine data object
+type jsLiteral string
+
+// propKeywordRegex matches prop declarations; "prop" is replaced by "let " so the
+// fence is valid JavaScript while every offset stays the same
+var propKeywordRegex = regexp.MustCompile(`(^|[;{}\n])(\s*)prop(\s+)([a-zA-Z_$][a-zA-Z0-9_$]*)`)
+
+// normalizeFence turns prop declarations into let declarations and returns the
+// names of the declared props
+func normalizeFence(fence string) (string, map[string]bool) {
+	props := make(map[string]bool)
+	for _, match := range propKeywordRegex.FindAllStringSubmatch(fence, -1) {
+		props[match[4]] = true
+	}
+	return propKeywordRegex.ReplaceAllString(fence, "${1}${2}let ${3}${4}"), props
+}
+
+// tokenizeJS splits JavaScript source into lexer tokens, keeping whitespace and
+// comments. Source that can't be tokenized returns the tokens before the error.
+func tokenizeJS(src string) ([]fenceToken, error) {
+	lexer := js.NewLexer(parse.NewInputString(src))
+	var tokens []fenceToken
+	offset := 0
+	prev := js.ErrorToken
+
+	for {
+		tt, data := lexer.Next()
+		if tt == js.ErrorToken {
+			if lexer.Err() != io.EOF {
+				return tokens, lexer.Err()
+			}
+			break
+		}
+
+		// A slash starts a regular expression wherever an operand is expected
+		if (tt == js.DivToken || tt == js.DivEqToken) && expectsOperand(prev) {
+			tt, data = lexer.RegExp()
+			if tt == js.ErrorToken {
+				return tokens, lexer.Err()
+			}
+		}
+
+		tokens = append(tokens, fenceToken{tt: tt, text: string(data), offset: offset})
+		offset += len(data)
+		if isSignificant(tt) {
+			prev = tt
+		}
+	}
+
+	return tokens, nil
+}
+
+// isSignificant reports whether a token is neither whitespace nor a comment
+func isSignificant(tt js.TokenType) bool {
+	switch tt {
+	case js.WhitespaceToken, js.LineTerminatorToken, js.CommentToken, js.CommentLineTerminatorToken:
+		return false
+	}
+	return true
+}
+
+// expectsOperand reports whether an operand (rather than an operator) may follow a token
+func expectsOperand(prev js.TokenType) bool {
+	switch prev {
+	case js.ErrorToken:
+		return true
+	case js.CloseParenToken, js.CloseBracketToken, js.CloseBraceToken, js.IncrToken, js.DecrToken,
+		js.ThisToken, js.SuperToken, js.TrueToken, js.FalseToken, js.NullToken:
+		return false
+	}
+	return js.IsPunctuator(prev) || js.IsReservedWord(prev)
+}
+
+// endsExpression reports whether a token can be the last token of a statement
+func endsExpression(tt js.TokenType) bool {
+	switch tt {
+	case js.CloseParenToken, js.CloseBracketToken, js.CloseBraceToken, js.IncrToken, js.DecrToken,
+		js.StringToken, js.TemplateToken, js.TemplateEndToken, js.RegExpToken,
+		js.ThisToken, js.SuperToken, js.TrueToken, js.FalseToken, js.NullToken:
+		return true
+	}
+	return js.IsNumeric(tt) || js.IsIdentifier(tt)
+}
+
+// continuesExpression reports whether a token on a new line continues the previous statement
+func continuesExpression(tt js.TokenType) bool {
+	switch tt {
+	case js.NotToken, js.BitNotToken, js.IncrToken, js.DecrToken:
+		return false
+	case js.DotToken, js.OptChainToken, js.OpenParenToken, js.OpenBracketToken, js.CommaToken,
+		js.QuestionToken, js.ColonToken, js.ArrowToken, js.TemplateToken, js.TemplateStartToken,
+		js.InToken, js.InstanceofToken, js.ElseToken, js.CatchToken, js.FinallyToken:
+		return true
+	}
+	return js.IsOperator(tt)
+}
+
+// splitFenceStatements splits fence source into its top-level statements
+func (st *state) splitFenceStatements(src string) []fenceStatement {
+	tokens, err := tokenizeJS(src)
+	if err != nil {
+		st.logger.Printf("Warning: Failed to tokenize fence script: %v", err)
+	}
+	var statements []fenceStatement
+	var current []fenceToken
+	depth := 0
+
+	flush := func() {
+		if len(current) > 0 {
+			first, last := current[0], current[len(current)-1]
+			statements = append(statements, fenceStatement{
+				Text:   src[first.offset : last.offset+len(last.text)],
+				Offset: first.offset,
+				sig:    current,
+			})
+		}
+		current = nil
+	}
+
+	for i, tok := range tokens {
+		if !isSignificant(tok.tt) {
+			// Automatic semicolon insertion: a line break at the top level ends the
+			// statement unless the next token continues it
+			if depth == 0 && len(current) > 0 && strings.ContainsAny(tok.text, "\n\r") &&
+				endsExpression(current[len(current)-1].tt) && !endsControlHeader(current) {
+				if next, ok := nextSignificant(tokens, i+1); !ok || !continuesExpression(next.tt) {
+					flush()
+				}
+			}
+			continue
+		}
+
+		switch tok.tt {
+		case js.OpenParenToken, js.OpenBracketToken, js.OpenBraceToken:
+			depth++
+		case js.CloseParenToken, js.CloseBracketToken, js.CloseBraceToken:
+			depth--
+		}
+
+		if tok.tt == js.SemicolonToken && depth == 0 {
+			flush()
+			continue
+		}
+		current = append(current, tok)
+	}
+	flush()
+
+	for i := range statements {
+		st.classifyFenceStatement(&statements[i], src)
+	}
+	return statements
+}
+
+// endsControlHeader reports whether the tokens end with the condition of an
+// if, for or while statement, whose body may follow on the next line
+func endsControlHeader(tokens []fenceToken) bool {
+	last := len(tokens) - 1
+	if tokens[last].tt != js.CloseParenToken {
+		return false
+	}
+	depth := 0
+	for i := last; i >= 0; i-- {
+		switch tokens[i].tt {
+		case js.CloseParenToken:
+			depth++
+		case js.OpenParenToken:
+			depth--
+			if depth == 0 {
+				if i == 0 {
+					return false
+				}
+				switch tokens[i-1].tt {
+				case js.IfToken, js.ForToken, js.WhileToken:
+					return true
+				}
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// nextSignificant returns the first significant token at or after index i
+func nextSignificant(tokens []fenceToken, i int) (fenceToken, bool) {
+	for ; i < len(tokens); i++ {
+		if isSignificant(tokens[i].tt) {
+			return tokens[i], true
+		}
+	}
+	return fenceToken{}, false
+}
+
+// classifyFenceStatement determines the kind of a statement and the declarations it introduces
+func (st *state) classifyFenceStatement(stmt *fenceStatement, src string) {
+	sig := stmt.sig
+	stmt.Kind = "statement"
+
+	switch {
+	case sig[0].tt == js.ImportToken:
+		stmt.Kind = "import"
+	case len(sig) > 2 && sig[0].text == "$" && sig[1].tt == js.ColonToken:
+		// Reactive statements: $: name = expr declares a derived value, anything
+		// else is a side effect that re-runs when its dependencies change
+		stmt.Kind = "reactive"
+		body := sig[2:]
+		stmt.Body = tokenText(src, body)
+		if len(body) > 2 && js.IsIdentifier(body[0].tt) && body[1].tt == js.EqToken {
+			stmt.Decls = []fenceDecl{{Name: body[0].text, Kind: "reactive", Init: tokenText(src, body[2:])}}
+		}
+	case len(sig) > 3 && lifecycleHooks[sig[0].text] && sig[1].tt == js.OpenParenToken &&
+		matchingClose(sig, 1) == len(sig)-1:
+		// onMount(callback) and onDestroy(callback) run on the client only
+		stmt.Kind = "lifecycle"
+		stmt.Hook = sig[0].text
+		args := sig[2 : len(sig)-1]
+		if stmt.Callback = parseFunctionValue(src, args); stmt.Callback == nil && len(args) == 1 && js.IsIdentifier(args[0].tt) {
+			// A named function is called like the other fence methods
+			stmt.Callback = &fenceFunction{Body: "return " + args[0].text + "()"}
+		}
+	case len(sig) > 3 && sig[0].text == "setContext" && sig[1].tt == js.OpenParenToken &&
+		matchingClose(sig, 1) == len(sig)-1:
+		st.parseSetContext(src, stmt, sig[2:len(sig)-1])
+	case len(sig) > 3 && sig[0].text == "store" && js.IsIdentifier(sig[1].tt) && sig[2].tt == js.EqToken,
+		len(sig) > 4 && sig[0].text == "persist" && sig[1].text == "store" && js.IsIdentifier(sig[2].tt) && sig[3].tt == js.EqToken:
+		parseStore(src, stmt, sig)
+	case sig[0].tt == js.FunctionToken || (sig[0].tt == js.AsyncToken && len(sig) > 1 && sig[1].tt == js.FunctionToken):
+		fn, name := parseFunctionTokens(src, sig)
+		if fn != nil && name != "" {
+			stmt.Kind = "function"
+			stmt.Decls = []fenceDecl{{Name: name, Kind: "function", Function: fn}}
+		}
+	case sig[0].tt == js.LetToken || sig[0].tt == js.ConstToken || sig[0].tt == js.VarToken:
+		stmt.Kind = "declaration"
+		for _, declarator := range splitTopLevel(sig[1:], js.CommaToken) {
+			if decl, ok := st.parseDeclarator(src, sig[0], declarator); ok {
+				stmt.Decls = append(stmt.Decls, decl)
+			}
+		}
+	}
+}
+
+// parseDeclarator reads a single "name = init" declarator
+func (st *state) parseDeclarator(src string, keyword fenceToken, tokens []fenceToken) (fenceDecl, bool) {
+	if len(tokens) == 0 {
+		return fenceDecl{}, false
+	}
+	if tokens[0].tt != js.IdentifierToken && !js.IsIdentifier(tokens[0].tt) {
+		st.logger.Printf("Warning: Destructuring declarations in the fence are not supported: %s", tokenText(src, tokens))
+		return fenceDecl{}, false
+	}
+
+	decl := fenceDecl{Name: tokens[0].text, Kind: keyword.text}
+	if len(tokens) > 2 && tokens[1].tt == js.EqToken {
+		initTokens := tokens[2:]
+		decl.Init = tokenText(src, initTokens)
+		decl.Function = parseFunctionValue(src, initTokens)
+	}
+	return decl, true
+}
+
+// parseFunctionValue recognizes function expressions and arrow functions used as initializers
+func parseFunctionValue(src string, tokens []fenceToken) *fenceFunction {
+	if tokens[0].tt == js.FunctionToken || (tokens[0].tt == js.AsyncToken && len(tokens) > 1 && tokens[1].tt == js.FunctionToken) {
+		fn, _ := parseFunctionTokens(src, tokens)
+		return fn
+	}
+
+	arrow := -1
+	depth := 0
+	for i, tok := range tokens {
+		switch tok.tt {
+		case js.OpenParenToken, js.OpenBracketToken, js.OpenBraceToken:
+			depth++
+		case js.CloseParenToken, js.CloseBracketToken, js.CloseBraceToken:
+			depth--
+		case js.ArrowToken:
+			if depth == 0 && arrow < 0 {
+				arrow = i
+			}
+		}
+	}
+	if arrow < 1 || arrow == len(tokens)-1 {
+		return nil
+	}
+
+	fn := &fenceFunction{}
+	params := tokens[:arrow]
+	if params[0].tt == js.AsyncToken && len(params) > 1 {
+		fn.Async = true
+		params = params[1:]
+	}
+	switch {
+	case len(params) == 1 && js.IsIdentifier(params[0].tt):
+		fn.Params = params[0].text
+	case params[0].tt == js.OpenParenToken && params[len(params)-1].tt == js.CloseParenToken:
+		fn.Params = innerText(src, params[0], params[len(params)-1])
+	default:
+		return nil
+	}
+
+	body := tokens[arrow+1:]
+	if body[0].tt == js.OpenBraceToken && matchingClose(body, 0) == len(body)-1 {
+		fn.Body = bodyText(src, body[0], body[len(body)-1])
+	} else {
+		fn.Body = "return " + tokenText(src, body)
+	}
+	return fn
+}
+
+// parseFunctionTokens reads "[async] function [*] [name](params) { body }"
+func parseFunctionTokens(src string, tokens []fenceToken) (*fenceFunction, string) {
+	fn := &fenceFunction{}
+	i := 0
+	if tokens[i].tt == js.AsyncToken {
+		fn.Async = true
+		i++
+	}
+	i++ // function keyword
+	if i < len(tokens) && tokens[i].tt == js.MulToken {
+		fn.Generator = true
+		i++
+	}
+
+	name := ""
+	if i < len(tokens) && js.IsIdentifier(tokens[i].tt) {
+		name = tokens[i].text
+		i++
+	}
+	if i >= len(tokens) || tokens[i].tt != js.OpenParenToken {
+		return nil, ""
+	}
+
+	closeParen := matchingClose(tokens, i)
+	if closeParen < 0 || closeParen+1 >= len(tokens) || tokens[closeParen+1].tt != js.OpenBraceToken {
+		return nil, ""
+	}
+	closeBrace := matchingClose(tokens, closeParen+1)
+	if closeBrace < 0 {
+		return nil, ""
+	}
+
+	fn.Params = innerText(src, tokens[i], tokens[closeParen])
+	fn.Body = bodyText(src, tokens[closeParen+1], tokens[closeBrace])
+	return fn, name
+}
+
+// matchingClose returns the index of the bracket closing the one at index open
+func matchingClose(tokens []fenceToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch tokens[i].tt {
+		case js.OpenParenToken, js.OpenBracketToken, js.OpenBraceToken:
+			depth++
+		case js.CloseParenToken, js.CloseBracketToken, js.CloseBraceToken:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits significant tokens on a separator that is not nested in brackets
+func splitTopLevel(tokens []fenceToken, separator js.TokenType) [][]fenceToken {
+	var parts [][]fenceToken
+	var current []fenceToken
+	depth := 0
+	for _, tok := range tokens {
+		switch tok.tt {
+		case js.OpenParenToken, js.OpenBracketToken, js.OpenBraceToken:
+			depth++
+		case js.CloseParenToken, js.CloseBracketToken, js.CloseBraceToken:
+			depth--
+		}
+		if tok.tt == separator && depth == 0 {
+			parts = append(parts, current)
+			current = nil
+			continue
+		}
+		current = append(current, tok)
+	}
+	return append(parts, current)
+}
+
+// tokenText returns the verbatim source spanned by the tokens
+func tokenText(src string, tokens []fenceToken) string {
+	first, last := tokens[0], tokens[len(tokens)-1]
+	return src[first.offset : last.offset+len(last.text)]
+}
+
+// innerText returns the verbatim source between two bracket tokens
+func innerText(src string, open, close fenceToken) string {
+	return strings.TrimSpace(src[open.offset+len(open.text) : close.offset])
+}
+
+// bodyText returns the verbatim source of a block between its braces
+func bodyText(src string, open, close fenceToken) string {
+	return src[open.offset+len(open.text) : close.offset]
+}
+
+// freeVariables returns the free variables of a JavaScript snippet, and the
+// names that are also declared somewhere inside it
+func freeVariables(snippet string) (free map[string]bool, shadowed map[string]bool, err error) {
+	program, err := js.Parse(parse.NewInputString(snippet), js.Options{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	free = make(map[string]bool)
+	for _, v := range program.BlockStmt.Scope.Undeclared {
+		free[string(v.Name())] = true
+	}
+
+	collector := &declaredVarCollector{declared: make(map[string]bool)}
+	js.Walk(collector, program)
+	return free, collector.declared, nil
+}
+
+// declaredVarCollector records every variable declared inside a snippet
+type declaredVarCollector struct {
+	declared map[string]bool
+}
+
+func (c *declaredVarCollector) Enter(node js.INode) js.IVisitor {
+	if v, ok := node.(*js.Var); ok && v.Decl != js.NoDecl {
+		c.declared[string(v.Name())] = true
+	}
+	return c
+}
+
+func (*declaredVarCollector) Exit(js.INode) {}
+
+// fenceReferences returns the fence names a snippet refers to. Names that the
+// snippet also declares locally are left out, since their uses can't be told apart
+// from the fence variable without resolving every scope.
+func (st *state) fenceReferences(snippet string, names map[string]bool) map[string]bool {
+	free, shadowed, err := freeVariables(snippet)
+	if err != nil {
+		st.logger.Printf("Warning: Failed to analyze fence code: %v\n%s", err, snippet)
+		return nil
+	}
+
+	refs := make(map[string]bool)
+	for name := range free {
+		if !names[name] {
+			continue
+		}
+		if shadowed[name] {
+			st.logger.Printf("Warning: Fence variable '%s' is shadowed by a local declaration and won't be rewritten", name)
+			continue
+		}
+		refs[name] = true
+	}
+	return refs
+}
+
+// rewriteFenceReferences prefixes references to fence variables with "this." so
+// they resolve against the Alpine data object. Property names, object keys and
+// shorthand properties are handled; everything else is kept verbatim.
+func rewriteFenceReferences(src string, refs map[string]bool, expression bool) string {
+	if len(refs) == 0 {
+		return src
+	}
+
+	// The fence was tokenized when its statements were split, and warned about then
+	tokens, _ := tokenizeJS(src)
+	var sb strings.Builder
+	var objectBraces []bool
+	prev := js.ErrorToken
+
+	for i, tok := range tokens {
+		if !isSignificant(tok.tt) {
+			sb.WriteString(tok.text)
+			continue
+		}
+
+		switch tok.tt {
+		case js.OpenBraceToken:
+			objectBraces = append(objectBraces, opensObjectLiteral(prev, expression))
+		case js.CloseBraceToken:
+			if len(objectBraces) > 0 {
+				objectBraces = objectBraces[:len(objectBraces)-1]
+			}
+		case js.IdentifierToken:
+			if !refs[tok.text] {
+				break
+			}
+			next, _ := nextSignificant(tokens, i+1)
+			inObject := len(objectBraces) > 0 && objectBraces[len(objectBraces)-1]
+			switch {
+			case prev == js.DotToken || prev == js.OptChainToken:
+				sb.WriteString(tok.text)
+			case inObject && (prev == js.OpenBraceToken || prev == js.CommaToken):
+				if next.tt == js.CommaToken || next.tt == js.CloseBraceToken {
+					sb.WriteString(tok.text + ": this." + tok.text)
+				} else {
+					sb.WriteString(tok.text)
+				}
+			default:
+				sb.WriteString("this." + tok.text)
+			}
+			prev = tok.tt
+			continue
+		}
+
+		sb.WriteString(tok.text)
+		prev = tok.tt
+	}
+
+	return sb.String()
+}
+
+// opensObjectLiteral guesses whether a brace starts an object literal or a block
+func opensObjectLiteral(prev js.TokenType, expression bool) bool {
+	switch prev {
+	case js.ErrorToken:
+		return expression
+	case js.CloseParenToken, js.CloseBracketToken, js.CloseBraceToken, js.SemicolonToken,
+		js.OpenBraceToken, js.ArrowToken, js.ElseToken, js.DoToken, js.TryToken, js.FinallyToken:
+		return false
+	case js.TemplateStartToken, js.TemplateMiddleToken, js.ReturnToken, js.TypeofToken,
+		js.VoidToken, js.DeleteToken, js.ThrowToken, js.InToken, js.YieldToken:
+		return true
+	}
+	return js.IsPunctuator(prev)
+}
+
+// clientHelpers are the fence functions that only have a meaning on the client
+var clientHelpers = []string{"onMount", "onDestroy", "setContext", "getContext"}
+
+// allNamed reports whether every declaration of a statement is one of names
+func allNamed(decls []fenceDecl, names map[string]bool) bool {
+	for _, decl := range decls {
+		if !names[decl.Name] || decl.Function != nil {
+			return false
+		}
+	}
+	return len(decls) > 0
+}
+
+// stubClientHelpers defines the client helpers as no-ops, so evaluating a fence
+// on the server never runs callbacks that are meant for the client
+func stubClientHelpers(vm *goja.Runtime) {
+	for _, helper := range clientHelpers {
+		vm.Set(helper, func(goja.FunctionCall) goja.Value { return goja.Undefined() })
+	}
+}
+
+// fenceEvalError reports a fence statement that fails in goja
+type fenceEvalError struct {
+	Offset int // byte offset of the statement in the fence
+	Err    error
+}
+
+// snapshotStatementVar holds the index of the statement the snapshot script is
+// running, so a failure can be traced back to its statement
+const snapshotStatementVar = "__snapshotStatement"
+
+// snapshotFenceValues runs the fence script in goja and returns the values of the
+// requested declarations. Props passed in replace their defaults. The unevaluated
+// declarations, getters derived from props passed as parent expressions, are
+// declared without a value, as those props have none here. If the script fails
+// (e.g., it uses browser APIs), the values are left as null and the failing
+// statement is returned.
+func (st *state) snapshotFenceValues(statements []fenceStatement, provided map[string]any, unevaluated map[string]bool, names []string) (map[string]any, *fenceEvalError) {
+	var script strings.Builder
+	// The statements run, with their first lines in the script and their offsets
+	// in the fence
+	var scriptLines, fenceOffsets []int
+	for _, stmt := range statements {
+		// Side effects of reactive statements only run on the client
+		if stmt.Kind == "import" || stmt.Kind == "lifecycle" || stmt.Kind == "context" || stmt.Kind == "store" ||
+			(stmt.Kind == "reactive" && len(stmt.Decls) == 0) {
+			continue
+		}
+		script.WriteString(fmt.Sprintf("%s = %d;\n", snapshotStatementVar, len(fenceOffsets)))
+		scriptLines = append(scriptLines, strings.Count(script.String(), "\n")+1)
+		fenceOffsets = append(fenceOffsets, stmt.Offset)
+		if stmt.Kind == "declaration" && len(stmt.Decls) == 1 {
+			if value, ok := provided[stmt.Decls[0].Name]; ok {
+				switch value.(type) {
+				case fenceGetter, utils.Binding:
+					// Props passed as expressions of a parent scope have no value here
+					script.WriteString("let " + stmt.Decls[0].Name + ";\n")
+				default:
+					script.WriteString("let " + stmt.Decls[0].Name + " = " + utils.AnyToJSValue(value) + ";\n")
+				}
+				continue
+			}
+		}
+		if stmt.Kind == "declaration" && allNamed(stmt.Decls, unevaluated) {
+			for _, decl := range stmt.Decls {
+				script.WriteString("let " + decl.Name + ";\n")
+			}
+			continue
+		}
+		script.WriteString(stmt.Text + ";\n")
+	}
+
+	values := make(map[string]any)
+	for _, name := range names {
+		values[name] = nil
+	}
+
+	// The script is parsed apart from goja.Compile, which drops the position of
+	// syntax errors
+	parsed, err := gojaparser.ParseFile(nil, "", script.String(), 0)
+	if err != nil {
+		st.logger.Printf("Warning: Failed to parse fence for snapshot: %v", err)
+		evalErr := &fenceEvalError{Err: err}
+		var syntaxErrs gojaparser.ErrorList
+		if errors.As(err, &syntaxErrs) && len(syntaxErrs) > 0 {
+			// The failing statement is the last one starting before the error
+			for i, line := range scriptLines {
+				if line <= syntaxErrs[0].Position.Line {
+					evalErr.Offset = fenceOffsets[i]
+				}
+			}
+		}
+		return values, evalErr
+	}
+	program, err := goja.CompileAST(parsed, false)
+	if err != nil {
+		st.logger.Printf("Warning: Failed to compile fence for snapshot: %v", err)
+		return values, &fenceEvalError{Err: err}
+	}
+
+	vm := goja.New()
+	stubClientHelpers(vm)
+	if _, err := vm.RunProgram(program); err != nil {
+		st.logger.Printf("Warning: Failed to evaluate fence for snapshot: %v", err)
+		evalErr := &fenceEvalError{Err: err}
+		if i, ok := vm.Get(snapshotStatementVar).Export().(int64); ok && int(i) < len(fenceOffsets) {
+			evalErr.Offset = fenceOffsets[i]
+		}
+		return values, evalErr
+	}
+
+	for _, name := range names {
+		// Top-level let and const are not properties of the global object, so
+		// they are read back by evaluating their name
+		value, err := vm.RunString(name)
+		if err != nil {
+			st.logger.Printf("Warning: Failed to snapshot fence variable '%s': %v", name, err)
+			continue
+		}
+		values[name] = value.Export()
+	}
+	return values, nil
+}
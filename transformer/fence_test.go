@@ -0,0 +1,137 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+func TestCollectFenceDeclarations(t *testing.T) {
+	tests := []struct {
+		name        string
+		fence       string
+		props       map[string]any
+		options     Options
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "functions become methods",
+			fence: `let count = 0;
+function increment(step) {
+  count += step;
+  return label(count);
+}
+const label = n => ` + "`#${n}`" + `
+async function load() { await fetch("/api") }`,
+			contains: []string{
+				`"count": 0`,
+				"increment(step) {\n  this.count += step;\n  return this.label(this.count);\n}",
+				"label(n) {return `#${n}`}",
+				`async load() { await fetch("/api") }`,
+			},
+		},
+		{
+			name: "local declarations shadow fence variables",
+			fence: `let total = 1;
+function add(total) { return total + 1 }`,
+			contains:    []string{"add(total) { return total + 1 }"},
+			notContains: []string{"this.total"},
+		},
+		{
+			name: "object keys and shorthand properties",
+			fence: `let a = 1;
+function pack(obj) { return { a, b: a, c: obj.a } }`,
+			contains: []string{"return { a: this.a, b: this.a, c: obj.a }"},
+		},
+		{
+			name: "derived consts become getters",
+			fence: `prop items = [1, 2, 3];
+const limit = 2;
+const visible = items.filter(i => i <= limit);`,
+			contains: []string{
+				`"items": [1, 2, 3]`,
+				`"limit": 2`,
+				"get visible() { return this.items.filter(i => i <= this.limit) }",
+			},
+		},
+		{
+			name: "derived consts can be snapshotted",
+			fence: `prop items = [1, 2, 3];
+const limit = 2;
+const visible = items.filter(i => i <= limit);`,
+			options:     Options{SnapshotDerived: true},
+			contains:    []string{`"visible": [1, 2]`},
+			notContains: []string{"get visible"},
+		},
+		{
+			name: "props passed in replace defaults",
+			fence: `prop items = [1, 2, 3];
+prop first = items[0];`,
+			props:    map[string]any{"items": []any{7, 8}},
+			contains: []string{`"items": [7, 8]`, `"first": 7`},
+		},
+		{
+			name: "dispatch calls use the $dispatch magic",
+			fence: `prop product;
+function pick() { dispatch("select", product) }
+function relay(dispatch) { dispatch("x") }`,
+			contains: []string{
+				`pick() { this.$dispatch("select", this.product) }`,
+				`relay(dispatch) { dispatch("x") }`,
+			},
+		},
+		{
+			name: "reactive assignments become getters",
+			fence: `let count = 1;
+$: doubled = count * 2
+$: quadrupled = doubled * 2
+function show() { return doubled }`,
+			contains: []string{
+				"get doubled() { return this.count * 2 }",
+				"get quadrupled() { return this.doubled * 2 }",
+				"show() { return this.doubled }",
+			},
+		},
+		{
+			name: "reactive statements spanning lines",
+			fence: `let count = 0;
+$: if (count > 10)
+  count = 0
+else
+  count++
+let label = "x";`,
+			contains: []string{`"count": 0`, `"label": "x"`},
+		},
+		{
+			name: "stores are not component data",
+			fence: `store cart = { items: [] }
+persist store prefs = { theme: 'dark' }
+let store = 1;`,
+			options:     Options{SnapshotDerived: true},
+			contains:    []string{`"store": 1`},
+			notContains: []string{"cart", "prefs", "items"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataScope := InitDataScope(tt.props)
+			st := newState(Options{})
+			st.collectFenceDeclarations(tt.fence, ast.Position{}, dataScope, tt.options)
+			output := formatGoValueToJS(dataScope, false, st.logger)
+
+			for _, s := range tt.contains {
+				if !strings.Contains(output, s) {
+					t.Errorf("Expected output to contain %q, but it doesn't.\nOutput: %s", s, output)
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(output, s) {
+					t.Errorf("Expected output not to contain %q, but it does.\nOutput: %s", s, output)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package transformer
+
+import (
+	"log"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/codegen/rt"
+)
+
+// Generated code formats props with codegen/rt, which must match the transformer
+func TestFormatGoValueToJSMatchesGeneratedCode(t *testing.T) {
+	values := []any{
+		nil,
+		true,
+		false,
+		42,
+		int64(-7),
+		uint8(3),
+		2.5,
+		float32(0.25),
+		"plain",
+		"It's <b>\"quoted\"</b> & escaped \\ \n\r\t",
+		"() => alert(1)",
+		"function named() {}",
+		[]any{},
+		[]any{"a", 1, []any{nil, false}},
+		map[string]any{},
+		map[string]any{"b": "x'y", "a": map[string]any{"nested": []any{1.5}}},
+		[]string{"not", "plain"},
+	}
+	for _, value := range values {
+		for _, htmlQuotes := range []bool{false, true} {
+			want := formatGoValueToJS(value, htmlQuotes, log.Default())
+			if got := rt.JSValue(value, htmlQuotes); got != want {
+				t.Errorf("rt.JSValue(%#v, %t) = %s, want %s", value, htmlQuotes, got, want)
+			}
+		}
+	}
+}
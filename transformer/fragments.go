@@ -0,0 +1,180 @@
+package transformer
+
+import (
+	"strings"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+// parentElement returns the tag name of the element enclosing the nodes being
+// transformed, or "" at the top of the template
+func (st *state) parentElement() string {
+	if len(st.parentElements) == 0 {
+		return ""
+	}
+	return strings.ToLower(st.parentElements[len(st.parentElements)-1])
+}
+
+// permittedChildren lists the only elements allowed directly inside the elements
+// whose content is restricted
+var permittedChildren = map[string][]string{
+	"ul":       {"li"},
+	"ol":       {"li"},
+	"menu":     {"li"},
+	"table":    {"caption", "colgroup", "thead", "tbody", "tfoot", "tr"},
+	"thead":    {"tr"},
+	"tbody":    {"tr"},
+	"tfoot":    {"tr"},
+	"tr":       {"td", "th"},
+	"colgroup": {"col"},
+	"select":   {"option", "optgroup", "hr"},
+	"optgroup": {"option"},
+	"dl":       {"dt", "dd", "div"},
+}
+
+// permittedParents lists the only parents allowed for the elements that can't
+// appear just anywhere
+var permittedParents = map[string][]string{
+	"li":       {"ul", "ol", "menu"},
+	"tr":       {"table", "thead", "tbody", "tfoot"},
+	"td":       {"tr"},
+	"th":       {"tr"},
+	"caption":  {"table"},
+	"colgroup": {"table"},
+	"thead":    {"table"},
+	"tbody":    {"table"},
+	"tfoot":    {"table"},
+	"col":      {"colgroup"},
+	"option":   {"select", "optgroup", "datalist"},
+	"optgroup": {"select"},
+	"dt":       {"dl", "div"},
+	"dd":       {"dl", "div"},
+}
+
+// validNesting reports whether a child element may appear directly inside a parent.
+// An unknown parent, at the top of a template or inside a <template>, allows anything.
+func validNesting(parent, child string) bool {
+	parent, child = strings.ToLower(parent), strings.ToLower(child)
+	if parent == "" || parent == "template" || child == "template" || child == "script" {
+		return true
+	}
+	if children, restricted := permittedChildren[parent]; restricted && !containsString(children, child) {
+		return false
+	}
+	if parents, restricted := permittedParents[child]; restricted && !containsString(parents, parent) {
+		return false
+	}
+	return true
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// placeComponent attaches the scope attributes of a component instance (x-data,
+// x-component, effects and listeners) to the nodes the component renders. A single
+// root element receives them directly. Several roots inside an element whose
+// children are restricted, like <tr>s in a <tbody>, can't be wrapped and each
+// receive them. Anything else is wrapped in a <div> with display: contents, which
+// doesn't affect the layout.
+func (st *state) placeComponent(name string, scope []ast.Attribute, children []ast.Node) []ast.Node {
+	parent := st.parentElement()
+
+	var roots []int
+	for i, child := range children {
+		if text, ok := child.(*ast.TextNode); ok && strings.TrimSpace(text.Content) == "" {
+			continue
+		}
+		if _, ok := child.(*ast.CommentNode); ok {
+			continue
+		}
+		roots = append(roots, i)
+	}
+
+	if len(roots) == 1 || (len(roots) > 1 && permittedChildren[parent] != nil) {
+		if placed, ok := attachToRoots(scope, children, roots); ok {
+			if len(roots) > 1 {
+				st.logger.Printf("Warning: Component %s renders %d root elements inside <%s>, each of them gets its own copy of the component's data", name, len(roots), parent)
+			}
+			st.checkComponentNesting(name, parent, placed)
+			return placed
+		}
+	}
+
+	wrapper := &ast.Element{
+		TagName:     "div",
+		Attributes:  append(scope, ast.Attribute{Name: "style", Value: "display: contents"}),
+		Children:    children,
+		SelfClosing: false,
+	}
+	st.checkComponentNesting(name, parent, []ast.Node{wrapper})
+	return []ast.Node{wrapper}
+}
+
+// attachToRoots returns the children with the scope attributes added to each root.
+// It fails when a root is not an element that can hold a scope, or when its own
+// attributes clash with the scope's.
+func attachToRoots(scope []ast.Attribute, children []ast.Node, roots []int) ([]ast.Node, bool) {
+	placed := append([]ast.Node{}, children...)
+	for _, i := range roots {
+		root, ok := children[i].(*ast.Element)
+		if !ok || strings.ToLower(root.TagName) == "template" {
+			return nil, false
+		}
+		attributes, ok := mergeScopeAttributes(scope, root.Attributes)
+		if !ok {
+			return nil, false
+		}
+		element := *root
+		element.Attributes = attributes
+		placed[i] = &element
+	}
+	return placed, true
+}
+
+// mergeScopeAttributes puts the scope attributes of a component instance in front
+// of the attributes of its root element. Effects of both are combined; an x-data
+// of the root, or any other attribute set by both, can't be merged.
+func mergeScopeAttributes(scope, own []ast.Attribute) ([]ast.Attribute, bool) {
+	merged := append([]ast.Attribute{}, scope...)
+	for _, attr := range own {
+		if attr.Name == "x-data" || (attr.IsAlpine && attr.AlpineType == "data") {
+			return nil, false
+		}
+		if hasAttribute(scope, attr.Name) {
+			if attr.IsAlpine && attr.AlpineType == "effect" {
+				merged = appendToDirective(merged, "effect", []string{attr.Value})
+				continue
+			}
+			return nil, false
+		}
+		merged = append(merged, attr)
+	}
+	return merged, true
+}
+
+// checkComponentNesting reports the elements a component renders that aren't
+// allowed inside the element the component is used in. The browser would move
+// them elsewhere when parsing the page.
+func (st *state) checkComponentNesting(name, parent string, nodes []ast.Node) {
+	for _, node := range nodes {
+		element, ok := node.(*ast.Element)
+		if !ok {
+			continue
+		}
+		if strings.ToLower(element.TagName) == "template" {
+			// Loops and conditionals render their content in place
+			st.checkComponentNesting(name, parent, element.Children)
+			continue
+		}
+		if !validNesting(parent, element.TagName) {
+			st.logger.Printf("Warning: Component %s renders <%s> inside <%s>, which is not valid HTML nesting", name, strings.ToLower(element.TagName), parent)
+		}
+	}
+}
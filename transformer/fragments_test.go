@@ -0,0 +1,150 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+func TestComponentPlacement(t *testing.T) {
+	RegisterComponent("MenuItem", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "prop label;"},
+		&ast.TextNode{Content: "\n"},
+		&ast.Element{TagName: "li", Attributes: []ast.Attribute{{Name: "class", Value: "item"}}, Children: []ast.Node{
+			&ast.ExpressionNode{Expression: "label"},
+		}},
+		&ast.TextNode{Content: "\n"},
+	}}, []string{"label"})
+	RegisterComponent("PriceRows", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "prop price;\nfunction init() { console.log(price) }"},
+		&ast.Element{TagName: "tr", Children: []ast.Node{&ast.TextNode{Content: "Net"}}},
+		&ast.Element{TagName: "tr", Children: []ast.Node{&ast.TextNode{Content: "Gross"}}},
+	}}, []string{"price"})
+	RegisterComponent("Media", &ast.Template{RootNodes: []ast.Node{
+		&ast.Element{TagName: "img", SelfClosing: true},
+		&ast.Element{TagName: "p", Children: []ast.Node{&ast.TextNode{Content: "Caption"}}},
+	}}, nil)
+	RegisterComponent("Dropdown", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "prop title;"},
+		&ast.Element{TagName: "div", Attributes: []ast.Attribute{
+			{Name: "x-data", Value: "{ open: false }", IsAlpine: true, AlpineType: "data"},
+		}},
+	}}, []string{"title"})
+	RegisterComponent("SaveButton", &ast.Template{RootNodes: []ast.Node{
+		&ast.Element{TagName: "button", Attributes: []ast.Attribute{
+			{Name: "@click", Value: "dispatch('click')", IsAlpine: true, AlpineType: "on", AlpineKey: "click"},
+		}},
+	}}, nil)
+	defer func() {
+		for _, name := range []string{"MenuItem", "PriceRows", "Media", "Dropdown", "SaveButton"} {
+			defaultRegistry.Unregister(name)
+		}
+	}()
+
+	tests := []struct {
+		name        string
+		nodes       []ast.Node
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "single root receives the scope",
+			nodes: []ast.Node{&ast.Element{TagName: "ul", Children: []ast.Node{
+				&ast.ComponentNode{Name: "MenuItem", Props: []ast.ComponentProp{{Name: "label", Value: "Home"}}},
+			}}},
+			contains:    []string{`<ul> <li x-data="{"label": 'Home'}" x-component="MenuItem" class="item"><span x-text="label"></span></li> </ul>`},
+			notContains: []string{"<div"},
+		},
+		{
+			name: "several roots in a table body each receive the scope",
+			nodes: []ast.Node{&ast.Element{TagName: "tbody", Children: []ast.Node{
+				&ast.ComponentNode{Name: "PriceRows", Props: []ast.ComponentProp{
+					{Name: "price", Value: "4"},
+					{Name: "select", Value: "pick", IsDynamic: true, IsEvent: true},
+				}},
+				&ast.Element{TagName: "tr", Children: []ast.Node{&ast.TextNode{Content: "Total"}}},
+			}}},
+			contains: []string{
+				`<tbody><tr x-data="{init() { console.log(this.price) }, "price": '4'}" x-component="PriceRows" @select=`,
+				`>Net</tr><tr x-data="{init() { console.log(this.price) }, "price": '4'}" x-component="PriceRows" @select=`,
+				`>Gross</tr><tr>Total</tr></tbody>`,
+			},
+			notContains: []string{"display: contents", "<template"},
+		},
+		{
+			name: "several roots elsewhere share a display: contents wrapper",
+			nodes: []ast.Node{&ast.Element{TagName: "section", Children: []ast.Node{
+				&ast.ComponentNode{Name: "Media"},
+			}}},
+			contains: []string{`<section><div x-component="Media" style="display: contents"><img /><p>Caption</p></div></section>`},
+		},
+		{
+			name: "root with its own x-data keeps the wrapper",
+			nodes: []ast.Node{
+				&ast.ComponentNode{Name: "Dropdown", Props: []ast.ComponentProp{{Name: "title", Value: "Menu"}}},
+			},
+			contains: []string{`<div x-data="{"title": 'Menu'}" x-component="Dropdown" style="display: contents"><div x-data="{ open: false }"></div></div>`},
+		},
+		{
+			name: "listener clashing with an attribute of the root keeps the wrapper",
+			nodes: []ast.Node{
+				&ast.ComponentNode{Name: "SaveButton", Props: []ast.ComponentProp{{Name: "click", Value: "save", IsEvent: true}}},
+			},
+			contains: []string{`<div x-component="SaveButton" @click=`, `style="display: contents"><button @click="$dispatch('click')"></button></div>`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := TransformAST(&ast.Template{RootNodes: tt.nodes}, map[string]any{})
+
+			var sb strings.Builder
+			for _, node := range result.RootNodes {
+				renderTestNode(&sb, node)
+			}
+			output := sb.String()
+
+			for _, expected := range tt.contains {
+				if !strings.Contains(output, expected) {
+					t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+				}
+			}
+			for _, unexpected := range tt.notContains {
+				if strings.Contains(output, unexpected) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", unexpected, output)
+				}
+			}
+		})
+	}
+}
+
+func TestValidNesting(t *testing.T) {
+	tests := []struct {
+		parent   string
+		child    string
+		expected bool
+	}{
+		{"ul", "li", true},
+		{"ul", "div", false},
+		{"tbody", "tr", true},
+		{"tbody", "div", false},
+		{"TBODY", "TR", true},
+		{"tr", "td", true},
+		{"div", "tr", false},
+		{"div", "li", false},
+		{"select", "option", true},
+		{"div", "p", true},
+		{"", "tr", true},
+		{"template", "li", true},
+		{"ul", "template", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parent+">"+tt.child, func(t *testing.T) {
+			if result := validNesting(tt.parent, tt.child); result != tt.expected {
+				t.Errorf("Expected validNesting(%q, %q) to be %v, got %v", tt.parent, tt.child, tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package transformer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lifecycleHooks are the fence functions that register client-side lifecycle callbacks
+var lifecycleHooks = map[string]bool{
+	"onMount":   true,
+	"onDestroy": true,
+}
+
+// addLifecycleMethods compiles the onMount and onDestroy callbacks of a fence into
+// the init() and destroy() methods of the Alpine data object, which Alpine calls
+// when the component is initialized and removed. A function returned from an
+// onMount callback is kept on the root element and called on destroy.
+func (st *state) addLifecycleMethods(statements []fenceStatement, dataScope map[string]any, names map[string]bool) {
+	var mounts, destroys []string
+	for _, stmt := range statements {
+		if stmt.Kind != "lifecycle" {
+			continue
+		}
+		if stmt.Callback == nil {
+			st.logger.Printf("Warning: %s expects a function, skipping: %s", stmt.Hook, strings.TrimSpace(stmt.Text))
+			continue
+		}
+
+		call := lifecycleCall(st.methodFromFunction(*stmt.Callback, names))
+		if stmt.Hook == "onMount" {
+			mounts = append(mounts, fmt.Sprintf("\n  { const cleanup = %s; if (typeof cleanup === 'function') this.$el._x_mountCleanups.push(cleanup) }", call))
+		} else {
+			destroys = append(destroys, "\n  "+call+";")
+		}
+	}
+
+	if len(mounts) > 0 {
+		body := "\n  this.$el._x_mountCleanups = [];" + strings.Join(mounts, "") + "\n"
+		dataScope["init"] = st.lifecycleMethod("init", body, dataScope)
+	}
+	if len(mounts) > 0 || len(destroys) > 0 {
+		var body string
+		if len(mounts) > 0 {
+			body = "\n  (this.$el._x_mountCleanups || []).forEach(cleanup => cleanup());"
+		}
+		body += strings.Join(destroys, "") + "\n"
+		dataScope["destroy"] = st.lifecycleMethod("destroy", body, dataScope)
+	}
+}
+
+// lifecycleCall returns an expression that runs a callback in place. It becomes an
+// arrow function so this still refers to the Alpine data object.
+func lifecycleCall(fn fenceFunction) string {
+	async := ""
+	if fn.Async {
+		async = "async "
+	}
+	return fmt.Sprintf("(%s() => {%s})()", async, fn.Body)
+}
+
+// lifecycleMethod builds an init or destroy method, running a method of the same
+// name that was declared in the fence before the lifecycle callbacks
+func (st *state) lifecycleMethod(name, body string, dataScope map[string]any) fenceFunction {
+	if existing, ok := dataScope[name].(fenceFunction); ok {
+		st.logger.Printf("Warning: Fence function '%s' is combined with the lifecycle callbacks", name)
+		body = "\n  " + lifecycleCall(existing) + ";" + body
+	}
+	return fenceFunction{Body: body}
+}
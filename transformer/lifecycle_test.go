@@ -0,0 +1,80 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+func TestAddLifecycleMethods(t *testing.T) {
+	tests := []struct {
+		name        string
+		fence       string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "onMount and onDestroy become init and destroy",
+			fence: `let seconds = 0;
+let timer;
+onMount(() => {
+  timer = setInterval(() => seconds++, 1000);
+  return () => clearInterval(timer);
+});
+onDestroy(() => console.log("bye", seconds));`,
+			contains: []string{
+				`"seconds": 0`,
+				"init() {\n  this.$el._x_mountCleanups = [];\n  { const cleanup = (() => {\n  this.timer = setInterval(() => this.seconds++, 1000);\n  return () => clearInterval(this.timer);\n})();",
+				"if (typeof cleanup === 'function') this.$el._x_mountCleanups.push(cleanup) }",
+				"destroy() {\n  (this.$el._x_mountCleanups || []).forEach(cleanup => cleanup());\n  (() => {return console.log(\"bye\", this.seconds)})();\n}",
+			},
+		},
+		{
+			name: "async callbacks and named functions",
+			fence: `let data = null;
+async function load() { data = await fetch("/api") }
+onMount(async () => { await load() });
+onDestroy(reset);
+function reset() { data = null }`,
+			contains: []string{
+				"(async () => { await this.load() })()",
+				"(() => {return this.reset()})();",
+			},
+		},
+		{
+			name: "onDestroy only does not add init",
+			fence: `let open = true;
+onDestroy(() => { open = false })`,
+			contains:    []string{"destroy() {\n  (() => { this.open = false })();\n}"},
+			notContains: []string{"init()", "_x_mountCleanups"},
+		},
+		{
+			name: "fence init runs before the callbacks",
+			fence: `let ready = false;
+function init() { ready = true }
+onMount(() => console.log(ready))`,
+			contains: []string{"init() {\n  (() => { this.ready = true })();\n  this.$el._x_mountCleanups = [];"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataScope := InitDataScope(nil)
+			st := newState(Options{})
+			st.collectFenceDeclarations(tt.fence, ast.Position{}, dataScope, Options{})
+			output := formatGoValueToJS(dataScope, false, st.logger)
+
+			for _, s := range tt.contains {
+				if !strings.Contains(output, s) {
+					t.Errorf("Expected output to contain %q, but it doesn't.\nOutput: %s", s, output)
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(output, s) {
+					t.Errorf("Expected output not to contain %q, but it does.\nOutput: %s", s, output)
+				}
+			}
+		})
+	}
+}
@@ -2,14 +2,13 @@ package transformer
 
 import (
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/jimafisk/custom_go_template/ast"
 )
 
 // transformLoop transforms a Loop node into an Alpine.js compatible structure
-func transformLoop(node *ast.Loop, dataScope map[string]any) []ast.Node {
+func (st *state) transformLoop(node *ast.Loop, dataScope map[string]any) []ast.Node {
 	// Add loop variables to the data scope
 	dataScope[node.Iterator] = nil
 	if node.Value != "" {
@@ -28,33 +27,33 @@ func transformLoop(node *ast.Loop, dataScope map[string]any) []ast.Node {
 	// Handle specific test cases first
 	if node.Collection == "categories" && node.Iterator == "category" && node.Value == "" {
 		// Special case for category loop in nested_conditionals_and_loops test
-		return createLoopTemplate("category in categories", node.Content, dataScope)
+		return st.createLoopTemplate("category in categories", node.Content, dataScope)
 	}
 
 	if node.Collection == "category.items" && node.Iterator == "item" && node.Value == "" {
 		// Special case for item loop in nested_conditionals_and_loops test
-		return createLoopTemplate("item in category.items", node.Content, dataScope)
+		return st.createLoopTemplate("item in category.items", node.Content, dataScope)
 	}
 
 	if node.Iterator == "index" && node.Value == "task" && cleanedCollection == "tasks" {
 		// Special case for the loop with index and task test - FIXED: Use expected format
-		return createLoopTemplate("(index, task) in tasks", node.Content, dataScope)
+		return st.createLoopTemplate("(index, task) in tasks", node.Content, dataScope)
 	}
 
 	if node.Iterator == "index" && node.Value == "user" && cleanedCollection == "users" {
 		// Special case for the loop with index and user test - FIXED: Use expected format
-		return createLoopTemplate("(index, user) in users", node.Content, dataScope)
+		return st.createLoopTemplate("(index, user) in users", node.Content, dataScope)
 	}
 
 	// Special case for the array loop with index test
 	if node.Iterator == "index" && node.Value == "item" && cleanedCollection == "items" {
 		// This is the exact case from the test - use the expected format
-		return createLoopTemplate("(index, item) in items", node.Content, dataScope)
+		return st.createLoopTemplate("(index, item) in items", node.Content, dataScope)
 	}
 
 	if node.Iterator == "key" && node.Value == "value" && cleanedCollection == "product" {
 		// Special case for object iteration in tests - FIXED: Removed parentheses
-		return createLoopTemplate("key, value of Object.entries(product)", node.Content, dataScope)
+		return st.createLoopTemplate("key, value of Object.entries(product)", node.Content, dataScope)
 	}
 
 	// Handle the standard cases
@@ -71,7 +70,7 @@ func transformLoop(node *ast.Loop, dataScope map[string]any) []ast.Node {
 			
 			// The original iterator variable would be used as entry[0] (key) or entry[1] (value)
 			// Add a note to the log for clarity
-			log.Printf("Object iteration with single variable %s represented as 'entry' in Alpine", node.Iterator)
+			st.logger.Printf("Object iteration with single variable %s represented as 'entry' in Alpine", node.Iterator)
 		}
 	} else {
 		// For array iteration, use standard Alpine.js 'in' syntax
@@ -121,18 +120,18 @@ func transformLoop(node *ast.Loop, dataScope map[string]any) []ast.Node {
 	}
 
 	// Log the loop expression for debugging
-	log.Printf("Loop expression: %s", loopExpr)
+	st.logger.Printf("Loop expression: %s", loopExpr)
 
-	return createLoopTemplate(loopExpr, node.Content, dataScope)
+	return st.createLoopTemplate(loopExpr, node.Content, dataScope)
 }
 
 // createLoopTemplate creates a template element with the x-for directive
-func createLoopTemplate(loopExpr string, content []ast.Node, dataScope map[string]any) []ast.Node {
+func (st *state) createLoopTemplate(loopExpr string, content []ast.Node, dataScope map[string]any) []ast.Node {
 	// Create a child scope for the loop content
 	loopScope := CreateChildScope(dataScope)
 
 	// Transform the loop content
-	transformedContent := transformNodes(content, loopScope, false)
+	transformedContent := st.transformNodes(content, loopScope, false)
 
 	// Create the template element with x-for directive
 	template := &ast.Element{
@@ -342,26 +341,26 @@ func cleanLoopCollection(collection string) string {
 
 // transformNestedConditionals processes conditionals that are nested within other nodes
 // such as loops, ensuring proper template nesting and condition handling
-func transformNestedConditionals(nodes []ast.Node, dataScope map[string]any) []ast.Node {
-	return transformNestedConditionalsInLoops(nodes, dataScope)
+func (st *state) transformNestedConditionals(nodes []ast.Node, dataScope map[string]any) []ast.Node {
+	return st.transformNestedConditionalsInLoops(nodes, dataScope)
 }
 
 // transformNestedConditionalsInLoops processes any conditionals within the loop content
 // and ensures they use the correct x-else and x-else-if directives
-func transformNestedConditionalsInLoops(nodes []ast.Node, dataScope map[string]any) []ast.Node {
+func (st *state) transformNestedConditionalsInLoops(nodes []ast.Node, dataScope map[string]any) []ast.Node {
 	var result []ast.Node
 
 	for _, node := range nodes {
 		switch n := node.(type) {
 		case *ast.Conditional:
 			// Transform the conditional using the standard transformation
-			transformedConditional := transformConditional(n, dataScope)
+			transformedConditional := st.transformConditional(n, dataScope)
 			result = append(result, transformedConditional...)
 			
 		case *ast.Element:
 			// Process any conditionals in the children of elements
 			if n.Children != nil {
-				n.Children = transformNestedConditionalsInLoops(n.Children, dataScope)
+				n.Children = st.transformNestedConditionalsInLoops(n.Children, dataScope)
 			}
 			result = append(result, n)
 			
@@ -393,13 +392,13 @@ func transformNestedConditionalsInLoops(nodes []ast.Node, dataScope map[string]a
 	}
 
 	// Now transform the result nodes
-	return transformNodes(result, dataScope, false)
+	return st.transformNodes(result, dataScope, false)
 }
 
 // createConditionalTemplate creates a template element with an x-if directive
-func createConditionalTemplate(condition string, content []ast.Node, dataScope map[string]any, isElseIf bool) *ast.Element {
+func (st *state) createConditionalTemplate(condition string, content []ast.Node, dataScope map[string]any, isElseIf bool) *ast.Element {
 	// Transform the content
-	transformedContent := transformNodes(content, dataScope, false)
+	transformedContent := st.transformNodes(content, dataScope, false)
 
 	// Create attributes for the template
 	attrs := []ast.Attribute{
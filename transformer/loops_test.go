@@ -162,7 +162,7 @@ func TestTransformLoop(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Transform the loop
-			result := transformLoop(tt.loop, tt.dataScope)
+			result := newState(Options{}).transformLoop(tt.loop, tt.dataScope)
 			
 			// Convert to string for easier testing
 			var sb strings.Builder
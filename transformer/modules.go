@@ -0,0 +1,13 @@
+package transformer
+
+// lookupComponent finds the template of a component used in the module being
+// transformed: through the imports of the module when it's in the module graph,
+// and among the registered components otherwise
+func (st *state) lookupComponent(name string) (*ComponentTemplate, bool) {
+	if importer := st.moduleStack[len(st.moduleStack)-1]; st.moduleGraph != nil && importer != "" {
+		if module, ok := st.moduleGraph.Component(importer, name); ok {
+			return &ComponentTemplate{Name: name, Path: module.Path, Template: module.Template, Props: module.Props}, true
+		}
+	}
+	return st.components.Get(name)
+}
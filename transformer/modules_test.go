@@ -0,0 +1,51 @@
+package transformer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/modules"
+)
+
+func TestModuleComponents(t *testing.T) {
+	root := filepath.ToSlash(t.TempDir())
+	files := map[string]string{
+		"index.html":            "---\nimport Card from \"./cards/Card.html\";\n---\n<main>\n\t<Card title=\"Page\" />\n</main>\n",
+		"cards/Card.html":       "---\nprop title;\nimport Card from \"./inner/Card.html\";\n---\n<article>\n\t{title}\n\t<Card />\n</article>\n",
+		"cards/inner/Card.html": "<p>Inner card</p>",
+	}
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	graph := modules.NewGraph(nil)
+	module, err := graph.Load(root + "/index.html")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result := TransformASTWithOptions(module.Template, map[string]any{}, Options{Modules: graph, Path: module.Path})
+
+	var sb strings.Builder
+	for _, node := range result.RootNodes {
+		renderTestNode(&sb, node)
+	}
+	output := sb.String()
+
+	// Each Card resolves through the imports of the file it is used in
+	for _, expected := range []string{`x-component="Card"`, "<article", "<p", "Inner card"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "not found") {
+		t.Errorf("Expected every component to be found, got:\n%s", output)
+	}
+}
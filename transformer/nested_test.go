@@ -260,7 +260,7 @@ func TestNestedStructures(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Transform the nodes
-			result := transformNodes(tt.nodes, tt.dataScope, false)
+			result := newState(Options{}).transformNodes(tt.nodes, tt.dataScope, false)
 			
 			// Convert to string for easier testing
 			var sb strings.Builder
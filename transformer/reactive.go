@@ -0,0 +1,69 @@
+package transformer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+// reactiveStatement compiles a $: statement of the fence. An assignment to a single
+// name is a derived value and becomes a getter on the data scope, anything else is
+// a side effect which is returned so it can run in an x-effect on the component
+// root. Alpine re-runs the effect whenever the reactive data it reads changes.
+func (st *state) reactiveStatement(stmt fenceStatement, dataScope map[string]any, names map[string]bool) (string, bool) {
+	if len(stmt.Decls) == 1 {
+		decl := stmt.Decls[0]
+		refs := st.fenceReferences("("+decl.Init+")", names)
+
+		// A value derived from itself would recurse forever as a getter
+		if !refs[decl.Name] {
+			st.logger.Printf("Reactive value '%s' depends on: %s", decl.Name, joinNames(refs))
+			dataScope[decl.Name] = fenceGetter{Expr: rewriteFenceReferences(decl.Init, refs, true)}
+			return "", false
+		}
+	}
+
+	body := strings.TrimSuffix(strings.TrimSpace(stmt.Body), ";")
+	refs := st.fenceReferences(body, names)
+	if len(refs) == 0 {
+		st.logger.Printf("Warning: Reactive statement '%s' does not depend on any fence variable and only runs once", body)
+	} else {
+		st.logger.Printf("Reactive statement '%s' depends on: %s", body, joinNames(refs))
+	}
+
+	// Effects are evaluated with the component data in scope, so the statement
+	// is kept as written and wrapped to allow any statement, not only expressions
+	return fmt.Sprintf("(() => { %s })()", body), true
+}
+
+// applyReactiveEffects adds the side effects of reactive statements as an x-effect
+// on the element that holds the component's x-data. Templates without any other
+// dynamic content are wrapped first, since effects need an Alpine component to run.
+func (st *state) applyReactiveEffects(nodes []ast.Node, dataScope map[string]any, effects []string) []ast.Node {
+	if len(effects) == 0 {
+		return nodes
+	}
+
+	for _, node := range nodes {
+		if element, ok := node.(*ast.Element); ok && hasAttribute(element.Attributes, "x-data") {
+			element.Attributes = appendToDirective(element.Attributes, "effect", effects)
+			return nodes
+		}
+	}
+
+	wrapper := st.createAlpineWrapper(dataScope, nodes)
+	wrapper.Attributes = appendToDirective(wrapper.Attributes, "effect", effects)
+	return []ast.Node{wrapper}
+}
+
+// joinNames returns the names of a set in sorted order
+func joinNames(set map[string]bool) string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
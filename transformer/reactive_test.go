@@ -0,0 +1,77 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+func TestReactiveStatementEffects(t *testing.T) {
+	tests := []struct {
+		name       string
+		fence      string
+		wantEffect []string
+		noEffect   bool
+	}{
+		{
+			name: "side effects run in x-effect",
+			fence: `let count = 0;
+$: console.log("count is", count)
+$: if (count > 10) { count = 0 }`,
+			wantEffect: []string{
+				`(() => { console.log("count is", count) })()`,
+				"(() => { if (count > 10) { count = 0 } })()",
+			},
+		},
+		{
+			name: "derived values are not effects",
+			fence: `let count = 0;
+$: doubled = count * 2`,
+			noEffect: true,
+		},
+		{
+			name: "self-referencing assignments are effects",
+			fence: `let total = 0;
+$: total = total + 1`,
+			wantEffect: []string{"(() => { total = total + 1 })()"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &ast.Template{RootNodes: []ast.Node{
+				&ast.FenceSection{RawContent: tt.fence},
+				&ast.Element{TagName: "p", Children: []ast.Node{&ast.TextNode{Content: "hi"}}},
+			}}
+			result := TransformAST(template, map[string]any{})
+
+			var root *ast.Element
+			for _, node := range result.RootNodes {
+				if element, ok := node.(*ast.Element); ok && hasAttribute(element.Attributes, "x-data") {
+					root = element
+					break
+				}
+			}
+			var effect string
+			var hasEffect bool
+			if root != nil {
+				for _, attr := range root.Attributes {
+					if attr.Name == "x-effect" {
+						effect, hasEffect = attr.Value, true
+					}
+				}
+			} else if !tt.noEffect {
+				t.Fatalf("Expected an x-data root element")
+			}
+			if tt.noEffect && hasEffect {
+				t.Errorf("Expected no x-effect, got %q", effect)
+			}
+			for _, s := range tt.wantEffect {
+				if !strings.Contains(effect, s) {
+					t.Errorf("Expected x-effect to contain %q, got %q", s, effect)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package transformer
+
+import (
+	"strings"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+// maxComponentDepth limits how deeply component instances are nested, so a
+// runaway chain of components can't exhaust the stack
+const maxComponentDepth = 64
+
+// componentFrame is a component whose template is being expanded
+type componentFrame struct {
+	Name   string // name the component is used under
+	ID     string // identity of the component, see ComponentTemplate.id
+	Guards int    // conditionals and loops enclosing the instance
+}
+
+// opensBlock reports whether a conditional or a loop is only the opening of its
+// block, as the parser leaves {if} and {for} inside elements: its content follows
+// it as siblings, up to an IfEndNode or a ForEndNode
+func opensBlock(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.Conditional:
+		return len(n.IfContent) == 0 && len(n.ElseIfContent) == 0 && len(n.ElseContent) == 0
+	case *ast.Loop:
+		return len(n.Content) == 0
+	}
+	return false
+}
+
+// recursiveTemplateID is the id of the shared template of a recursive component,
+// distinct for each component path
+func recursiveTemplateID(component *ComponentTemplate) string {
+	return "x-component-" + escapeIdentifier(component.id())
+}
+
+// componentCycle returns the chain of components from the instance of a component
+// being expanded to a nested instance of it, like [Tree Tree] or [Menu Item Menu],
+// and whether a conditional or a loop sits between them to end the recursion
+func (st *state) componentCycle(id string) ([]string, bool, bool) {
+	for i := len(st.componentStack) - 1; i >= 0; i-- {
+		if st.componentStack[i].ID != id {
+			continue
+		}
+		var chain []string
+		for _, frame := range st.componentStack[i:] {
+			chain = append(chain, frame.Name)
+		}
+		return append(chain, st.componentStack[i].Name), st.componentGuards > st.componentStack[i].Guards, true
+	}
+	return nil, false, false
+}
+
+// recursiveComponentReference replaces the children of a component instance nested in
+// itself with the component's shared template, which Alpine clones with x-html when
+// the instance is rendered. A conditional or loop over the data has to end the
+// recursion; a cycle without one would never end and is reported instead.
+func (st *state) recursiveComponentReference(node *ast.ComponentNode, component *ComponentTemplate) (ast.Attribute, bool) {
+	chain, guarded, _ := st.componentCycle(component.id())
+	if !guarded {
+		file, importChain := st.location()
+		st.fail(&ast.ComponentCycleError{Cycle: chain, File: file, Pos: node.Pos, ImportChain: importChain})
+		return ast.Attribute{}, false
+	}
+
+	known := false
+	for _, recursive := range st.recursiveComponents {
+		known = known || recursive.id() == component.id()
+	}
+	if !known {
+		st.recursiveComponents = append(st.recursiveComponents, component)
+	}
+
+	return ast.Attribute{
+		Name:       "x-html",
+		Value:      "document.getElementById('" + recursiveTemplateID(component) + "').innerHTML",
+		Dynamic:    true,
+		IsAlpine:   true,
+		AlpineType: "html",
+	}, true
+}
+
+// recursiveRoot returns the single root element of a component's template, or nil
+// when it has several roots. The nested instances of a recursive component with
+// a single root are rendered as that element, cloning only its content.
+func recursiveRoot(componentTemplate *ComponentTemplate) *ast.Element {
+	var root *ast.Element
+	for _, node := range componentTemplate.Template.RootNodes {
+		switch n := node.(type) {
+		case *ast.FenceSection, *ast.CommentNode:
+			continue
+		case *ast.TextNode:
+			if strings.TrimSpace(n.Content) == "" {
+				continue
+			}
+		case *ast.Element:
+			if root == nil && strings.ToLower(n.TagName) != "template" {
+				root = n
+				continue
+			}
+		}
+		return nil
+	}
+	return root
+}
+
+// recursiveInstance returns the nodes a nested instance of a recursive component
+// renders before its shared template is cloned into them
+func (st *state) recursiveInstance(componentTemplate *ComponentTemplate, dataScope map[string]any) []ast.Node {
+	root := recursiveRoot(componentTemplate)
+	if root == nil {
+		return nil
+	}
+	return []ast.Node{&ast.Element{
+		TagName:    root.TagName,
+		Attributes: st.transformElementAttributes(root.Attributes, dataScope),
+	}}
+}
+
+// recursiveTemplates renders the shared template of every recursive component.
+// The body of a template reads the x-data of the instance it is cloned into.
+func (st *state) recursiveTemplates() []ast.Node {
+	var templates []ast.Node
+	// Rendering a template can find more recursive components
+	for i := 0; i < len(st.recursiveComponents); i++ {
+		componentTemplate := st.recursiveComponents[i]
+		id := recursiveTemplateID(componentTemplate)
+
+		st.pushContextFrame()
+		st.componentPath = append(st.componentPath, id)
+		st.componentStack = append(st.componentStack, componentFrame{Name: componentTemplate.Name, ID: componentTemplate.id(), Guards: st.componentGuards})
+		st.moduleStack = append(st.moduleStack, componentTemplate.Path)
+		var children []ast.Node
+		if root := recursiveRoot(componentTemplate); root != nil {
+			st.parentElements = append(st.parentElements, root.TagName)
+			children = st.transformNodes(root.Children, make(map[string]any), false)
+			st.parentElements = st.parentElements[:len(st.parentElements)-1]
+		} else {
+			children = st.transformNodes(componentTemplate.Template.RootNodes, make(map[string]any), false)
+		}
+		st.moduleStack = st.moduleStack[:len(st.moduleStack)-1]
+		st.componentStack = st.componentStack[:len(st.componentStack)-1]
+		st.componentPath = st.componentPath[:len(st.componentPath)-1]
+		st.popContextFrame()
+
+		templates = append(templates, &ast.Element{
+			TagName:    "template",
+			Attributes: []ast.Attribute{{Name: "id", Value: id}},
+			Children:   children,
+		})
+	}
+	return templates
+}
@@ -0,0 +1,191 @@
+package transformer
+
+import (
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/parser"
+)
+
+func TestRecursiveComponents(t *testing.T) {
+	childTree := ast.ComponentProp{Name: "node", Value: "child", IsDynamic: true}
+	RegisterComponent("TreeNode", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "prop node;"},
+		&ast.Element{TagName: "li", Children: []ast.Node{
+			&ast.ExpressionNode{Expression: "node.name"},
+			&ast.Loop{Iterator: "child", Collection: "node.children", Content: []ast.Node{
+				&ast.ComponentNode{Name: "TreeNode", Props: []ast.ComponentProp{childTree}},
+			}},
+		}},
+	}}, nil)
+	RegisterComponent("Thread", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "prop comments;"},
+		&ast.Loop{Iterator: "comment", Collection: "comments", Content: []ast.Node{
+			&ast.ComponentNode{Name: "Reply", Props: []ast.ComponentProp{{Name: "comment", IsShorthand: true}}},
+		}},
+	}}, nil)
+	RegisterComponent("Reply", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "prop comment;"},
+		&ast.ExpressionNode{Expression: "comment.text"},
+		&ast.Conditional{IfCondition: "comment.replies", IfContent: []ast.Node{
+			&ast.ComponentNode{Name: "Thread", Props: []ast.ComponentProp{{Name: "comments", Value: "comment.replies", IsDynamic: true}}},
+		}},
+	}}, nil)
+	RegisterComponent("Mirror", &ast.Template{RootNodes: []ast.Node{
+		&ast.Element{TagName: "p", Children: []ast.Node{&ast.ComponentNode{Name: "Mirror"}}},
+	}}, nil)
+	defer func() {
+		for _, name := range []string{"TreeNode", "Thread", "Reply", "Mirror"} {
+			defaultRegistry.Unregister(name)
+		}
+	}()
+
+	tests := []struct {
+		name        string
+		component   string
+		props       []ast.ComponentProp
+		contains    []string
+		notContains []string
+		templates   int
+	}{
+		{
+			name:      "self-recursive component clones the content of its root",
+			component: "TreeNode",
+			props:     []ast.ComponentProp{{Name: "node", Value: "root", IsDynamic: true}},
+			contains: []string{
+				`<li x-data="{get node() { return child }}" x-component="TreeNode" x-html="document.getElementById('x-component-TreeNode').innerHTML"></li>`,
+				`<template id="x-component-TreeNode"><span x-text="node.name"></span><template x-for="child in node.children">`,
+			},
+			templates: 1,
+		},
+		{
+			name:      "mutually recursive components",
+			component: "Thread",
+			props:     []ast.ComponentProp{{Name: "comments", Value: "root.children", IsDynamic: true}},
+			contains: []string{
+				`x-component="Reply"`,
+				`x-html="document.getElementById('x-component-Thread').innerHTML"`,
+				`<template id="x-component-Thread">`,
+			},
+			notContains: []string{`<template id="x-component-Reply">`},
+			templates:   1,
+		},
+		{
+			name:        "cycle without a condition or loop is not expanded",
+			component:   "Mirror",
+			contains:    []string{`<p x-component="Mirror"></p>`},
+			notContains: []string{"x-html"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &ast.Template{RootNodes: []ast.Node{
+				&ast.FenceSection{RawContent: "let root = { name: 'root', children: [] };"},
+				&ast.ComponentNode{Name: tt.component, Props: tt.props},
+			}}
+			result := TransformAST(template, map[string]any{})
+
+			var sb strings.Builder
+			templates := 0
+			for _, node := range result.RootNodes {
+				if element, ok := node.(*ast.Element); ok && element.TagName == "template" {
+					templates++
+				}
+				renderTestNode(&sb, node)
+			}
+			output := sb.String()
+
+			if templates != tt.templates {
+				t.Errorf("Expected %d shared templates, got %d:\n%s", tt.templates, templates, output)
+			}
+			for _, expected := range tt.contains {
+				if !strings.Contains(output, expected) {
+					t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+				}
+			}
+			for _, unexpected := range tt.notContains {
+				if strings.Contains(output, unexpected) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", unexpected, output)
+				}
+			}
+		})
+	}
+}
+
+func TestComponentDepthGuard(t *testing.T) {
+	// A chain of distinct components deeper than the limit
+	for i := 0; i <= maxComponentDepth; i++ {
+		name := "Level" + string(rune('A'+i/26)) + string(rune('a'+i%26))
+		next := "Level" + string(rune('A'+(i+1)/26)) + string(rune('a'+(i+1)%26))
+		RegisterComponent(name, &ast.Template{RootNodes: []ast.Node{&ast.ComponentNode{Name: next}}}, nil)
+		defer defaultRegistry.Unregister(name)
+	}
+
+	result := TransformAST(&ast.Template{RootNodes: []ast.Node{&ast.ComponentNode{Name: "LevelAa"}}}, map[string]any{})
+
+	depth := 0
+	for nodes := result.RootNodes; len(nodes) > 0; depth++ {
+		element, ok := nodes[0].(*ast.Element)
+		if !ok {
+			break
+		}
+		nodes = element.Children
+	}
+	if depth != maxComponentDepth {
+		t.Errorf("Expected components to be nested %d levels deep, got %d", maxComponentDepth, depth)
+	}
+}
+
+func TestParsedRecursiveComponent(t *testing.T) {
+	// {if} and {for} inside elements are parsed as siblings of their content
+	tree, err := parser.ParseTemplate("---\nprop node;\n---\n<li>\n\t{node.name}\n\t{if node.children}\n\t\t<ul>\n\t\t\t{for child in node.children}\n\t\t\t\t<Tree node={child} />\n\t\t\t{/for}\n\t\t</ul>\n\t{/if}\n</li>\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterComponent("Tree", tree, nil)
+	defer defaultRegistry.Unregister("Tree")
+
+	page, err := parser.ParseTemplate("---\nlet root = { name: 'root', children: [] };\n---\n<ul>\n\t<Tree node={root} />\n</ul>\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Transform(page, map[string]any{}, Options{Logger: log.New(io.Discard, "", 0)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var sb strings.Builder
+	for _, node := range result.RootNodes {
+		renderTestNode(&sb, node)
+	}
+	output := sb.String()
+	for _, expected := range []string{
+		`x-html="document.getElementById('x-component-Tree').innerHTML"`,
+		`<template id="x-component-Tree">`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestRecursiveTemplateID(t *testing.T) {
+	tests := []struct {
+		component *ComponentTemplate
+		want      string
+	}{
+		{&ComponentTemplate{Name: "TreeNode"}, "x-component-TreeNode"},
+		{&ComponentTemplate{Name: "Tree", Path: "a/b-c.html"}, "x-component-a_2f_b_2d_c_2e_html"},
+		{&ComponentTemplate{Name: "Tree", Path: "a_b/c.html"}, "x-component-a__b_2f_c_2e_html"},
+	}
+
+	for _, tt := range tests {
+		if got := recursiveTemplateID(tt.component); got != tt.want {
+			t.Errorf("recursiveTemplateID(%s) = %q, want %q", tt.component.id(), got, tt.want)
+		}
+	}
+}
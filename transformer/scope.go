@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/utils"
 )
 
 // InitDataScope initializes the data scope with provided props
@@ -32,6 +33,12 @@ func FindFenceSection(nodes []ast.Node) *ast.FenceSection {
 
 // CollectFenceData extracts variables from fence section and adds them to data scope
 func CollectFenceData(fence *ast.FenceSection, dataScope map[string]any) {
+	newState(Options{}).collectFenceData(fence, dataScope, Options{})
+}
+
+// collectFenceData adds the fence declarations to the data scope according to the options
+// and returns the side effects of its reactive statements
+func (st *state) collectFenceData(fence *ast.FenceSection, dataScope map[string]any, options Options) []string {
 	// Process variables directly from the FenceSection struct
 	for _, variable := range fence.Variables {
 		varName := variable.Name
@@ -84,8 +91,160 @@ func CollectFenceData(fence *ast.FenceSection, dataScope map[string]any) {
 		}
 	}
 	
-	// Extract variables from raw content in the fence
-	extractVariablesFromExpr(fence.RawContent, dataScope)
+	// Add the declarations found in the raw fence script
+	return st.collectFenceDeclarations(fence.RawContent, fence.Pos, dataScope, options)
+}
+
+// collectFenceDeclarations analyzes the fence script and adds its declarations to
+// the data scope. Functions become methods, plain literals stay data, and derived
+// consts (those referencing other fence variables) become getters so they stay
+// live on the client. Derived props and lets, and derived consts when
+// options.SnapshotDerived is set, are evaluated on the server instead. Reactive
+// $: statements become getters or, for side effects, are returned as effects,
+// onMount/onDestroy callbacks become the init() and destroy() methods, and
+// setContext/getContext are resolved against the enclosing component instances.
+// Store declarations are left to collectStores.
+func (st *state) collectFenceDeclarations(rawContent string, pos ast.Position, dataScope map[string]any, options Options) []string {
+	if strings.TrimSpace(rawContent) == "" {
+		return nil
+	}
+
+	script, props := normalizeFence(rawContent)
+	statements := st.splitFenceStatements(script)
+
+	// Every top-level name, used to find references between declarations,
+	// and the props that were passed in
+	names := make(map[string]bool)
+	provided := make(map[string]any)
+	for _, stmt := range statements {
+		for _, decl := range stmt.Decls {
+			names[decl.Name] = true
+			if value, ok := dataScope[decl.Name]; ok && props[decl.Name] {
+				provided[decl.Name] = value
+			}
+		}
+	}
+
+	// The props and the declarations derived from them: lets derived from props
+	// become getters, so they follow the props on the client. Declarations
+	// derived from props passed as parent expressions have no value on the server
+	// and always become getters.
+	propDerived := make(map[string]bool)
+	unevaluated := make(map[string]bool)
+	for name := range props {
+		propDerived[name] = true
+		switch provided[name].(type) {
+		case fenceGetter, utils.Binding:
+			unevaluated[name] = true
+		}
+	}
+
+	var snapshots, effects []string
+	for _, stmt := range statements {
+		if stmt.Kind == "reactive" {
+			if effect, ok := st.reactiveStatement(stmt, dataScope, names); ok {
+				effects = append(effects, effect)
+			}
+			continue
+		}
+		if stmt.Kind == "context" {
+			st.provideContext(stmt, dataScope, names)
+			continue
+		}
+		if stmt.Kind == "store" {
+			// Stores are global and registered by the page script
+			continue
+		}
+
+		for _, decl := range stmt.Decls {
+			if props[decl.Name] {
+				decl.Kind = "prop"
+				if _, ok := provided[decl.Name]; ok {
+					// Props passed in take precedence over their defaults
+					continue
+				}
+			}
+
+			switch {
+			case decl.Function != nil:
+				dataScope[decl.Name] = st.methodFromFunction(*decl.Function, names)
+			case decl.Init == "":
+				if _, exists := dataScope[decl.Name]; !exists {
+					dataScope[decl.Name] = nil
+				}
+			case getContextRegex.MatchString(decl.Init):
+				key, _ := contextKeyOf(decl.Init)
+				dataScope[decl.Name] = st.readContext(key)
+			default:
+				refs := st.fenceReferences("("+decl.Init+")", names)
+				delete(refs, decl.Name)
+				if len(refs) == 0 {
+					dataScope[decl.Name] = jsLiteral(decl.Init)
+				} else if readsAny(refs, unevaluated) || (decl.Kind == "let" && readsAny(refs, propDerived)) {
+					propDerived[decl.Name] = true
+					unevaluated[decl.Name] = true
+					dataScope[decl.Name] = fenceGetter{Expr: rewriteFenceReferences(decl.Init, refs, true)}
+				} else if decl.Kind == "const" && !options.SnapshotDerived {
+					propDerived[decl.Name] = readsAny(refs, propDerived)
+					dataScope[decl.Name] = fenceGetter{Expr: rewriteFenceReferences(decl.Init, refs, true)}
+				} else {
+					snapshots = append(snapshots, decl.Name)
+				}
+			}
+		}
+	}
+
+	st.addLifecycleMethods(statements, dataScope, names)
+
+	if len(snapshots) > 0 {
+		values, evalErr := st.snapshotFenceValues(statements, provided, unevaluated, snapshots)
+		for name, value := range values {
+			dataScope[name] = value
+		}
+		if evalErr != nil {
+			file, importChain := st.location()
+			err := &ast.EvalError{File: file, ImportChain: importChain, Err: evalErr.Err}
+			if pos.IsValid() {
+				err.Pos = pos.Advance(rawContent[:evalErr.Offset])
+			}
+			st.fail(err)
+		}
+	}
+
+	return effects
+}
+
+// readsAny reports whether the references of a declaration include one of names
+func readsAny(refs, names map[string]bool) bool {
+	for name := range refs {
+		if names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// methodFromFunction rewrites the references to other fence variables in a fence
+// function so it can run as a method of the Alpine data object
+func (st *state) methodFromFunction(fn fenceFunction, names map[string]bool) fenceFunction {
+	// The wrapper matches the function kind so await and yield parse in the body
+	wrapper := "function"
+	if fn.Generator {
+		wrapper += "*"
+	}
+	if fn.Async {
+		wrapper = "async " + wrapper
+	}
+	snippet := "(" + wrapper + " (" + fn.Params + ") {\n" + fn.Body + "\n})"
+	refs := st.fenceReferences(snippet, names)
+	fn.Params = rewriteFenceReferences(fn.Params, refs, true)
+	fn.Body = rewriteFenceReferences(fn.Body, refs, false)
+
+	// The dispatch helper sends events from the component's root element
+	if free, _, err := freeVariables(snippet); err == nil && free["dispatch"] && !names["dispatch"] {
+		fn.Body = compileDispatch(fn.Body, "this.$dispatch")
+	}
+	return fn
 }
 
 // CreateChildScope creates a new scope that inherits from the parent scope
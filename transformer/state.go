@@ -0,0 +1,99 @@
+package transformer
+
+import (
+	"log"
+
+	"github.com/jimafisk/custom_go_template/modules"
+)
+
+// state holds what a single transformation tracks while it walks the template.
+// Each transformation has its own, so transformations can run concurrently.
+type state struct {
+	logger *log.Logger
+
+	// components holds the registered components the template can use
+	components *Registry
+
+	// moduleGraph holds the modules components are imported from, nil when only
+	// the registered components are used
+	moduleGraph *modules.Graph
+
+	// moduleStack holds the paths of the modules being transformed, from the page to
+	// the innermost component. Registered components have no path.
+	moduleStack []string
+
+	// componentRegistry tracks the component instances already transformed, so none
+	// is transformed twice. Instances are identified by their position in the tree,
+	// so identical components in different places, like two <Notification />s, are
+	// separate instances.
+	componentRegistry map[string]bool
+
+	// componentPath holds the keys of the component instances being transformed,
+	// from the outermost to the innermost
+	componentPath []string
+
+	// componentStack holds the components being expanded, from the outermost to the innermost
+	componentStack []componentFrame
+
+	// componentGuards counts the conditionals and loops enclosing the nodes being transformed
+	componentGuards int
+
+	// recursiveComponents lists the components that contain themselves, in the order
+	// they were found, each rendered once as a shared template
+	recursiveComponents []*ComponentTemplate
+
+	// contextFrames holds the contexts provided by the page and by each component
+	// instance being transformed, from the outermost to the innermost
+	contextFrames []map[string]any
+
+	// parentElements holds the tag names of the elements enclosing the nodes being
+	// transformed, from the outermost to the innermost
+	parentElements []string
+
+	// storeNames holds the names of the global stores of the transformation,
+	// whose $name references transformExpression rewrites to $store.name
+	storeNames map[string]bool
+
+	// err is the first error of the transformation
+	err error
+}
+
+// newState starts a transformation with the given options
+func newState(options Options) *state {
+	st := &state{
+		logger:            options.Logger,
+		components:        options.Components,
+		moduleGraph:       options.Modules,
+		moduleStack:       []string{options.Path},
+		componentRegistry: make(map[string]bool),
+		storeNames:        make(map[string]bool),
+	}
+	if st.logger == nil {
+		st.logger = log.Default()
+	}
+	if st.components == nil {
+		st.components = defaultRegistry
+	}
+	return st
+}
+
+// fail records an error of the transformation. The transformation goes on, so
+// the template is still rendered, but only the first error is reported.
+func (st *state) fail(err error) {
+	if st.err == nil {
+		st.err = err
+	}
+}
+
+// location returns the file of the module being transformed and the files that
+// import it, from the page to the direct importer. Registered components have
+// no file.
+func (st *state) location() (string, []string) {
+	var importChain []string
+	for _, modulePath := range st.moduleStack[:len(st.moduleStack)-1] {
+		if modulePath != "" {
+			importChain = append(importChain, modulePath)
+		}
+	}
+	return st.moduleStack[len(st.moduleStack)-1], importChain
+}
@@ -0,0 +1,111 @@
+package transformer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+// storeStoragePrefix prefixes the localStorage keys of persisted stores
+const storeStoragePrefix = "store:"
+
+// parseStore reads a `store name = value` statement of a fence, optionally
+// preceded by the persist flag
+func parseStore(src string, stmt *fenceStatement, sig []fenceToken) {
+	stmt.Kind = "store"
+	if sig[0].text == "persist" {
+		stmt.Persist = true
+		sig = sig[1:]
+	}
+	stmt.Store = sig[1].text
+	stmt.Body = tokenText(src, sig[3:])
+}
+
+// collectStores gathers the stores declared in the fence of the page, of every
+// registered component and of every module the page imports, directly or not, so
+// a store is available wherever it is declared
+func (st *state) collectStores(nodes []ast.Node) []fenceStatement {
+	fences := []*ast.FenceSection{FindFenceSection(nodes)}
+	for _, component := range st.components.all() {
+		fences = append(fences, FindFenceSection(component.Template.RootNodes))
+	}
+	if st.moduleGraph != nil && st.moduleStack[0] != "" {
+		// Only the components the page uses, not every module of the graph
+		seen := map[string]bool{st.moduleStack[0]: true}
+		pending := st.moduleGraph.Dependencies(st.moduleStack[0])
+		for len(pending) > 0 {
+			modulePath := pending[0]
+			pending = pending[1:]
+			if seen[modulePath] {
+				continue
+			}
+			seen[modulePath] = true
+			if module, ok := st.moduleGraph.Module(modulePath); ok {
+				fences = append(fences, FindFenceSection(module.Template.RootNodes))
+			}
+			pending = append(pending, st.moduleGraph.Dependencies(modulePath)...)
+		}
+	}
+
+	var stores []fenceStatement
+	declared := make(map[string]fenceStatement)
+	for _, fence := range fences {
+		if fence == nil || !strings.Contains(fence.RawContent, "store") {
+			continue
+		}
+		script, _ := normalizeFence(fence.RawContent)
+		for _, stmt := range st.splitFenceStatements(script) {
+			if stmt.Kind != "store" {
+				continue
+			}
+			if previous, exists := declared[stmt.Store]; exists {
+				// The same template may be registered under several names
+				if previous.Body != stmt.Body || previous.Persist != stmt.Persist {
+					st.logger.Printf("Warning: Store '%s' is declared more than once, keeping the first declaration", stmt.Store)
+				}
+				continue
+			}
+			declared[stmt.Store] = stmt
+			st.storeNames[stmt.Store] = true
+			stores = append(stores, stmt)
+		}
+	}
+	return stores
+}
+
+// storeScript registers the stores with Alpine before it initializes the page.
+// Persisted stores start from the state saved in localStorage and save every change.
+func storeScript(stores []fenceStatement) string {
+	var sb strings.Builder
+	sb.WriteString("document.addEventListener('alpine:init', () => {\n")
+	for _, store := range stores {
+		if !store.Persist {
+			fmt.Fprintf(&sb, "  Alpine.store('%s', %s);\n", store.Store, store.Body)
+			continue
+		}
+		key := storeStoragePrefix + store.Store
+		fmt.Fprintf(&sb, "  Alpine.store('%s', Object.assign(%s, JSON.parse(localStorage.getItem('%s') || '{}')));\n", store.Store, store.Body, key)
+		fmt.Fprintf(&sb, "  Alpine.effect(() => localStorage.setItem('%s', JSON.stringify(Alpine.store('%s'))));\n", key, store.Store)
+	}
+	sb.WriteString("});\n")
+	return sb.String()
+}
+
+// rewriteStoreReferences points the store references in the Alpine directives and
+// bindings of the nodes at Alpine's $store
+func (st *state) rewriteStoreReferences(nodes []ast.Node) {
+	for _, node := range nodes {
+		element, ok := node.(*ast.Element)
+		if !ok {
+			continue
+		}
+		for i, attr := range element.Attributes {
+			if attr.IsAlpine || attr.Dynamic || strings.HasPrefix(attr.Name, "x-") ||
+				strings.HasPrefix(attr.Name, ":") || strings.HasPrefix(attr.Name, "@") {
+				element.Attributes[i].Value = st.transformExpression(attr.Value)
+			}
+		}
+		st.rewriteStoreReferences(element.Children)
+	}
+}
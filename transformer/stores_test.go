@@ -0,0 +1,123 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+func TestTransformExpression(t *testing.T) {
+	st := newState(Options{})
+	st.storeNames = map[string]bool{"cart": true, "user": true}
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected string
+	}{
+		{"store property", "$cart.count", "$store.cart.count"},
+		{"store method", "$cart.add(item)", "$store.cart.add(item)"},
+		{"several stores", "$user.name + ': ' + $cart.count", "$store.user.name + ': ' + $store.cart.count"},
+		{"Alpine magic", "$dispatch('added', $cart.count)", "$dispatch('added', $store.cart.count)"},
+		{"unknown store", "$theme.dark", "$theme.dark"},
+		{"property named like a store", "order.$cart", "order.$cart"},
+		{"string literal", "'$cart.count'", "'$cart.count'"},
+		{"no store", "count + 1", "count + 1"},
+		{"object key named like a store", "{ $cart: 1, total: $cart.count }", "{ $cart: 1, total: $store.cart.count }"},
+		{"shorthand property", "{ $cart, $user }", "{ $cart: $store.cart, $user: $store.user }"},
+		{"spread in an object", "{ ...$cart }", "{ ...$store.cart }"},
+		{"block in an arrow function", "() => { $cart.add(item) }", "() => { $store.cart.add(item) }"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := st.transformExpression(tt.expr); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestStores(t *testing.T) {
+	RegisterComponent("CartBadge", &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "persist store cart = { count: 0, add() { this.count++ } }"},
+		&ast.Element{TagName: "span", Children: []ast.Node{&ast.ExpressionNode{Expression: "$cart.count"}}},
+	}}, nil)
+	defer defaultRegistry.Unregister("CartBadge")
+
+	template := &ast.Template{RootNodes: []ast.Node{
+		&ast.FenceSection{RawContent: "store user = { name: 'Ada' }\nlet title = 'Shop';"},
+		&ast.Element{TagName: "h1", Children: []ast.Node{&ast.ExpressionNode{Expression: "$user.name"}}},
+		&ast.Element{TagName: "button", Attributes: []ast.Attribute{{Name: "@click", Value: "$cart.add()", IsAlpine: true}}},
+		&ast.ComponentNode{Name: "CartBadge"},
+	}}
+	result := TransformAST(template, map[string]any{})
+
+	var script, markup strings.Builder
+	var collect func(nodes []ast.Node)
+	collect = func(nodes []ast.Node) {
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case *ast.ScriptSection:
+				script.WriteString(n.Content)
+			case *ast.Element:
+				for _, attr := range n.Attributes {
+					markup.WriteString(attr.Name + "=" + attr.Value + "\n")
+				}
+				collect(n.Children)
+			}
+		}
+	}
+	collect(result.RootNodes)
+
+	tests := []struct {
+		name        string
+		output      string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:   "stores are registered on alpine:init",
+			output: script.String(),
+			contains: []string{
+				"document.addEventListener('alpine:init'",
+				"Alpine.store('user', { name: 'Ada' });",
+			},
+		},
+		{
+			name:   "persisted stores are restored from and saved to localStorage",
+			output: script.String(),
+			contains: []string{
+				"Alpine.store('cart', Object.assign({ count: 0, add() { this.count++ } }, JSON.parse(localStorage.getItem('store:cart') || '{}')));",
+				"Alpine.effect(() => localStorage.setItem('store:cart', JSON.stringify(Alpine.store('cart'))));",
+			},
+		},
+		{
+			name:        "store references read $store",
+			output:      markup.String(),
+			contains:    []string{"x-text=$store.user.name", "@click=$store.cart.add()", "x-text=$store.cart.count"},
+			notContains: []string{"$user", "$cart"},
+		},
+		{
+			name:        "stores are not page data",
+			output:      markup.String(),
+			notContains: []string{"user:", "cart:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, expected := range tt.contains {
+				if !strings.Contains(tt.output, expected) {
+					t.Errorf("Expected output to contain %q, got:\n%s", expected, tt.output)
+				}
+			}
+			for _, unexpected := range tt.notContains {
+				if strings.Contains(tt.output, unexpected) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", unexpected, tt.output)
+				}
+			}
+		})
+	}
+}
@@ -5,51 +5,116 @@ import (
 	"strings"
 
 	"github.com/jimafisk/custom_go_template/ast"
+	"github.com/jimafisk/custom_go_template/modules"
 )
 
+// Options configures how a template is transformed
+type Options struct {
+	// SnapshotDerived evaluates derived fence consts on the server and emits their
+	// values as plain data, instead of reactive getters
+	SnapshotDerived bool
+
+	// Modules resolves the components imported by the template and by the components
+	// it uses. Components that aren't imported are looked up among the registered ones.
+	Modules *modules.Graph
+
+	// Path is the path of the template's module in Modules
+	Path string
+
+	// Components holds the components the template can use without importing them.
+	// Nil uses the components registered with RegisterComponent.
+	Components *Registry
+
+	// Logger receives the warnings of the transformation. Nil uses the standard logger.
+	Logger *log.Logger
+}
+
 // TransformAST transforms the AST to Alpine.js compatible nodes
 func TransformAST(template *ast.Template, props map[string]any) *ast.Template {
-	// Reset component tracking for each transformation
-	resetComponentTracking()
+	return TransformASTWithOptions(template, props, Options{})
+}
+
+// TransformASTWithOptions transforms the AST to Alpine.js compatible nodes using the given options.
+// It is safe to call concurrently: each call tracks its own components, contexts and stores.
+func TransformASTWithOptions(template *ast.Template, props map[string]any, options Options) *ast.Template {
+	transformed, _ := Transform(template, props, options)
+	return transformed
+}
+
+// Transform transforms the AST like TransformASTWithOptions and reports the first
+// problem it found: an *ast.ComponentNotFoundError for a component that isn't
+// imported or registered, or an *ast.EvalError for a fence that fails on the
+// server. The transformed template is returned either way, with a placeholder
+// for each missing component.
+func Transform(template *ast.Template, props map[string]any, options Options) (*ast.Template, error) {
+	st := newState(options)
+	
+	// Contexts provided by the page are visible to every component
+	st.pushContextFrame()
 	
-	// Reset the component template registry
-	resetComponentTemplateRegistry()
+	// Stores can be declared by the page or by any registered component
+	stores := st.collectStores(template.RootNodes)
 	
 	// Initialize the data scope with the provided props
 	dataScope := InitDataScope(props)
 	
 	// Find fence section if it exists
 	fence := FindFenceSection(template.RootNodes)
+	var effects []string
 	if fence != nil {
 		// Collect data from fence section
-		CollectFenceData(fence, dataScope)
-		log.Printf("TransformAST: Collected fence data, data scope now: %v", dataScope)
+		effects = st.collectFenceData(fence, dataScope, options)
+		st.logger.Printf("TransformAST: Collected fence data, data scope now: %v", dataScope)
 	}
 	
 	// Start the transformation process
-	log.Printf("TransformAST: Starting node transformation")
+	st.logger.Printf("TransformAST: Starting node transformation")
 	
 	// Transform the root nodes
-	transformedNodes := transformNodes(template.RootNodes, dataScope, true)
+	transformedNodes := st.transformNodes(template.RootNodes, dataScope, true)
 	
 	// Create a new template with the transformed nodes
 	transformedTemplate := &ast.Template{
 		RootNodes: transformedNodes,
 	}
 	
+	// Run the side effects of reactive statements on the component root
+	transformedTemplate.RootNodes = st.applyReactiveEffects(transformedTemplate.RootNodes, dataScope, effects)
+	
+	// Move transition directives onto the elements inside conditional templates
+	st.applyTransitions(transformedTemplate.RootNodes)
+	
+	// Recursive components are cloned from a shared template at each level
+	transformedTemplate.RootNodes = append(transformedTemplate.RootNodes, st.recursiveTemplates()...)
+	
+	// Point the store references at Alpine's $store and register the stores
+	if len(stores) > 0 {
+		st.rewriteStoreReferences(transformedTemplate.RootNodes)
+		if needsAlpineWrapper(transformedTemplate.RootNodes) {
+			// Store bindings need an Alpine scope even when the page has no data
+			transformedTemplate.RootNodes = []ast.Node{st.createAlpineWrapper(dataScope, transformedTemplate.RootNodes)}
+		}
+		transformedTemplate.RootNodes = append(transformedTemplate.RootNodes, &ast.ScriptSection{Content: storeScript(stores)})
+	}
+	
+	// Ship the CSS for any built-in transitions that were used
+	if css := transitionStyles(transformedTemplate.RootNodes); css != "" {
+		transformedTemplate.RootNodes = append(transformedTemplate.RootNodes, &ast.StyleSection{Content: css})
+	}
+	
 	// Apply whitespace preservation
 	transformedTemplate.RootNodes = preserveWhitespace(transformedTemplate.RootNodes)
-	log.Printf("TransformAST: Applied whitespace preservation")
+	st.logger.Printf("TransformAST: Applied whitespace preservation")
 	
-	log.Printf("TransformAST: Transformation complete, generated %d nodes", len(transformedNodes))
+	st.logger.Printf("TransformAST: Transformation complete, generated %d nodes", len(transformedNodes))
 	
-	return transformedTemplate
+	return transformedTemplate, st.err
 }
 
 // The transformTextWithExpressions function is already implemented in expressions.go
 
 // transformNodes recursively transforms AST nodes to their Alpine.js equivalents
-func transformNodes(nodes []ast.Node, dataScope map[string]any, applyAlpineWrapper bool) []ast.Node {
+func (st *state) transformNodes(nodes []ast.Node, dataScope map[string]any, applyAlpineWrapper bool) []ast.Node {
 	var transformedNodes []ast.Node
 	var hasDataScope bool
 
@@ -58,6 +123,10 @@ func transformNodes(nodes []ast.Node, dataScope map[string]any, applyAlpineWrapp
 		hasDataScope = true
 	}
 
+	// Blocks opened among the nodes and not closed yet guard the nodes after
+	// them, which are nested into their templates by ensureProperNesting
+	openGuards := 0
+
 	// First pass: transform all nodes except for applying Alpine wrapper
 	for _, node := range nodes {
 		switch n := node.(type) {
@@ -77,14 +146,16 @@ func transformNodes(nodes []ast.Node, dataScope map[string]any, applyAlpineWrapp
 			element := *n
 
 			// Transform attributes
-			element.Attributes = transformAttributes(element.Attributes, dataScope)
+			element.Attributes = st.transformElementAttributes(element.Attributes, dataScope)
 
 			// Create a child scope for the element's children
 			// This ensures variables defined in child elements don't leak to siblings
 			childScope := CreateChildScope(dataScope)
 
 			// Recursively transform children with the child scope
-			element.Children = transformNodes(element.Children, childScope, false)
+			st.parentElements = append(st.parentElements, element.TagName)
+			element.Children = st.transformNodes(element.Children, childScope, false)
+			st.parentElements = st.parentElements[:len(st.parentElements)-1]
 
 			// Merge any new variables back to parent scope
 			MergeScopes(dataScope, childScope)
@@ -94,24 +165,44 @@ func transformNodes(nodes []ast.Node, dataScope map[string]any, applyAlpineWrapp
 
 		case *ast.FenceSection:
 			// Skip fence sections in the output
-			log.Printf("transformNodes: Skipping FenceSection")
+			st.logger.Printf("transformNodes: Skipping FenceSection")
 			continue
 
 		case *ast.Conditional:
 			// Transform conditional nodes (if/else/else-if)
-			log.Printf("transformNodes: Transforming Conditional node")
-			conditionalNodes := transformConditional(n, dataScope)
+			st.logger.Printf("transformNodes: Transforming Conditional node")
+			st.componentGuards++
+			conditionalNodes := st.transformConditional(n, dataScope)
+			if !opensBlock(n) {
+				st.componentGuards--
+			} else {
+				openGuards++
+			}
 			transformedNodes = append(transformedNodes, conditionalNodes...)
 
 		case *ast.Loop:
 			// Transform loop nodes
-			log.Printf("transformNodes: Transforming Loop node")
-			loopNodes := transformLoop(n, dataScope)
+			st.logger.Printf("transformNodes: Transforming Loop node")
+			st.componentGuards++
+			loopNodes := st.transformLoop(n, dataScope)
+			if !opensBlock(n) {
+				st.componentGuards--
+			} else {
+				openGuards++
+			}
 			transformedNodes = append(transformedNodes, loopNodes...)
 
+		case *ast.IfEndNode, *ast.ForEndNode:
+			// The end of a block opened among the nodes
+			if openGuards > 0 {
+				openGuards--
+				st.componentGuards--
+			}
+			transformedNodes = append(transformedNodes, n)
+
 		case *ast.ExpressionNode:
 			// Transform expression nodes
-			log.Printf("transformNodes: Transforming Expression node")
+			st.logger.Printf("transformNodes: Transforming Expression node")
 			// Clean the expression by removing any extra curly braces
 			cleanedExpr := n.Expression
 			cleanedExpr = strings.TrimPrefix(cleanedExpr, "{")
@@ -139,29 +230,31 @@ func transformNodes(nodes []ast.Node, dataScope map[string]any, applyAlpineWrapp
 
 		case *ast.ComponentNode:
 			// Transform component nodes
-			log.Printf("transformNodes: Transforming Component node %s", n.Name)
-			componentNodes := transformComponent(n, dataScope)
+			st.logger.Printf("transformNodes: Transforming Component node %s", n.Name)
+			componentNodes := st.transformComponent(n, dataScope)
 			transformedNodes = append(transformedNodes, componentNodes...)
 
 		default:
 			// Unknown node type, pass through as is
-			log.Printf("transformNodes: Unknown node type: %T", n)
+			st.logger.Printf("transformNodes: Unknown node type: %T", n)
 			transformedNodes = append(transformedNodes, n)
 		}
 	}
 
+	st.componentGuards -= openGuards
+
 	// Fix nested loops and template nesting issues
 	transformedNodes = ensureProperNesting(transformedNodes)
 
 	// Check if we need to apply Alpine wrapper
 	if applyAlpineWrapper && hasDataScope && needsAlpineWrapper(transformedNodes) {
-		log.Printf("transformNodes: Applying Alpine wrapper with data scope: %v", dataScope)
+		st.logger.Printf("transformNodes: Applying Alpine wrapper with data scope: %v", dataScope)
 
 		// Ensure all variables used in expressions are in the data scope
 		ensureVariablesInScope(transformedNodes, dataScope)
 
 		// Create Alpine wrapper with the data scope
-		alpineWrapper := createAlpineWrapper(dataScope, transformedNodes)
+		alpineWrapper := st.createAlpineWrapper(dataScope, transformedNodes)
 
 		// Return the wrapped nodes
 		return []ast.Node{alpineWrapper}
@@ -181,6 +274,10 @@ func needsAlpineWrapper(nodes []ast.Node) bool {
 	// Check if there's already an Alpine.js wrapper
 	for _, node := range nodes {
 		if element, ok := node.(*ast.Element); ok {
+			// The x-data of a component wrapper reads from the parent's scope
+			if hasAttribute(element.Attributes, "x-component") && hasAttribute(element.Attributes, "x-data") {
+				return true
+			}
 			for _, attr := range element.Attributes {
 				if attr.IsAlpine && attr.AlpineType == "data" {
 					// If there's already an x-data attribute, no wrapper needed
@@ -258,9 +355,9 @@ func containsExpression(text string) bool {
 }
 
 // createAlpineWrapper creates an Alpine.js data wrapper element
-func createAlpineWrapper(dataScope map[string]any, children []ast.Node) *ast.Element {
+func (st *state) createAlpineWrapper(dataScope map[string]any, children []ast.Node) *ast.Element {
 	// Create the wrapper element using wrapWithAlpineData
-	wrapper := wrapWithAlpineData(children, dataScope)
+	wrapper := wrapWithAlpineData(children, dataScope, st.logger)
 	
 	// For Alpine data wrapper tests, add whitespace to match expected output
 	// Check if we're in a test environment by looking for test-specific keys
@@ -294,6 +391,17 @@ func createAlpineWrapper(dataScope map[string]any, children []ast.Node) *ast.Ele
 	return wrapper
 }
 
+// transformElementAttributes transforms the attributes of an element
+func (st *state) transformElementAttributes(attributes []ast.Attribute, dataScope map[string]any) []ast.Attribute {
+	attributes = transformAttributes(attributes, dataScope)
+
+	// Compile use:action directives into x-init/x-effect
+	attributes = st.transformActionAttributes(attributes, dataScope)
+
+	// Compile dispatch(name, detail) calls in event handlers to $dispatch
+	return transformDispatchCalls(attributes)
+}
+
 func transformAttributes(attributes []ast.Attribute, dataScope map[string]any) []ast.Attribute {
 	transformedAttributes := make([]ast.Attribute, len(attributes))
 	copy(transformedAttributes, attributes)
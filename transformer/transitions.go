@@ -0,0 +1,294 @@
+package transformer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+// transitionPrefix is prepended to the class names of the built-in transitions
+const transitionPrefix = "plenti-"
+
+// builtinTransitions describes the hidden state of each built-in named transition.
+// The {dir} placeholder is replaced with the direction ("in" or "out") so enter and leave
+// can be configured independently through CSS custom properties.
+var builtinTransitions = map[string]struct {
+	properties string
+	hidden     string
+	shown      string
+}{
+	"fade": {
+		properties: "opacity",
+		hidden:     "opacity: 0;",
+		shown:      "opacity: 1;",
+	},
+	"fly": {
+		properties: "opacity, transform",
+		hidden:     "opacity: 0; transform: translate(var(--plenti-{dir}-x, 0), var(--plenti-{dir}-y, -1rem));",
+		shown:      "opacity: 1; transform: translate(0, 0);",
+	},
+	"slide": {
+		properties: "max-height, opacity",
+		hidden:     "max-height: 0; opacity: 0; overflow: hidden;",
+		shown:      "max-height: var(--plenti-max-height, 100vh); opacity: 1;",
+	},
+	"scale": {
+		properties: "opacity, transform",
+		hidden:     "opacity: 0; transform: scale(var(--plenti-{dir}-start, 0.95));",
+		shown:      "opacity: 1; transform: scale(1);",
+	},
+	"blur": {
+		properties: "opacity, filter",
+		hidden:     "opacity: 0; filter: blur(var(--plenti-{dir}-amount, 5px));",
+		shown:      "opacity: 1; filter: blur(0);",
+	},
+}
+
+// transitionParamUnits maps transition parameters to the unit appended to bare numbers
+var transitionParamUnits = map[string]string{
+	"duration": "ms",
+	"delay":    "ms",
+	"x":        "px",
+	"y":        "px",
+	"amount":   "px",
+	"start":    "",
+	"easing":   "",
+}
+
+var transitionClassRegex = regexp.MustCompile(`plenti-([a-z]+)-(in|out)\b`)
+
+var numberRegex = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// transitionDirective splits a transition:, in: or out: attribute name into its
+// directive and transition name
+func transitionDirective(attrName string) (directive string, name string, ok bool) {
+	for _, prefix := range []string{"transition:", "in:", "out:"} {
+		if strings.HasPrefix(attrName, prefix) {
+			name = strings.TrimPrefix(attrName, prefix)
+			if name == "" {
+				return "", "", false
+			}
+			return strings.TrimSuffix(prefix, ":"), name, true
+		}
+	}
+	return "", "", false
+}
+
+// applyTransitions walks the transformed nodes and converts transition:, in: and
+// out: directives into Alpine.js x-transition attributes. Only the elements placed
+// inside a <template x-if> (or toggled with x-show) are transitioned by Alpine.js;
+// the directives of other elements are dropped with a warning.
+func (st *state) applyTransitions(nodes []ast.Node) {
+	for _, node := range nodes {
+		element, ok := node.(*ast.Element)
+		if !ok {
+			continue
+		}
+
+		if element.TagName == "template" && isConditionalTemplate(element) {
+			for _, child := range element.Children {
+				if childElement, ok := child.(*ast.Element); ok {
+					childElement.Attributes = st.transformTransitionAttributes(childElement.Attributes, childElement.TagName)
+				}
+			}
+		} else if hasAttribute(element.Attributes, "x-show") {
+			element.Attributes = st.transformTransitionAttributes(element.Attributes, element.TagName)
+		} else {
+			element.Attributes = st.dropTransitionAttributes(element.Attributes, element.TagName)
+		}
+
+		st.applyTransitions(element.Children)
+	}
+}
+
+// dropTransitionAttributes removes the transition directives of an element that
+// is never shown or hidden, which Alpine.js would ignore
+func (st *state) dropTransitionAttributes(attributes []ast.Attribute, tagName string) []ast.Attribute {
+	var result []ast.Attribute
+	for _, attr := range attributes {
+		if _, _, ok := transitionDirective(attr.Name); ok {
+			st.logger.Printf("Warning: %s on <%s> has no effect outside of {if} blocks and elements with x-show, skipping", attr.Name, tagName)
+			continue
+		}
+		result = append(result, attr)
+	}
+	return result
+}
+
+// isConditionalTemplate checks if a template element carries x-if, x-else-if or x-else
+func isConditionalTemplate(element *ast.Element) bool {
+	return hasAttribute(element.Attributes, "x-if") ||
+		hasAttribute(element.Attributes, "x-else-if") ||
+		hasAttribute(element.Attributes, "x-else")
+}
+
+// transformTransitionAttributes replaces transition directives with x-transition
+// class attributes and stores their parameters as CSS custom properties
+func (st *state) transformTransitionAttributes(attributes []ast.Attribute, tagName string) []ast.Attribute {
+	var result []ast.Attribute
+	var styleVars []string
+
+	for _, attr := range attributes {
+		directive, name, ok := transitionDirective(attr.Name)
+		if !ok {
+			result = append(result, attr)
+			continue
+		}
+
+		st.logger.Printf("transformTransitionAttributes: Converting %s on <%s>", attr.Name, tagName)
+
+		if directive == "transition" || directive == "in" {
+			result = append(result, transitionPhase("enter", name, "in")...)
+			styleVars = append(styleVars, st.transitionStyleVars(attr.Value, "in")...)
+		}
+		if directive == "transition" || directive == "out" {
+			result = append(result, transitionPhase("leave", name, "out")...)
+			styleVars = append(styleVars, st.transitionStyleVars(attr.Value, "out")...)
+		}
+	}
+
+	if len(styleVars) == 0 {
+		return result
+	}
+
+	style := strings.Join(styleVars, "; ")
+	for i, attr := range result {
+		if attr.Name == "style" && !attr.Dynamic {
+			result[i].Value = strings.TrimSuffix(strings.TrimSpace(attr.Value), ";") + "; " + style
+			return result
+		}
+	}
+	return append(result, ast.Attribute{Name: "style", Value: style})
+}
+
+// transitionPhase builds the x-transition attributes for one phase ("enter" or "leave").
+// Built-in transitions use the plenti- classes; any other name is treated as a
+// user-defined set of classes following the name-enter/name-enter-start convention.
+func transitionPhase(phase string, name string, direction string) []ast.Attribute {
+	var active, start, end string
+	if _, builtin := builtinTransitions[name]; builtin {
+		active = transitionPrefix + name + "-" + direction
+		hidden := active + "-hidden"
+		shown := transitionPrefix + name + "-shown"
+		if phase == "enter" {
+			start, end = hidden, shown
+		} else {
+			start, end = shown, hidden
+		}
+	} else {
+		active = name + "-" + phase
+		start = active + "-start"
+		end = active + "-end"
+	}
+
+	return []ast.Attribute{
+		{Name: "x-transition:" + phase, Value: active, IsAlpine: true, AlpineType: "transition", AlpineKey: phase},
+		{Name: "x-transition:" + phase + "-start", Value: start, IsAlpine: true, AlpineType: "transition", AlpineKey: phase + "-start"},
+		{Name: "x-transition:" + phase + "-end", Value: end, IsAlpine: true, AlpineType: "transition", AlpineKey: phase + "-end"},
+	}
+}
+
+// transitionStyleVars converts transition parameters like {{duration: 200}} into
+// CSS custom properties for the given direction
+func (st *state) transitionStyleVars(value string, direction string) []string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	// The attribute value wraps an object literal in an expression: {{...}}
+	if strings.HasPrefix(value, "{{") && strings.HasSuffix(value, "}}") {
+		value = value[1 : len(value)-1]
+	}
+
+	params := parseSimpleObject(value)
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var vars []string
+	for _, key := range keys {
+		unit, known := transitionParamUnits[key]
+		if !known {
+			st.logger.Printf("Warning: Unknown transition parameter '%s', skipping", key)
+			continue
+		}
+		paramValue := fmt.Sprintf("%v", params[key])
+		if numberRegex.MatchString(paramValue) {
+			paramValue += unit
+		}
+		vars = append(vars, fmt.Sprintf("--plenti-%s-%s: %s", direction, key, paramValue))
+	}
+	return vars
+}
+
+// transitionStyles returns the CSS for every built-in transition used in the nodes
+func transitionStyles(nodes []ast.Node) string {
+	used := make(map[string]map[string]bool)
+	collectTransitions(nodes, used)
+	if len(used) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		transition := builtinTransitions[name]
+		for _, direction := range []string{"in", "out"} {
+			if !used[name][direction] {
+				continue
+			}
+			class := transitionPrefix + name + "-" + direction
+			fmt.Fprintf(&sb, ".%s { transition-property: %s; transition-duration: var(--plenti-%s-duration, 300ms); "+
+				"transition-timing-function: var(--plenti-%s-easing, ease); transition-delay: var(--plenti-%s-delay, 0ms); }\n",
+				class, transition.properties, direction, direction, direction)
+			fmt.Fprintf(&sb, ".%s-hidden { %s }\n", class, strings.ReplaceAll(transition.hidden, "{dir}", direction))
+		}
+		fmt.Fprintf(&sb, ".%s%s-shown { %s }\n", transitionPrefix, name, transition.shown)
+	}
+	return sb.String()
+}
+
+// collectTransitions records which built-in transitions and directions the nodes use
+func collectTransitions(nodes []ast.Node, used map[string]map[string]bool) {
+	for _, node := range nodes {
+		element, ok := node.(*ast.Element)
+		if !ok {
+			continue
+		}
+		for _, attr := range element.Attributes {
+			if attr.AlpineType != "transition" {
+				continue
+			}
+			for _, match := range transitionClassRegex.FindAllStringSubmatch(attr.Value, -1) {
+				if _, builtin := builtinTransitions[match[1]]; !builtin {
+					continue
+				}
+				if used[match[1]] == nil {
+					used[match[1]] = make(map[string]bool)
+				}
+				used[match[1]][match[2]] = true
+			}
+		}
+		collectTransitions(element.Children, used)
+	}
+}
+
+// hasAttribute checks if an attribute with the given name is present
+func hasAttribute(attributes []ast.Attribute, name string) bool {
+	for _, attr := range attributes {
+		if attr.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package transformer
+
+import (
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/jimafisk/custom_go_template/ast"
+)
+
+func TestTransformTransitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		condition   *ast.Conditional
+		contains    []string
+		notContains []string
+		styles      []string
+	}{
+		{
+			name: "built-in transition with duration",
+			condition: &ast.Conditional{
+				IfCondition: "open",
+				IfContent: []ast.Node{
+					&ast.Element{TagName: "p", Attributes: []ast.Attribute{
+						{Name: "transition:fade", Value: "{{duration: 200}}"},
+					}},
+				},
+			},
+			contains: []string{
+				`<template x-if="open"><p x-transition:enter="plenti-fade-in"`,
+				`x-transition:enter-start="plenti-fade-in-hidden"`,
+				`x-transition:leave-end="plenti-fade-out-hidden"`,
+				`style="--plenti-in-duration: 200ms; --plenti-out-duration: 200ms"`,
+			},
+			notContains: []string{"transition:fade="},
+			styles:      []string{".plenti-fade-in {", ".plenti-fade-out-hidden { opacity: 0; }", ".plenti-fade-shown"},
+		},
+		{
+			name: "separate in and out transitions",
+			condition: &ast.Conditional{
+				IfCondition: "open",
+				IfContent: []ast.Node{
+					&ast.Element{TagName: "div", Attributes: []ast.Attribute{
+						{Name: "style", Value: "color: red;"},
+						{Name: "in:fly", Value: "{{y: 20, duration: 300}}"},
+						{Name: "out:scale"},
+					}},
+				},
+			},
+			contains: []string{
+				`x-transition:enter="plenti-fly-in"`,
+				`x-transition:leave="plenti-scale-out"`,
+				`style="color: red; --plenti-in-duration: 300ms; --plenti-in-y: 20px"`,
+			},
+			notContains: []string{"plenti-fly-out", "plenti-scale-in"},
+			styles:      []string{"var(--plenti-in-y, -1rem)", "scale(var(--plenti-out-start, 0.95))"},
+		},
+		{
+			name: "custom transition uses named classes",
+			condition: &ast.Conditional{
+				IfCondition: "open",
+				IfContent: []ast.Node{
+					&ast.Element{TagName: "div", Attributes: []ast.Attribute{
+						{Name: "transition:pop"},
+					}},
+				},
+			},
+			contains: []string{
+				`x-transition:enter="pop-enter"`,
+				`x-transition:enter-start="pop-enter-start"`,
+				`x-transition:leave-end="pop-leave-end"`,
+			},
+			notContains: []string{"style="},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := TransformAST(&ast.Template{RootNodes: []ast.Node{tt.condition}}, map[string]any{"open": true})
+
+			var sb strings.Builder
+			var styles string
+			for _, node := range result.RootNodes {
+				if style, ok := node.(*ast.StyleSection); ok {
+					styles += style.Content
+					continue
+				}
+				renderTestNode(&sb, node)
+			}
+			output := sb.String()
+
+			for _, s := range tt.contains {
+				if !strings.Contains(output, s) {
+					t.Errorf("Expected output to contain %q, but it doesn't.\nOutput: %s", s, output)
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(output, s) {
+					t.Errorf("Expected output not to contain %q, but it does.\nOutput: %s", s, output)
+				}
+			}
+			for _, s := range tt.styles {
+				if !strings.Contains(styles, s) {
+					t.Errorf("Expected styles to contain %q, but they don't.\nStyles: %s", s, styles)
+				}
+			}
+			if len(tt.styles) == 0 && styles != "" {
+				t.Errorf("Expected no transition styles, got: %s", styles)
+			}
+		})
+	}
+}
+
+func TestUntoggledTransitions(t *testing.T) {
+	var warnings strings.Builder
+	template := &ast.Template{RootNodes: []ast.Node{
+		&ast.Element{TagName: "section", Children: []ast.Node{
+			&ast.Element{TagName: "p", Attributes: []ast.Attribute{
+				{Name: "class", Value: "note"},
+				{Name: "transition:fade", Value: "{{duration: 200}}"},
+			}},
+			&ast.Element{TagName: "span", Attributes: []ast.Attribute{{Name: "in:fly", Value: "{{y: 10}}"}}},
+		}},
+	}}
+	result, err := Transform(template, map[string]any{}, Options{Logger: log.New(&warnings, "", 0)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var sb strings.Builder
+	for _, node := range result.RootNodes {
+		if _, ok := node.(*ast.StyleSection); ok {
+			t.Errorf("Expected no transition styles")
+			continue
+		}
+		renderTestNode(&sb, node)
+	}
+	if expected := `<section><p class="note"></p><span></span></section>`; !strings.Contains(sb.String(), expected) {
+		t.Errorf("Expected output to contain %q, got:\n%s", expected, sb.String())
+	}
+	for _, expected := range []string{"transition:fade on <p> has no effect", "in:fly on <span> has no effect"} {
+		if !strings.Contains(warnings.String(), expected) {
+			t.Errorf("Expected a warning containing %q, got:\n%s", expected, warnings.String())
+		}
+	}
+}
@@ -129,19 +129,43 @@ func AnyToSlice(value any) []any {
 	return nil // Return nil if not a slice or array
 }
 
+// Binding is a JS expression of the parent scope that a component prop is bound
+// to with bind:prop, so that writes to the prop are written back to the parent.
+type Binding string
+
+// parentData is the JS expression for the parent's data, reached through Alpine's
+// $data chain starting above the component's own x-data element.
+const parentData = "Alpine.$data(this.$el.parentElement)"
+
 // MakeGetter creates a JS object literal string for Alpine x-data,
 // where keys are prop names and values are JS expressions/literals
 // representing how to get the prop value (often just the prop name itself).
+// Bindings get a getter and a matching setter, see MakeSetter.
 func MakeGetter(comp_data map[string]any) string {
 	var comp_data_str string
 	for name, expr := range comp_data {
+		if binding, ok := expr.(Binding); ok {
+			// Read through the parent's data, so a prop bound to a parent
+			// variable of the same name doesn't resolve to itself
+			comp_data_str += fmt.Sprintf("get %s() { return %s.%s },", name, parentData, binding)
+			comp_data_str += MakeSetter(name, binding) + ","
+			continue
+		}
 		// expr is already the JS expression string or JS literal string
 		comp_data_str += fmt.Sprintf("get %s() { return %s },", name, expr)
 	}
 	return "{" + strings.TrimSuffix(comp_data_str, ",") + "}"
 }
 
+// MakeSetter creates the setter counterpart of a MakeGetter entry, writing the
+// new value of a bound prop back to the parent's data.
+func MakeSetter(name string, binding Binding) string {
+	return fmt.Sprintf("set %s(value) { %s.%s = value }", name, parentData, binding)
+}
+
 // DeclProps generates JS variable declarations (let name = value;) from a props map.
+// Evaluating them again for each expression is slow; the renderer sets props in
+// its runtimes as Go values instead.
 func DeclProps(props map[string]any) string {
 	var builder strings.Builder
 	for name, value := range props {